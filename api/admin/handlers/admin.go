@@ -175,7 +175,7 @@ func DeleteAdmin(ctx *gin.Context) {
 // @Tags         admin
 // @Accept       json
 // @Produce      json
-// @Param        cache_type  query     string  false  "Cache type to flush (user/team/challenge/login/static_config/team_solved/reset_password/all)"
+// @Param        cache_type  query     string  false  "Cache type to flush (user/team/challenge/login/static_config/team_solved/team_hint/rate_limit/prerequisite/challenge_list/attachment/reset_password/all)"
 // @Success      200         {object}  types.SuccessResponse
 // @Failure      400         {object}  types.ErrorResponse
 // @Router       /admin/flush_cache [post]
@@ -199,6 +199,12 @@ func FlushCache(ctx *gin.Context) {
 		shared.ChallengeCache.Reset()
 		shared.StaticConfig.Reset()
 		shared.TeamSolvedCache.Reset()
+		shared.TeamHintCache.Reset()
+		shared.RateLimitCache.Reset()
+		shared.RateLimitViolations.Reset()
+		shared.PrerequisiteCache.Reset()
+		shared.ChallengeListCache.Reset()
+		shared.AttachmentCache.Reset()
 		auditLog.WithFields(logrus.Fields{
 			"event":  "flush_cache",
 			"status": "success",
@@ -263,6 +269,53 @@ func FlushCache(ctx *gin.Context) {
 			"ip":     ctx.ClientIP(),
 		}).Info("Team solved cache flushed successfully")
 		ctx.JSON(http.StatusOK, types.SuccessResponse{Message: "Team solved cache flushed successfully"})
+	case "team_hint":
+		shared.TeamHintCache.Reset()
+		auditLog.WithFields(logrus.Fields{
+			"event":  "flush_cache",
+			"status": "success",
+			"cache":  "team_hint",
+			"ip":     ctx.ClientIP(),
+		}).Info("Team hint cache flushed successfully")
+		ctx.JSON(http.StatusOK, types.SuccessResponse{Message: "Team hint cache flushed successfully"})
+	case "rate_limit":
+		shared.RateLimitCache.Reset()
+		shared.RateLimitViolations.Reset()
+		auditLog.WithFields(logrus.Fields{
+			"event":  "flush_cache",
+			"status": "success",
+			"cache":  "rate_limit",
+			"ip":     ctx.ClientIP(),
+		}).Info("Rate limit cache flushed successfully")
+		ctx.JSON(http.StatusOK, types.SuccessResponse{Message: "Rate limit cache flushed successfully"})
+	case "challenge_list":
+		shared.ChallengeListCache.Reset()
+		auditLog.WithFields(logrus.Fields{
+			"event":  "flush_cache",
+			"status": "success",
+			"cache":  "challenge_list",
+			"ip":     ctx.ClientIP(),
+		}).Info("Challenge list cache flushed successfully")
+		ctx.JSON(http.StatusOK, types.SuccessResponse{Message: "Challenge list cache flushed successfully"})
+	case "prerequisite":
+		shared.PrerequisiteCache.Reset()
+		shared.ChallengeListCache.Reset()
+		auditLog.WithFields(logrus.Fields{
+			"event":  "flush_cache",
+			"status": "success",
+			"cache":  "prerequisite",
+			"ip":     ctx.ClientIP(),
+		}).Info("Prerequisite cache flushed successfully")
+		ctx.JSON(http.StatusOK, types.SuccessResponse{Message: "Prerequisite cache flushed successfully"})
+	case "attachment":
+		shared.AttachmentCache.Reset()
+		auditLog.WithFields(logrus.Fields{
+			"event":  "flush_cache",
+			"status": "success",
+			"cache":  "attachment",
+			"ip":     ctx.ClientIP(),
+		}).Info("Attachment cache flushed successfully")
+		ctx.JSON(http.StatusOK, types.SuccessResponse{Message: "Attachment cache flushed successfully"})
 	case "reset_password":
 		// TODO: integrate with rodan-authify
 		auditLog.WithFields(logrus.Fields{
@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/intraware/rodan/api/announcements"
+	"github.com/intraware/rodan/internal/models"
+	"github.com/intraware/rodan/internal/types"
+	"github.com/intraware/rodan/internal/utils"
+	"github.com/sirupsen/logrus"
+)
+
+func ToAnnouncementResponse(a models.Announcement) AnnouncementResponse {
+	return AnnouncementResponse{
+		ID:          a.ID,
+		Title:       a.Title,
+		Body:        a.Body,
+		ChallengeID: a.ChallengeID,
+		Pinned:      a.Pinned,
+		VisibleAt:   a.VisibleAt,
+		CreatedAt:   a.CreatedAt,
+		UpdatedAt:   a.UpdatedAt,
+	}
+}
+
+// bindAnnouncement reads and checks an announcement body, writing the error response itself
+func bindAnnouncement(ctx *gin.Context, event string) (AnnouncementResponse, bool) {
+	auditLog := utils.Logger.WithField("type", "audit")
+	var req AnnouncementResponse
+	if err := ctx.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Title) == "" {
+		auditLog.WithFields(logrus.Fields{
+			"event":  event,
+			"status": "failure",
+			"reason": "invalid_request",
+			"ip":     ctx.ClientIP(),
+		}).Warn("Invalid request in " + event)
+		ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid request"})
+		return req, false
+	}
+	if req.ChallengeID != nil {
+		var count int64
+		if err := models.DB.Model(&models.Challenge{}).Where("id = ?", *req.ChallengeID).Count(&count).Error; err != nil || count == 0 {
+			auditLog.WithFields(logrus.Fields{
+				"event":        event,
+				"status":       "failure",
+				"reason":       "challenge_not_found",
+				"challenge_id": *req.ChallengeID,
+				"ip":           ctx.ClientIP(),
+			}).Warn("Announcement references unknown challenge")
+			ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Challenge not found"})
+			return req, false
+		}
+	}
+	if req.VisibleAt.IsZero() {
+		req.VisibleAt = time.Now()
+	}
+	return req, true
+}
+
+// GetAllAnnouncements godoc
+// @Summary      Get all announcements
+// @Description  Retrieves every announcement, including scheduled ones
+// @Security     BearerAuth
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Success      200  {array}   AnnouncementResponse
+// @Failure      500  {object}  types.ErrorResponse
+// @Router       /admin/announcements [get]
+func GetAllAnnouncements(ctx *gin.Context) {
+	auditLog := utils.Logger.WithField("type", "audit")
+	var all []models.Announcement
+	if err := models.DB.Order("pinned DESC, visible_at DESC").Find(&all).Error; err != nil {
+		auditLog.WithFields(logrus.Fields{
+			"event":  "get_all_announcements",
+			"status": "failure",
+			"reason": "database_error",
+			"ip":     ctx.ClientIP(),
+		}).Error("Database error in getAllAnnouncements")
+		ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Database error"})
+		return
+	}
+	resp := make([]AnnouncementResponse, 0, len(all))
+	for _, a := range all {
+		resp = append(resp, ToAnnouncementResponse(a))
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// AddAnnouncement godoc
+// @Summary      Add an announcement
+// @Description  Creates an announcement, published at visible_at (defaults to now)
+// @Security     BearerAuth
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        announcement  body      AnnouncementResponse  true  "Announcement object"
+// @Success      200           {object}  AnnouncementResponse
+// @Failure      400           {object}  types.ErrorResponse
+// @Failure      500           {object}  types.ErrorResponse
+// @Router       /admin/announcements [post]
+func AddAnnouncement(ctx *gin.Context) {
+	auditLog := utils.Logger.WithField("type", "audit")
+	req, ok := bindAnnouncement(ctx, "add_announcement")
+	if !ok {
+		return
+	}
+	announcement := models.Announcement{
+		Title:       req.Title,
+		Body:        req.Body,
+		ChallengeID: req.ChallengeID,
+		Pinned:      req.Pinned,
+		VisibleAt:   req.VisibleAt,
+	}
+	if err := models.DB.Create(&announcement).Error; err != nil {
+		auditLog.WithFields(logrus.Fields{
+			"event":  "add_announcement",
+			"status": "failure",
+			"reason": "database_error",
+			"ip":     ctx.ClientIP(),
+		}).Error("Database error in addAnnouncement")
+		ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Database error"})
+		return
+	}
+	announcements.Invalidate()
+	announcements.Publish(announcement)
+	auditLog.WithFields(logrus.Fields{
+		"event":           "add_announcement",
+		"status":          "success",
+		"announcement_id": announcement.ID,
+		"visible_at":      announcement.VisibleAt,
+		"ip":              ctx.ClientIP(),
+	}).Info("Announcement added successfully")
+	ctx.JSON(http.StatusOK, ToAnnouncementResponse(announcement))
+}
+
+// UpdateAnnouncement godoc
+// @Summary      Update an announcement
+// @Description  Edits an existing announcement
+// @Security     BearerAuth
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id            path      int                   true  "Announcement ID"
+// @Param        announcement  body      AnnouncementResponse  true  "Announcement object"
+// @Success      200           {object}  AnnouncementResponse
+// @Failure      400           {object}  types.ErrorResponse
+// @Failure      404           {object}  types.ErrorResponse
+// @Failure      500           {object}  types.ErrorResponse
+// @Router       /admin/announcements/{id} [patch]
+func UpdateAnnouncement(ctx *gin.Context) {
+	auditLog := utils.Logger.WithField("type", "audit")
+	id := ctx.Param("id")
+	var announcement models.Announcement
+	if err := models.DB.First(&announcement, id).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, types.ErrorResponse{Error: "Announcement not found"})
+		return
+	}
+	req, ok := bindAnnouncement(ctx, "update_announcement")
+	if !ok {
+		return
+	}
+	rescheduled := !req.VisibleAt.Equal(announcement.VisibleAt) && req.VisibleAt.After(time.Now())
+	announcement.Title = req.Title
+	announcement.Body = req.Body
+	announcement.ChallengeID = req.ChallengeID
+	announcement.Pinned = req.Pinned
+	announcement.VisibleAt = req.VisibleAt
+	if err := models.DB.Save(&announcement).Error; err != nil {
+		auditLog.WithFields(logrus.Fields{
+			"event":  "update_announcement",
+			"status": "failure",
+			"reason": "database_error",
+			"ip":     ctx.ClientIP(),
+		}).Error("Database error in updateAnnouncement")
+		ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Database error"})
+		return
+	}
+	announcements.Invalidate()
+	if rescheduled {
+		announcements.Publish(announcement)
+	}
+	auditLog.WithFields(logrus.Fields{
+		"event":           "update_announcement",
+		"status":          "success",
+		"announcement_id": announcement.ID,
+		"ip":              ctx.ClientIP(),
+	}).Info("Announcement updated successfully")
+	ctx.JSON(http.StatusOK, ToAnnouncementResponse(announcement))
+}
+
+// DeleteAnnouncement godoc
+// @Summary      Delete an announcement
+// @Description  Removes an announcement from the player feed
+// @Security     BearerAuth
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Announcement ID"
+// @Success      200  {object}  types.SuccessResponse
+// @Failure      404  {object}  types.ErrorResponse
+// @Failure      500  {object}  types.ErrorResponse
+// @Router       /admin/announcements/{id} [delete]
+func DeleteAnnouncement(ctx *gin.Context) {
+	auditLog := utils.Logger.WithField("type", "audit")
+	id := ctx.Param("id")
+	var announcement models.Announcement
+	if err := models.DB.First(&announcement, id).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, types.ErrorResponse{Error: "Announcement not found"})
+		return
+	}
+	if err := models.DB.Delete(&announcement).Error; err != nil {
+		auditLog.WithFields(logrus.Fields{
+			"event":  "delete_announcement",
+			"status": "failure",
+			"reason": "database_error",
+			"ip":     ctx.ClientIP(),
+		}).Error("Database error in deleteAnnouncement")
+		ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Database error"})
+		return
+	}
+	announcements.Invalidate()
+	auditLog.WithFields(logrus.Fields{
+		"event":           "delete_announcement",
+		"status":          "success",
+		"announcement_id": announcement.ID,
+		"ip":              ctx.ClientIP(),
+	}).Info("Announcement deleted successfully")
+	ctx.JSON(http.StatusOK, types.SuccessResponse{Message: "Announcement deleted successfully"})
+}
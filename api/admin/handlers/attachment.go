@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/intraware/rodan/api/shared"
+	"github.com/intraware/rodan/internal/models"
+	"github.com/intraware/rodan/internal/storage"
+	"github.com/intraware/rodan/internal/types"
+	"github.com/intraware/rodan/internal/utils"
+	"github.com/intraware/rodan/internal/utils/values"
+	"github.com/sirupsen/logrus"
+)
+
+const defaultMaxUploadSize = 100 << 20
+
+func toAttachmentResponse(a models.Attachment) AttachmentResponse {
+	return AttachmentResponse{
+		ID:          a.ID,
+		ChallengeID: a.ChallengeID,
+		Name:        a.Name,
+		ContentType: a.ContentType,
+		Size:        a.Size,
+		SHA256:      a.SHA256,
+		Templated:   a.Templated,
+		Placeholder: a.Placeholder,
+		CreatedAt:   a.CreatedAt,
+	}
+}
+
+func newStorageKey() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// templateChallenge checks that a templated attachment can be solved with the flag put
+// into it, which needs the challenge to derive its flag per team
+func templateChallenge(challenge models.Challenge) error {
+	if !challenge.IsStatic {
+		return nil
+	}
+	var static models.StaticConfig
+	if err := models.DB.Where("challenge_id = ?", challenge.ID).First(&static).Error; err != nil || !static.PerTeam {
+		return errors.New("templated attachments need a dynamic challenge or a static one with per_team set")
+	}
+	return nil
+}
+
+// UploadAttachment godoc
+// @Summary      Upload a challenge attachment
+// @Description  Stores a file that players of the challenge can download
+// @Security     BearerAuth
+// @Tags         admin
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        id           path      int     true   "Challenge ID"
+// @Param        file         formData  file    true   "Attachment"
+// @Param        templated    formData  bool    false  "Swap the placeholder for the team's flag on download"
+// @Param        placeholder  formData  string  false  "Placeholder, {{FLAG}} by default"
+// @Success      200          {object}  AttachmentResponse
+// @Failure      400          {object}  types.ErrorResponse
+// @Failure      404          {object}  types.ErrorResponse
+// @Failure      413          {object}  types.ErrorResponse
+// @Failure      500          {object}  types.ErrorResponse
+// @Router       /admin/challenges/{id}/attachments [post]
+func UploadAttachment(ctx *gin.Context) {
+	auditLog := utils.Logger.WithField("type", "audit")
+	var challenge models.Challenge
+	if err := models.DB.Select("id, is_static").First(&challenge, ctx.Param("id")).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, types.ErrorResponse{Error: "Challenge not found"})
+		return
+	}
+	maxSize := values.GetConfig().App.Storage.MaxUploadSize
+	if maxSize <= 0 {
+		maxSize = defaultMaxUploadSize
+	}
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxSize)
+	header, err := ctx.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			ctx.JSON(http.StatusRequestEntityTooLarge, types.ErrorResponse{Error: "File too large"})
+			return
+		}
+		auditLog.WithFields(logrus.Fields{
+			"event":  "upload_attachment",
+			"status": "failure",
+			"reason": "invalid_request",
+			"ip":     ctx.ClientIP(),
+		}).Warn("Invalid request in uploadAttachment")
+		ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+	name := filepath.Base(strings.ReplaceAll(header.Filename, "\\", "/"))
+	if name == "." || name == "/" || name == "" {
+		ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid file name"})
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+	defer file.Close()
+	templated, _ := strconv.ParseBool(ctx.PostForm("templated"))
+	var placeholder string
+	var content io.Reader = file
+	if templated {
+		placeholder = ctx.PostForm("placeholder")
+		if placeholder == "" {
+			placeholder = storage.DefaultPlaceholder
+		}
+		if err := templateChallenge(challenge); err != nil {
+			ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: err.Error()})
+			return
+		}
+		data, err := storage.ReadTemplate(file)
+		if errors.Is(err, storage.ErrTemplateTooLarge) {
+			ctx.JSON(http.StatusRequestEntityTooLarge, types.ErrorResponse{Error: err.Error()})
+			return
+		} else if err != nil {
+			ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid request"})
+			return
+		}
+		if found, err := storage.HasPlaceholder(data, placeholder); err != nil || !found {
+			auditLog.WithFields(logrus.Fields{
+				"event":        "upload_attachment",
+				"status":       "failure",
+				"reason":       "invalid_template",
+				"challenge_id": challenge.ID,
+				"ip":           ctx.ClientIP(),
+			}).Warn("Templated attachment has no placeholder")
+			ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: storage.ErrNoPlaceholder.Error()})
+			return
+		}
+		content = bytes.NewReader(data)
+	}
+	key, err := newStorageKey()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to store file"})
+		return
+	}
+	hash := sha256.New()
+	size, err := storage.Put(ctx.Request.Context(), key, io.TeeReader(content, hash))
+	if err != nil {
+		auditLog.WithFields(logrus.Fields{
+			"event":        "upload_attachment",
+			"status":       "failure",
+			"reason":       "storage_error",
+			"challenge_id": challenge.ID,
+			"ip":           ctx.ClientIP(),
+			"error":        err.Error(),
+		}).Error("Failed to store attachment")
+		ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to store file"})
+		return
+	}
+	contentType := mime.TypeByExtension(filepath.Ext(name))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	attachment := models.Attachment{
+		ChallengeID: challenge.ID,
+		Name:        name,
+		ContentType: contentType,
+		Size:        size,
+		SHA256:      hex.EncodeToString(hash.Sum(nil)),
+		Key:         key,
+		Templated:   templated,
+		Placeholder: placeholder,
+	}
+	if err := models.DB.Create(&attachment).Error; err != nil {
+		storage.Delete(ctx.Request.Context(), key)
+		auditLog.WithFields(logrus.Fields{
+			"event":  "upload_attachment",
+			"status": "failure",
+			"reason": "database_error",
+			"ip":     ctx.ClientIP(),
+		}).Error("Database error in uploadAttachment")
+		ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Database error"})
+		return
+	}
+	shared.AttachmentCache.Delete(challenge.ID)
+	auditLog.WithFields(logrus.Fields{
+		"event":         "upload_attachment",
+		"status":        "success",
+		"challenge_id":  challenge.ID,
+		"attachment_id": attachment.ID,
+		"size":          size,
+		"templated":     templated,
+		"ip":            ctx.ClientIP(),
+	}).Info("Attachment uploaded successfully")
+	ctx.JSON(http.StatusOK, toAttachmentResponse(attachment))
+}
+
+// GetAttachments godoc
+// @Summary      List challenge attachments
+// @Description  Lists the files attached to a challenge
+// @Security     BearerAuth
+// @Tags         admin
+// @Produce      json
+// @Param        id   path      int  true  "Challenge ID"
+// @Success      200  {array}   AttachmentResponse
+// @Failure      500  {object}  types.ErrorResponse
+// @Router       /admin/challenges/{id}/attachments [get]
+func GetAttachments(ctx *gin.Context) {
+	auditLog := utils.Logger.WithField("type", "audit")
+	var attachments []models.Attachment
+	if err := models.DB.Where("challenge_id = ?", ctx.Param("id")).Order("id").Find(&attachments).Error; err != nil {
+		auditLog.WithFields(logrus.Fields{
+			"event":  "get_attachments",
+			"status": "failure",
+			"reason": "database_error",
+			"ip":     ctx.ClientIP(),
+		}).Error("Database error in getAttachments")
+		ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Database error"})
+		return
+	}
+	resp := make([]AttachmentResponse, 0, len(attachments))
+	for _, a := range attachments {
+		resp = append(resp, toAttachmentResponse(a))
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// DeleteAttachment godoc
+// @Summary      Delete a challenge attachment
+// @Description  Removes an attachment and its stored file
+// @Security     BearerAuth
+// @Tags         admin
+// @Produce      json
+// @Param        id             path      int  true  "Challenge ID"
+// @Param        attachment_id  path      int  true  "Attachment ID"
+// @Success      200            {object}  types.SuccessResponse
+// @Failure      404            {object}  types.ErrorResponse
+// @Failure      500            {object}  types.ErrorResponse
+// @Router       /admin/challenges/{id}/attachments/{attachment_id} [delete]
+func DeleteAttachment(ctx *gin.Context) {
+	auditLog := utils.Logger.WithField("type", "audit")
+	var attachment models.Attachment
+	if err := models.DB.Where("challenge_id = ?", ctx.Param("id")).First(&attachment, ctx.Param("attachment_id")).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, types.ErrorResponse{Error: "Attachment not found"})
+		return
+	}
+	if err := models.DB.Unscoped().Delete(&attachment).Error; err != nil {
+		auditLog.WithFields(logrus.Fields{
+			"event":  "delete_attachment",
+			"status": "failure",
+			"reason": "database_error",
+			"ip":     ctx.ClientIP(),
+		}).Error("Database error in deleteAttachment")
+		ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Database error"})
+		return
+	}
+	shared.AttachmentCache.Delete(attachment.ChallengeID)
+	// the row is gone so nobody can download it any more, a leftover file is only wasted space
+	if err := storage.Delete(ctx.Request.Context(), attachment.Key); err != nil {
+		auditLog.WithFields(logrus.Fields{
+			"event":         "delete_attachment",
+			"status":        "partial_failure",
+			"reason":        "storage_error",
+			"attachment_id": attachment.ID,
+			"ip":            ctx.ClientIP(),
+			"error":         err.Error(),
+		}).Warn("Failed to remove attachment file")
+	}
+	auditLog.WithFields(logrus.Fields{
+		"event":         "delete_attachment",
+		"status":        "success",
+		"challenge_id":  attachment.ChallengeID,
+		"attachment_id": attachment.ID,
+		"ip":            ctx.ClientIP(),
+	}).Info("Attachment deleted successfully")
+	ctx.JSON(http.StatusOK, types.SuccessResponse{Message: "Deleted successfully"})
+}
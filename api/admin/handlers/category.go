@@ -0,0 +1,304 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/intraware/rodan/api/shared"
+	"github.com/intraware/rodan/internal/models"
+	"github.com/intraware/rodan/internal/types"
+	"github.com/intraware/rodan/internal/utils"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// labelRow is a Category or Difficulty, both are a models.Label underneath
+type labelRow[T any] interface {
+	*T
+	Base() *models.Label
+}
+
+func toLabelResponse(l *models.Label) LabelResponse {
+	return LabelResponse{ID: l.ID, Name: l.Name, Color: l.Color, Order: l.SortOrder}
+}
+
+// validateLabels checks that the category and difficulty a challenge points at exist
+func validateLabels(categoryID, difficultyID *uint) error {
+	if categoryID != nil {
+		if err := models.DB.First(&models.Category{}, *categoryID).Error; err != nil {
+			return errors.New("category not found")
+		}
+	}
+	if difficultyID != nil {
+		if err := models.DB.First(&models.Difficulty{}, *difficultyID).Error; err != nil {
+			return errors.New("difficulty not found")
+		}
+	}
+	return nil
+}
+
+// invalidateLabels drops everything that embeds resolved label names
+func invalidateLabels() {
+	shared.CategoryCache.Reset()
+	shared.DifficultyCache.Reset()
+	shared.ChallengeListCache.Reset()
+}
+
+func listLabels[T any, PT labelRow[T]](ctx *gin.Context, event string) {
+	auditLog := utils.Logger.WithField("type", "audit")
+	var rows []T
+	if err := models.DB.Order("sort_order, id").Find(&rows).Error; err != nil {
+		auditLog.WithFields(logrus.Fields{
+			"event":  event,
+			"status": "failure",
+			"reason": "database_error",
+			"ip":     ctx.ClientIP(),
+		}).Error("Database error in " + event)
+		ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Database error"})
+		return
+	}
+	resp := make([]LabelResponse, 0, len(rows))
+	for i := range rows {
+		resp = append(resp, toLabelResponse(PT(&rows[i]).Base()))
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// saveLabel creates the label when row is new, otherwise updates it in place
+func saveLabel[T any, PT labelRow[T]](ctx *gin.Context, event string, row PT) {
+	auditLog := utils.Logger.WithField("type", "audit")
+	var req LabelResponse
+	if err := ctx.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Name) == "" {
+		auditLog.WithFields(logrus.Fields{
+			"event":  event,
+			"status": "failure",
+			"reason": "invalid_request",
+			"ip":     ctx.ClientIP(),
+		}).Warn("Invalid request in " + event)
+		ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+	label := row.Base()
+	label.Name = strings.TrimSpace(req.Name)
+	label.Color = req.Color
+	label.SortOrder = req.Order
+	if err := models.DB.Save(row).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			ctx.JSON(http.StatusConflict, types.ErrorResponse{Error: "Name already in use"})
+			return
+		}
+		auditLog.WithFields(logrus.Fields{
+			"event":  event,
+			"status": "failure",
+			"reason": "database_error",
+			"ip":     ctx.ClientIP(),
+		}).Error("Database error in " + event)
+		ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Database error"})
+		return
+	}
+	invalidateLabels()
+	auditLog.WithFields(logrus.Fields{
+		"event":    event,
+		"status":   "success",
+		"label_id": label.ID,
+		"ip":       ctx.ClientIP(),
+	}).Info("Label saved successfully")
+	ctx.JSON(http.StatusOK, toLabelResponse(label))
+}
+
+func updateLabel[T any, PT labelRow[T]](ctx *gin.Context, event string) {
+	row := PT(new(T))
+	if err := models.DB.First(row, ctx.Param("id")).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, types.ErrorResponse{Error: "Not found"})
+		return
+	}
+	saveLabel[T, PT](ctx, event, row)
+}
+
+// deleteLabel refuses to delete a label that challenges or prerequisites still use
+func deleteLabel[T any, PT labelRow[T]](ctx *gin.Context, event, column string) {
+	auditLog := utils.Logger.WithField("type", "audit")
+	row := PT(new(T))
+	if err := models.DB.First(row, ctx.Param("id")).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, types.ErrorResponse{Error: "Not found"})
+		return
+	}
+	id := row.Base().ID
+	var used int64
+	err := models.DB.Model(&models.Challenge{}).Where(column+" = ?", id).Count(&used).Error
+	if err == nil && used == 0 && column == "category_id" {
+		err = models.DB.Model(&models.ChallengePrerequisite{}).Where("category_id = ?", id).Count(&used).Error
+	}
+	if err != nil {
+		auditLog.WithFields(logrus.Fields{
+			"event":  event,
+			"status": "failure",
+			"reason": "database_error",
+			"ip":     ctx.ClientIP(),
+		}).Error("Database error in " + event)
+		ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Database error"})
+		return
+	}
+	if used > 0 {
+		auditLog.WithFields(logrus.Fields{
+			"event":    event,
+			"status":   "failure",
+			"reason":   "in_use",
+			"label_id": id,
+			"ip":       ctx.ClientIP(),
+		}).Warn("Label is still in use in " + event)
+		ctx.JSON(http.StatusConflict, types.ErrorResponse{Error: "Still used by challenges"})
+		return
+	}
+	// deleted for good, a soft deleted row would keep its name taken
+	if err := models.DB.Unscoped().Delete(row).Error; err != nil {
+		auditLog.WithFields(logrus.Fields{
+			"event":  event,
+			"status": "failure",
+			"reason": "database_error",
+			"ip":     ctx.ClientIP(),
+		}).Error("Database error in " + event)
+		ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Database error"})
+		return
+	}
+	invalidateLabels()
+	auditLog.WithFields(logrus.Fields{
+		"event":    event,
+		"status":   "success",
+		"label_id": id,
+		"ip":       ctx.ClientIP(),
+	}).Info("Label deleted successfully")
+	ctx.JSON(http.StatusOK, types.SuccessResponse{Message: "Deleted successfully"})
+}
+
+// GetCategories godoc
+// @Summary      List categories
+// @Description  Lists challenge categories in display order
+// @Security     BearerAuth
+// @Tags         admin
+// @Produce      json
+// @Success      200  {array}   LabelResponse
+// @Failure      500  {object}  types.ErrorResponse
+// @Router       /admin/categories [get]
+func GetCategories(ctx *gin.Context) {
+	listLabels[models.Category](ctx, "get_categories")
+}
+
+// AddCategory godoc
+// @Summary      Add a category
+// @Description  Creates a challenge category
+// @Security     BearerAuth
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        category  body      LabelResponse  true  "Category"
+// @Success      200       {object}  LabelResponse
+// @Failure      400       {object}  types.ErrorResponse
+// @Failure      409       {object}  types.ErrorResponse
+// @Failure      500       {object}  types.ErrorResponse
+// @Router       /admin/categories [post]
+func AddCategory(ctx *gin.Context) {
+	saveLabel(ctx, "add_category", &models.Category{})
+}
+
+// UpdateCategory godoc
+// @Summary      Update a category
+// @Description  Renames, recolors or reorders a challenge category
+// @Security     BearerAuth
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id        path      int            true  "Category ID"
+// @Param        category  body      LabelResponse  true  "Category"
+// @Success      200       {object}  LabelResponse
+// @Failure      400       {object}  types.ErrorResponse
+// @Failure      404       {object}  types.ErrorResponse
+// @Failure      409       {object}  types.ErrorResponse
+// @Failure      500       {object}  types.ErrorResponse
+// @Router       /admin/categories/{id} [patch]
+func UpdateCategory(ctx *gin.Context) {
+	updateLabel[models.Category](ctx, "update_category")
+}
+
+// DeleteCategory godoc
+// @Summary      Delete a category
+// @Description  Deletes a category that no challenge or prerequisite uses
+// @Security     BearerAuth
+// @Tags         admin
+// @Produce      json
+// @Param        id   path      int  true  "Category ID"
+// @Success      200  {object}  types.SuccessResponse
+// @Failure      404  {object}  types.ErrorResponse
+// @Failure      409  {object}  types.ErrorResponse
+// @Failure      500  {object}  types.ErrorResponse
+// @Router       /admin/categories/{id} [delete]
+func DeleteCategory(ctx *gin.Context) {
+	deleteLabel[models.Category](ctx, "delete_category", "category_id")
+}
+
+// GetDifficulties godoc
+// @Summary      List difficulties
+// @Description  Lists challenge difficulties in display order
+// @Security     BearerAuth
+// @Tags         admin
+// @Produce      json
+// @Success      200  {array}   LabelResponse
+// @Failure      500  {object}  types.ErrorResponse
+// @Router       /admin/difficulties [get]
+func GetDifficulties(ctx *gin.Context) {
+	listLabels[models.Difficulty](ctx, "get_difficulties")
+}
+
+// AddDifficulty godoc
+// @Summary      Add a difficulty
+// @Description  Creates a challenge difficulty
+// @Security     BearerAuth
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        difficulty  body      LabelResponse  true  "Difficulty"
+// @Success      200         {object}  LabelResponse
+// @Failure      400         {object}  types.ErrorResponse
+// @Failure      409         {object}  types.ErrorResponse
+// @Failure      500         {object}  types.ErrorResponse
+// @Router       /admin/difficulties [post]
+func AddDifficulty(ctx *gin.Context) {
+	saveLabel(ctx, "add_difficulty", &models.Difficulty{})
+}
+
+// UpdateDifficulty godoc
+// @Summary      Update a difficulty
+// @Description  Renames, recolors or reorders a challenge difficulty
+// @Security     BearerAuth
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id          path      int            true  "Difficulty ID"
+// @Param        difficulty  body      LabelResponse  true  "Difficulty"
+// @Success      200         {object}  LabelResponse
+// @Failure      400         {object}  types.ErrorResponse
+// @Failure      404         {object}  types.ErrorResponse
+// @Failure      409         {object}  types.ErrorResponse
+// @Failure      500         {object}  types.ErrorResponse
+// @Router       /admin/difficulties/{id} [patch]
+func UpdateDifficulty(ctx *gin.Context) {
+	updateLabel[models.Difficulty](ctx, "update_difficulty")
+}
+
+// DeleteDifficulty godoc
+// @Summary      Delete a difficulty
+// @Description  Deletes a difficulty that no challenge uses
+// @Security     BearerAuth
+// @Tags         admin
+// @Produce      json
+// @Param        id   path      int  true  "Difficulty ID"
+// @Success      200  {object}  types.SuccessResponse
+// @Failure      404  {object}  types.ErrorResponse
+// @Failure      409  {object}  types.ErrorResponse
+// @Failure      500  {object}  types.ErrorResponse
+// @Router       /admin/difficulties/{id} [delete]
+func DeleteDifficulty(ctx *gin.Context) {
+	deleteLabel[models.Difficulty](ctx, "delete_difficulty", "difficulty_id")
+}
@@ -4,28 +4,97 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/intraware/rodan/api/releases"
+	"github.com/intraware/rodan/api/shared"
+	"github.com/intraware/rodan/internal/flags"
 	"github.com/intraware/rodan/internal/models"
+	"github.com/intraware/rodan/internal/sandbox"
+	"github.com/intraware/rodan/internal/scoring"
 	"github.com/intraware/rodan/internal/types"
 	"github.com/intraware/rodan/internal/utils"
+	"github.com/intraware/rodan/internal/utils/values"
 	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 )
 
 func ToChallengeResponse(c models.Challenge) ChallengeResponse {
-	return ChallengeResponse{
+	resp := ChallengeResponse{
 		ID:            c.ID,
 		Name:          c.Name,
 		Author:        c.Author,
 		Desc:          c.Desc,
-		Category:      c.Category,
+		CategoryID:    c.CategoryID,
+		Category:      shared.CategoryLabel(c.CategoryID),
 		PointsMin:     c.PointsMin,
 		PointsMax:     c.PointsMax,
-		Difficulty:    c.Difficulty,
+		Scoring:       c.Scoring,
+		Decay:         c.Decay,
+		Tags:          c.Tags,
+		DifficultyID:  c.DifficultyID,
+		Difficulty:    shared.DifficultyLabel(c.DifficultyID),
 		IsStatic:      c.IsStatic,
 		IsVisible:     c.IsVisible,
+		ReleaseAt:     c.ReleaseAt,
+		HideAt:        c.HideAt,
 		StaticConfig:  c.StaticConfig,
 		DynamicConfig: c.DynamicConfig,
+		Flags:         c.Flags,
+		Prerequisites: c.Prerequisites,
 		Hints:         c.Hints,
 	}
+	// stored flags are hashes (or regexes that give the flag away), never send them back
+	if c.StaticConfig != nil {
+		static := *c.StaticConfig
+		static.Flag = ""
+		resp.StaticConfig = &static
+	}
+	if len(c.Flags) > 0 {
+		resp.Flags = make([]models.FlagMatcher, len(c.Flags))
+		for i, m := range c.Flags {
+			m.Flag = ""
+			resp.Flags[i] = m
+		}
+	}
+	return resp
+}
+
+// keepStoredFlags lets an admin send back a challenge as it was returned, with the
+// flags redacted, without wiping them. A blank flag keeps what is stored.
+func keepStoredFlags(req *ChallengeResponse, stored models.Challenge) {
+	if req.StaticConfig != nil && req.StaticConfig.Flag == "" && stored.StaticConfig != nil {
+		req.StaticConfig.Flag = stored.StaticConfig.Flag
+	}
+	byID := make(map[uint]models.FlagMatcher, len(stored.Flags))
+	for _, m := range stored.Flags {
+		byID[m.ID] = m
+	}
+	for i, m := range req.Flags {
+		if existing, ok := byID[m.ID]; ok && m.Flag == "" {
+			// the hash depends on how the flag was normalized, so keep the whole matcher
+			req.Flags[i] = existing
+		}
+	}
+}
+
+// sealFlags hashes the plaintext flags of a request before they are stored
+func sealFlags(req *ChallengeResponse) {
+	secret := values.GetConfig().Server.Security.FlagSecret
+	if req.StaticConfig != nil && req.StaticConfig.Flag != "" && !flags.IsHashed(req.StaticConfig.Flag) {
+		req.StaticConfig.Flag = flags.Hash(secret, req.StaticConfig.Flag)
+	}
+	for i, m := range req.Flags {
+		req.Flags[i] = flags.Seal(m, secret)
+	}
+}
+
+// validateFlags checks every flag matcher in the request
+func validateFlags(matchers []models.FlagMatcher) error {
+	for _, m := range matchers {
+		if err := flags.Validate(m); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // GetAllChallenges godoc
@@ -41,7 +110,7 @@ func ToChallengeResponse(c models.Challenge) ChallengeResponse {
 func GetAllChallenges(ctx *gin.Context) {
 	auditLog := utils.Logger.WithField("type", "audit")
 	var challenges []models.Challenge
-	if err := models.DB.Preload("Hints").Preload("StaticConfig").Preload("DynamicConfig").Find(&challenges).Error; err != nil {
+	if err := models.DB.Preload("Hints").Preload("StaticConfig").Preload("DynamicConfig").Preload("Flags").Preload("Prerequisites").Find(&challenges).Error; err != nil {
 		auditLog.WithFields(logrus.Fields{
 			"event":  "get_all_challenges",
 			"status": "failure",
@@ -89,18 +158,96 @@ func AddChallenge(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid request"})
 		return
 	}
+	if err := scoring.Validate(req.Scoring); err != nil {
+		auditLog.WithFields(logrus.Fields{
+			"event":   "add_challenge",
+			"status":  "failure",
+			"reason":  "invalid_scoring",
+			"scoring": req.Scoring,
+			"ip":      ctx.ClientIP(),
+		}).Warn("Unknown scoring strategy in addChallenge")
+		ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err := validateLabels(req.CategoryID, req.DifficultyID); err != nil {
+		auditLog.WithFields(logrus.Fields{
+			"event":  "add_challenge",
+			"status": "failure",
+			"reason": "invalid_label",
+			"ip":     ctx.ClientIP(),
+		}).Warn("Unknown category or difficulty in addChallenge")
+		ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err := validateSchedule(req.ReleaseAt, req.HideAt); err != nil {
+		auditLog.WithFields(logrus.Fields{
+			"event":  "add_challenge",
+			"status": "failure",
+			"reason": "invalid_schedule",
+			"ip":     ctx.ClientIP(),
+		}).Warn("Invalid release schedule in addChallenge")
+		ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if req.DynamicConfig != nil {
+		err := sandbox.ValidateLimits(*req.DynamicConfig, values.GetConfig().Docker.Sandbox)
+		if err == nil {
+			err = sandbox.ValidateServices(req.DynamicConfig.Services)
+		}
+		if err == nil {
+			err = sandbox.ValidateProxyPorts(*req.DynamicConfig)
+		}
+		if err != nil {
+			auditLog.WithFields(logrus.Fields{
+				"event":  "add_challenge",
+				"status": "failure",
+				"reason": "invalid_sandbox_config",
+				"ip":     ctx.ClientIP(),
+			}).Warn("Invalid sandbox config in addChallenge")
+			ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: err.Error()})
+			return
+		}
+	}
+	if err := validateFlags(req.Flags); err != nil {
+		auditLog.WithFields(logrus.Fields{
+			"event":  "add_challenge",
+			"status": "failure",
+			"reason": "invalid_flag",
+			"ip":     ctx.ClientIP(),
+		}).Warn("Invalid flag matcher in addChallenge")
+		ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err := validatePrerequisites(0, req.Prerequisites); err != nil {
+		auditLog.WithFields(logrus.Fields{
+			"event":  "add_challenge",
+			"status": "failure",
+			"reason": "invalid_prerequisites",
+			"ip":     ctx.ClientIP(),
+		}).Warn("Invalid prerequisites in addChallenge")
+		ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: err.Error()})
+		return
+	}
+	sealFlags(&req)
 	challenge := models.Challenge{
 		Name:          req.Name,
 		Author:        req.Author,
 		Desc:          req.Desc,
-		Category:      req.Category,
+		CategoryID:    req.CategoryID,
 		PointsMin:     req.PointsMin,
 		PointsMax:     req.PointsMax,
-		Difficulty:    req.Difficulty,
+		Scoring:       req.Scoring,
+		Decay:         req.Decay,
+		Tags:          req.Tags,
+		DifficultyID:  req.DifficultyID,
 		IsStatic:      req.IsStatic,
 		IsVisible:     req.IsVisible,
+		ReleaseAt:     req.ReleaseAt,
+		HideAt:        req.HideAt,
 		StaticConfig:  req.StaticConfig,
 		DynamicConfig: req.DynamicConfig,
+		Flags:         req.Flags,
+		Prerequisites: req.Prerequisites,
 		Hints:         req.Hints,
 	}
 	if err := models.DB.Create(&challenge).Error; err != nil {
@@ -119,6 +266,13 @@ func AddChallenge(ctx *gin.Context) {
 		"challenge_id": challenge.ID,
 		"ip":           ctx.ClientIP(),
 	}).Info("Challenge added successfully")
+	shared.ChallengeListCache.Reset()
+	// a lookup of this id before it existed may have cached no prerequisites
+	shared.PrerequisiteCache.Delete(challenge.ID)
+	sandbox.RefreshWarmPool()
+	if challenge.IsVisible {
+		releases.Announce(challenge)
+	}
 	ctx.JSON(http.StatusOK, ToChallengeResponse(challenge))
 }
 
@@ -149,26 +303,125 @@ func UpdateChallenge(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid request"})
 		return
 	}
+	if err := scoring.Validate(req.Scoring); err != nil {
+		auditLog.WithFields(logrus.Fields{
+			"event":   "update_challenge",
+			"status":  "failure",
+			"reason":  "invalid_scoring",
+			"scoring": req.Scoring,
+			"ip":      ctx.ClientIP(),
+		}).Warn("Unknown scoring strategy in updateChallenge")
+		ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err := validateLabels(req.CategoryID, req.DifficultyID); err != nil {
+		auditLog.WithFields(logrus.Fields{
+			"event":  "update_challenge",
+			"status": "failure",
+			"reason": "invalid_label",
+			"ip":     ctx.ClientIP(),
+		}).Warn("Unknown category or difficulty in updateChallenge")
+		ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err := validateSchedule(req.ReleaseAt, req.HideAt); err != nil {
+		auditLog.WithFields(logrus.Fields{
+			"event":  "update_challenge",
+			"status": "failure",
+			"reason": "invalid_schedule",
+			"ip":     ctx.ClientIP(),
+		}).Warn("Invalid release schedule in updateChallenge")
+		ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if req.DynamicConfig != nil {
+		err := sandbox.ValidateLimits(*req.DynamicConfig, values.GetConfig().Docker.Sandbox)
+		if err == nil {
+			err = sandbox.ValidateServices(req.DynamicConfig.Services)
+		}
+		if err == nil {
+			err = sandbox.ValidateProxyPorts(*req.DynamicConfig)
+		}
+		if err != nil {
+			auditLog.WithFields(logrus.Fields{
+				"event":  "update_challenge",
+				"status": "failure",
+				"reason": "invalid_sandbox_config",
+				"ip":     ctx.ClientIP(),
+			}).Warn("Invalid sandbox config in updateChallenge")
+			ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: err.Error()})
+			return
+		}
+	}
 	var challenge models.Challenge
-	if err := models.DB.Preload("Hints").Preload("StaticConfig").Preload("DynamicConfig").First(&challenge, id).Error; err != nil {
+	if err := models.DB.Preload("Hints").Preload("StaticConfig").Preload("DynamicConfig").Preload("Flags").Preload("Prerequisites").First(&challenge, id).Error; err != nil {
 		ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Challenge not found"})
 		return
 	}
+	keepStoredFlags(&req, challenge)
+	if err := validateFlags(req.Flags); err != nil {
+		auditLog.WithFields(logrus.Fields{
+			"event":  "update_challenge",
+			"status": "failure",
+			"reason": "invalid_flag",
+			"ip":     ctx.ClientIP(),
+		}).Warn("Invalid flag matcher in updateChallenge")
+		ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err := validatePrerequisites(challenge.ID, req.Prerequisites); err != nil {
+		auditLog.WithFields(logrus.Fields{
+			"event":        "update_challenge",
+			"status":       "failure",
+			"reason":       "invalid_prerequisites",
+			"challenge_id": challenge.ID,
+			"ip":           ctx.ClientIP(),
+		}).Warn("Invalid prerequisites in updateChallenge")
+		ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: err.Error()})
+		return
+	}
+	sealFlags(&req)
+	wasVisible := challenge.IsVisible
 	// Update fields
 	challenge.Name = req.Name
 	challenge.Author = req.Author
 	challenge.Desc = req.Desc
-	challenge.Category = req.Category
+	challenge.CategoryID = req.CategoryID
 	challenge.PointsMin = req.PointsMin
 	challenge.PointsMax = req.PointsMax
-	challenge.Difficulty = req.Difficulty
+	challenge.Scoring = req.Scoring
+	challenge.Decay = req.Decay
+	challenge.Tags = req.Tags
+	challenge.DifficultyID = req.DifficultyID
 	challenge.IsStatic = req.IsStatic
 	challenge.IsVisible = req.IsVisible
+	challenge.ReleaseAt = req.ReleaseAt
+	challenge.HideAt = req.HideAt
 	challenge.StaticConfig = req.StaticConfig
 	challenge.DynamicConfig = req.DynamicConfig
 	challenge.Hints = req.Hints
+	challenge.Flags = nil
+	challenge.Prerequisites = nil
 
-	if err := models.DB.Save(&challenge).Error; err != nil {
+	if err := models.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&challenge).Error; err != nil {
+			return err
+		}
+		// the flag list is replaced as a whole, matchers left out of the request are removed
+		if err := tx.Unscoped().Where("challenge_id = ?", challenge.ID).Delete(&models.FlagMatcher{}).Error; err != nil {
+			return err
+		}
+		for i := range req.Flags {
+			req.Flags[i].ID = 0
+			req.Flags[i].ChallengeID = challenge.ID
+		}
+		if len(req.Flags) > 0 {
+			if err := tx.Create(&req.Flags).Error; err != nil {
+				return err
+			}
+		}
+		return replacePrerequisites(tx, challenge.ID, req.Prerequisites)
+	}); err != nil {
 		auditLog.WithFields(logrus.Fields{
 			"event":  "update_challenge",
 			"status": "failure",
@@ -178,12 +431,24 @@ func UpdateChallenge(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Database error"})
 		return
 	}
+	challenge.Flags = req.Flags
+	challenge.Prerequisites = req.Prerequisites
+	shared.PrerequisiteCache.Delete(challenge.ID)
+	// per_team decides how flags are checked, stale copies would verify the wrong way
+	shared.ChallengeCache.Delete(challenge.ID)
+	shared.StaticConfig.Delete(challenge.ID)
+	shared.FlagOwnerIndex.Delete(challenge.ID)
 	auditLog.WithFields(logrus.Fields{
 		"event":        "update_challenge",
 		"status":       "success",
 		"challenge_id": challenge.ID,
 		"ip":           ctx.ClientIP(),
 	}).Info("Challenge updated successfully")
+	shared.ChallengeListCache.Reset()
+	sandbox.RefreshWarmPool()
+	if challenge.IsVisible && !wasVisible {
+		releases.Announce(challenge)
+	}
 	ctx.JSON(http.StatusOK, ToChallengeResponse(challenge))
 }
 
@@ -207,7 +472,13 @@ func DeleteChallenge(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Challenge not found"})
 		return
 	}
-	if err := models.DB.Delete(&challenge).Error; err != nil {
+	if err := models.DB.Transaction(func(tx *gorm.DB) error {
+		// a deleted challenge can no longer be solved, so nothing may stay locked behind it
+		if err := tx.Unscoped().Where("challenge_id = ? OR requires_id = ?", challenge.ID, challenge.ID).Delete(&models.ChallengePrerequisite{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&challenge).Error
+	}); err != nil {
 		auditLog.WithFields(logrus.Fields{
 			"event":  "delete_challenge",
 			"status": "failure",
@@ -223,6 +494,10 @@ func DeleteChallenge(ctx *gin.Context) {
 		"challenge_id": challenge.ID,
 		"ip":           ctx.ClientIP(),
 	}).Info("Challenge deleted successfully")
+	shared.PrerequisiteCache.Reset()
+	shared.ChallengeListCache.Reset()
+	shared.FlagOwnerIndex.Delete(challenge.ID)
+	sandbox.RefreshWarmPool()
 	ctx.JSON(http.StatusOK, types.SuccessResponse{Message: "Challenge deleted successfully"})
 }
 
@@ -246,6 +521,7 @@ func ChallengeVisible(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Challenge not found"})
 		return
 	}
+	wasVisible := challenge.IsVisible
 	challenge.IsVisible = true
 	if err := models.DB.Save(&challenge).Error; err != nil {
 		auditLog.WithFields(logrus.Fields{
@@ -263,6 +539,11 @@ func ChallengeVisible(ctx *gin.Context) {
 		"challenge_id": challenge.ID,
 		"ip":           ctx.ClientIP(),
 	}).Info("Challenge made visible successfully")
+	shared.ChallengeListCache.Reset()
+	sandbox.RefreshWarmPool()
+	if !wasVisible {
+		releases.Announce(challenge)
+	}
 	ctx.JSON(http.StatusOK, types.SuccessResponse{Message: "Challenge is now visible"})
 }
 
@@ -303,5 +584,7 @@ func ChallengeNotVisible(ctx *gin.Context) {
 		"challenge_id": challenge.ID,
 		"ip":           ctx.ClientIP(),
 	}).Info("Challenge made not visible successfully")
+	shared.ChallengeListCache.Reset()
+	sandbox.RefreshWarmPool()
 	ctx.JSON(http.StatusOK, types.SuccessResponse{Message: "Challenge is now not visible"})
 }
\ No newline at end of file
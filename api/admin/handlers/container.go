@@ -6,6 +6,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/intraware/rodan/api/shared"
+	"github.com/intraware/rodan/internal/sandbox"
 	"github.com/intraware/rodan/internal/types"
 	"github.com/intraware/rodan/internal/utils"
 	"github.com/intraware/rodan/internal/utils/docker"
@@ -26,6 +27,18 @@ func GetAllSandboxes(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, shared.SandBoxMap.DumpValues())
 }
 
+// GetWarmPool godoc
+// @Summary      Get warm pool metrics
+// @Description  Reports the ready, pending and hit/miss counts of the warm container pool per challenge
+// @Security     BearerAuth
+// @Tags         admin
+// @Produce      json
+// @Success      200  {array}   sandbox.PoolStat
+// @Router       /admin/containers/pool [get]
+func GetWarmPool(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, sandbox.WarmPoolStats())
+}
+
 // StopAllContainers godoc
 // @Summary      Stop all containers
 // @Description  Stops all running containers in the system
@@ -140,20 +153,29 @@ func StopTeamContainer(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid team ID"})
 		return
 	}
-	sandbox, ok := shared.SandBoxMap.Get(uint(teamID))
-	if !ok {
-		ctx.JSON(http.StatusNotFound, types.ErrorResponse{Error: "No active sandbox found for the specified team"})
-		return
+	sandboxes := shared.GetTeamSandBoxes(uint(teamID))
+	var stopped int
+	for _, sandbox := range sandboxes {
+		if !sandbox.Active {
+			continue
+		}
+		if err := sandbox.Stop(); err != nil {
+			auditLog.WithFields(logrus.Fields{
+				"event":     "stop_team_container",
+				"status":    "failure",
+				"reason":    "internal_error",
+				"team_id":   teamID,
+				"challenge": sandbox.ChallengeMeta.ID,
+				"ip":        ctx.ClientIP(),
+				"error":     err.Error(),
+			}).Error("Failed to stop team container")
+			ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to stop team container"})
+			return
+		}
+		stopped++
 	}
-	if err := sandbox.Stop(); err != nil {
-		auditLog.WithFields(logrus.Fields{
-			"event":   "stop_team_container",
-			"status":  "failure",
-			"reason":  "internal_error",
-			"team_id": teamID,
-			"ip":      ctx.ClientIP(),
-		}).Error("Failed to stop team container")
-		ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to stop team container"})
+	if stopped == 0 {
+		ctx.JSON(http.StatusNotFound, types.ErrorResponse{Error: "No active sandbox found for the specified team"})
 		return
 	}
 	auditLog.WithFields(logrus.Fields{
@@ -178,21 +200,29 @@ func StopTeamContainer(ctx *gin.Context) {
 // @Router       /admin/container/stop_challenge [delete]
 func StopChallengeContainer(ctx *gin.Context) {
 	auditLog := utils.Logger.WithField("type", "audit")
-	challengeID := ctx.Param("id")
-
-	// TODO: challenge container???????
-
-	// if err := container.StopChallenge(challengeID); err != nil {
-	// 	auditLog.WithFields(logrus.Fields{
-	// 		"event":        "stop_challenge_container",
-	// 		"status":       "failure",
-	// 		"reason":       "internal_error",
-	// 		"challenge_id": challengeID,
-	// 		"ip":           ctx.ClientIP(),
-	// 	}).Error("Failed to stop challenge container")
-	// 	ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to stop challenge container"})
-	// 	return
-	// }
+	challengeID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || challengeID <= 0 {
+		ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid challenge ID"})
+		return
+	}
+	for _, sandbox := range shared.SandBoxMap.DumpValues() {
+		if sandbox.ChallengeMeta.ID != uint(challengeID) || !sandbox.Active {
+			continue
+		}
+		if err := sandbox.Stop(); err != nil {
+			auditLog.WithFields(logrus.Fields{
+				"event":        "stop_challenge_container",
+				"status":       "failure",
+				"reason":       "internal_error",
+				"challenge_id": challengeID,
+				"team_id":      sandbox.TeamID,
+				"ip":           ctx.ClientIP(),
+				"error":        err.Error(),
+			}).Error("Failed to stop challenge container")
+			ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to stop challenge container"})
+			return
+		}
+	}
 	auditLog.WithFields(logrus.Fields{
 		"event":        "stop_challenge_container",
 		"status":       "success",
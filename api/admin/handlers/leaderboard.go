@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/intraware/rodan/api/leaderboard"
+)
+
+// GetLiveUserLeaderboard godoc
+// @Summary      Get live user leaderboard
+// @Description  Retrieves the current user standings, ignoring any scoreboard freeze
+// @Security     BearerAuth
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  []leaderboard.UserPoints
+// @Router       /admin/leaderboard/user [get]
+func GetLiveUserLeaderboard(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, leaderboard.GetLiveUserLeaderboard())
+}
+
+// GetLiveTeamLeaderboard godoc
+// @Summary      Get live team leaderboard
+// @Description  Retrieves the current team standings, ignoring any scoreboard freeze
+// @Security     BearerAuth
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  []leaderboard.TeamPoints
+// @Router       /admin/leaderboard/team [get]
+func GetLiveTeamLeaderboard(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, leaderboard.GetLiveTeamLeaderboard())
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/intraware/rodan/internal/models"
+	"gorm.io/gorm"
+)
+
+// validatePrerequisites checks the prerequisites requested for a challenge. challengeID
+// is 0 for a challenge that is not created yet, nothing can depend on it so it cannot
+// close a cycle.
+func validatePrerequisites(challengeID uint, reqs []models.ChallengePrerequisite) error {
+	var required []uint
+	for _, req := range reqs {
+		hasChallenge := req.RequiresID != nil
+		hasCategory := req.CategoryID != nil
+		if hasChallenge == hasCategory {
+			return errors.New("a prerequisite needs either requires_id or category_id")
+		}
+		if hasCategory {
+			if err := validateLabels(req.CategoryID, nil); err != nil {
+				return err
+			}
+		}
+		if hasCategory && req.MinPoints <= 0 {
+			return errors.New("a category prerequisite needs min_points > 0")
+		}
+		if hasChallenge {
+			if *req.RequiresID == challengeID {
+				return errors.New("a challenge cannot require itself")
+			}
+			required = append(required, *req.RequiresID)
+		}
+	}
+	if len(required) == 0 {
+		return nil
+	}
+	var found int64
+	if err := models.DB.Model(&models.Challenge{}).Where("id IN ?", required).Count(&found).Error; err != nil {
+		return err
+	}
+	if int(found) != len(uniqueIDs(required)) {
+		return errors.New("a required challenge does not exist")
+	}
+	if challengeID == 0 {
+		return nil
+	}
+	return checkPrerequisiteCycle(challengeID, required)
+}
+
+func uniqueIDs(ids []uint) map[uint]bool {
+	set := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
+
+// checkPrerequisiteCycle loads the stored dependency graph, with the challenge's own
+// edges swapped for the requested ones, and fails if the challenge can reach itself
+func checkPrerequisiteCycle(challengeID uint, required []uint) error {
+	var rows []models.ChallengePrerequisite
+	if err := models.DB.Where("requires_id IS NOT NULL AND challenge_id <> ?", challengeID).Find(&rows).Error; err != nil {
+		return err
+	}
+	edges := map[uint][]uint{}
+	for _, row := range rows {
+		edges[row.ChallengeID] = append(edges[row.ChallengeID], *row.RequiresID)
+	}
+	return findCycle(challengeID, required, edges)
+}
+
+// findCycle walks edges from the required challenges and fails if it gets back to
+// challengeID
+func findCycle(challengeID uint, required []uint, edges map[uint][]uint) error {
+	visited := map[uint]bool{}
+	stack := append([]uint(nil), required...)
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if current == challengeID {
+			return fmt.Errorf("prerequisites would form a cycle through challenge %d", challengeID)
+		}
+		if visited[current] {
+			continue
+		}
+		visited[current] = true
+		stack = append(stack, edges[current]...)
+	}
+	return nil
+}
+
+// replacePrerequisites swaps the stored prerequisites of a challenge for the given ones
+func replacePrerequisites(tx *gorm.DB, challengeID uint, reqs []models.ChallengePrerequisite) error {
+	if err := tx.Unscoped().Where("challenge_id = ?", challengeID).Delete(&models.ChallengePrerequisite{}).Error; err != nil {
+		return err
+	}
+	for i := range reqs {
+		reqs[i].ID = 0
+		reqs[i].ChallengeID = challengeID
+	}
+	if len(reqs) == 0 {
+		return nil
+	}
+	return tx.Create(&reqs).Error
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import "testing"
+
+func TestFindCycle(t *testing.T) {
+	cases := []struct {
+		name      string
+		challenge uint
+		required  []uint
+		edges     map[uint][]uint
+		cycle     bool
+	}{
+		{"no prerequisites", 1, nil, nil, false},
+		{"self loop", 1, []uint{1}, nil, true},
+		{"chain", 1, []uint{2}, map[uint][]uint{2: {3}, 3: {4}}, false},
+		{"two step cycle", 1, []uint{2}, map[uint][]uint{2: {1}}, true},
+		{"longer cycle", 1, []uint{2}, map[uint][]uint{2: {3}, 3: {4}, 4: {1}}, true},
+		{"cycle elsewhere", 1, []uint{2}, map[uint][]uint{2: {3}, 3: {2}}, false},
+		{"diamond", 1, []uint{2, 3}, map[uint][]uint{2: {4}, 3: {4}}, false},
+		{"diamond closing", 1, []uint{2, 3}, map[uint][]uint{2: {4}, 3: {4}, 4: {1}}, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := findCycle(tc.challenge, tc.required, tc.edges)
+			if tc.cycle && err == nil {
+				t.Fatal("expected a cycle to be reported")
+			}
+			if !tc.cycle && err != nil {
+				t.Fatalf("expected no cycle, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/intraware/rodan/internal/models"
+	"github.com/intraware/rodan/internal/types"
+	"github.com/intraware/rodan/internal/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// validateSchedule checks that a challenge is not hidden before it is released
+func validateSchedule(releaseAt, hideAt *time.Time) error {
+	if releaseAt != nil && hideAt != nil && !hideAt.After(*releaseAt) {
+		return errors.New("hide_at must be after release_at")
+	}
+	return nil
+}
+
+// GetUpcomingReleases godoc
+// @Summary      List upcoming releases
+// @Description  Lists challenges with a pending scheduled release or hide, soonest first
+// @Security     BearerAuth
+// @Tags         admin
+// @Produce      json
+// @Success      200  {array}   UpcomingReleaseResponse
+// @Failure      500  {object}  types.ErrorResponse
+// @Router       /admin/challenges/upcoming [get]
+func GetUpcomingReleases(ctx *gin.Context) {
+	auditLog := utils.Logger.WithField("type", "audit")
+	var challenges []models.Challenge
+	if err := models.DB.Select("id, name, is_visible, release_at, hide_at").
+		Where("release_at IS NOT NULL OR hide_at IS NOT NULL").
+		Order("LEAST(COALESCE(release_at, hide_at), COALESCE(hide_at, release_at)), id").
+		Find(&challenges).Error; err != nil {
+		auditLog.WithFields(logrus.Fields{
+			"event":  "get_upcoming_releases",
+			"status": "failure",
+			"reason": "database_error",
+			"ip":     ctx.ClientIP(),
+		}).Error("Database error in getUpcomingReleases")
+		ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Database error"})
+		return
+	}
+	resp := make([]UpcomingReleaseResponse, 0, len(challenges))
+	for _, c := range challenges {
+		resp = append(resp, UpcomingReleaseResponse{
+			ID:        c.ID,
+			Name:      c.Name,
+			IsVisible: c.IsVisible,
+			ReleaseAt: c.ReleaseAt,
+			HideAt:    c.HideAt,
+		})
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
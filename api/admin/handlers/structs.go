@@ -1,6 +1,11 @@
 package handlers
 
-import "github.com/intraware/rodan/internal/models"
+import (
+	"time"
+
+	"github.com/intraware/rodan/api/shared"
+	"github.com/intraware/rodan/internal/models"
+)
 
 // swagger:model
 type UserResponse struct {
@@ -26,17 +31,102 @@ type TeamResponse struct {
 
 // swagger:model
 type ChallengeResponse struct {
-	ID            uint                  `json:"id"`
-	Name          string                `json:"name"`
-	Author        string                `json:"author"`
-	Desc          string                `json:"desc"`
-	Category      int8                  `json:"category"`
-	PointsMin     int                   `json:"points_min"`
-	PointsMax     int                   `json:"points_max"`
-	Difficulty    int8                  `json:"difficulty"`
-	IsStatic      bool                  `json:"is_static"`
-	IsVisible     bool                  `json:"is_visible"`
-	StaticConfig  *models.StaticConfig  `json:"static_config,omitempty"`
-	DynamicConfig *models.DynamicConfig `json:"dynamic_config,omitempty"`
-	Hints         []models.Hint         `json:"hints,omitempty"`
+	ID            uint                           `json:"id"`
+	Name          string                         `json:"name"`
+	Author        string                         `json:"author"`
+	Desc          string                         `json:"desc"`
+	CategoryID    *uint                          `json:"category_id"`
+	Category      *shared.Label                  `json:"category,omitempty"`
+	PointsMin     int                            `json:"points_min"`
+	PointsMax     int                            `json:"points_max"`
+	Scoring       string                         `json:"scoring"`
+	Decay         int                            `json:"decay"`
+	Tags          []string                       `json:"tags,omitempty"`
+	DifficultyID  *uint                          `json:"difficulty_id"`
+	Difficulty    *shared.Label                  `json:"difficulty,omitempty"`
+	IsStatic      bool                           `json:"is_static"`
+	IsVisible     bool                           `json:"is_visible"`
+	ReleaseAt     *time.Time                     `json:"release_at,omitempty"`
+	HideAt        *time.Time                     `json:"hide_at,omitempty"`
+	StaticConfig  *models.StaticConfig           `json:"static_config,omitempty"`
+	DynamicConfig *models.DynamicConfig          `json:"dynamic_config,omitempty"`
+	Flags         []models.FlagMatcher           `json:"flags,omitempty"`
+	Prerequisites []models.ChallengePrerequisite `json:"prerequisites,omitempty"`
+	Hints         []models.Hint                  `json:"hints,omitempty"`
+}
+
+// swagger:model
+type AttachmentResponse struct {
+	ID          uint      `json:"id"`
+	ChallengeID uint      `json:"challenge_id"`
+	Name        string    `json:"name"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	SHA256      string    `json:"sha256"`
+	Templated   bool      `json:"templated"`
+	Placeholder string    `json:"placeholder,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// swagger:model
+type UpcomingReleaseResponse struct {
+	ID        uint       `json:"id"`
+	Name      string     `json:"name"`
+	IsVisible bool       `json:"is_visible"`
+	ReleaseAt *time.Time `json:"release_at,omitempty"`
+	HideAt    *time.Time `json:"hide_at,omitempty"`
+}
+
+// swagger:model
+type AnnouncementResponse struct {
+	ID          uint      `json:"id"`
+	Title       string    `json:"title"`
+	Body        string    `json:"body"`
+	ChallengeID *uint     `json:"challenge_id,omitempty"`
+	Pinned      bool      `json:"pinned"`
+	VisibleAt   time.Time `json:"visible_at"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// swagger:model
+type SubmissionResponse struct {
+	ID          uint      `json:"id"`
+	UserID      uint      `json:"user_id"`
+	TeamID      uint      `json:"team_id"`
+	ChallengeID uint      `json:"challenge_id"`
+	FlagHash    string    `json:"flag_hash"`
+	Correct     bool      `json:"correct"`
+	IP          string    `json:"ip"`
+	SubmittedAt time.Time `json:"submitted_at"`
+}
+
+// swagger:model
+type SubmissionStats struct {
+	ChallengeID uint  `json:"challenge_id"`
+	Total       int64 `json:"total"`
+	Correct     int64 `json:"correct"`
+	Wrong       int64 `json:"wrong"`
+	Teams       int64 `json:"teams"`
+}
+
+// swagger:model
+type FlagShareIncidentResponse struct {
+	ID              uint      `json:"id"`
+	ChallengeID     uint      `json:"challenge_id"`
+	SubmitterUserID uint      `json:"submitter_user_id"`
+	SubmitterTeamID uint      `json:"submitter_team_id"`
+	OwnerTeamID     uint      `json:"owner_team_id"`
+	FlagHash        string    `json:"flag_hash"`
+	Action          string    `json:"action"`
+	IP              string    `json:"ip"`
+	DetectedAt      time.Time `json:"detected_at"`
+}
+
+// swagger:model
+type LabelResponse struct {
+	ID    uint   `json:"id"`
+	Name  string `json:"name"`
+	Color string `json:"color"`
+	Order int    `json:"order"`
 }
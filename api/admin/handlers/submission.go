@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/intraware/rodan/internal/models"
+	"github.com/intraware/rodan/internal/types"
+	"github.com/intraware/rodan/internal/utils"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultSubmissionLimit = 100
+	maxSubmissionLimit     = 1000
+)
+
+// filterSubmissions applies the shared challenge/team/user/correct/time range query params
+func filterSubmissions(ctx *gin.Context, query *gorm.DB) (*gorm.DB, error) {
+	for _, param := range []string{"challenge_id", "team_id", "user_id"} {
+		if raw := ctx.Query(param); raw != "" {
+			id, err := strconv.ParseUint(raw, 10, 64)
+			if err != nil {
+				return nil, errors.New("Invalid " + param)
+			}
+			query = query.Where(param+" = ?", uint(id))
+		}
+	}
+	if raw := ctx.Query("correct"); raw != "" {
+		correct, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, errors.New("Invalid correct")
+		}
+		query = query.Where("correct = ?", correct)
+	}
+	for param, clause := range map[string]string{"from": "created_at >= ?", "to": "created_at < ?"} {
+		if raw := ctx.Query(param); raw != "" {
+			t, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				return nil, errors.New("Invalid " + param + ", expected RFC3339")
+			}
+			query = query.Where(clause, t)
+		}
+	}
+	return query, nil
+}
+
+// GetSubmissions godoc
+// @Summary      Query flag submissions
+// @Description  Lists recorded flag submissions, newest first, filtered by challenge, team, user, correctness and time range
+// @Security     BearerAuth
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        challenge_id  query     int     false  "Challenge ID"
+// @Param        team_id       query     int     false  "Team ID"
+// @Param        user_id       query     int     false  "User ID"
+// @Param        correct       query     bool    false  "Only correct or only wrong submissions"
+// @Param        from          query     string  false  "Start of time range (RFC3339)"
+// @Param        to            query     string  false  "End of time range (RFC3339)"
+// @Param        limit         query     int     false  "Page size (default 100, max 1000)"
+// @Param        offset        query     int     false  "Page offset"
+// @Success      200           {array}   SubmissionResponse
+// @Failure      400           {object}  types.ErrorResponse
+// @Failure      500           {object}  types.ErrorResponse
+// @Router       /admin/submissions [get]
+func GetSubmissions(ctx *gin.Context) {
+	auditLog := utils.Logger.WithField("type", "audit")
+	query, err := filterSubmissions(ctx, models.DB.Model(&models.Submission{}))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: err.Error()})
+		return
+	}
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", strconv.Itoa(defaultSubmissionLimit)))
+	if err != nil || limit <= 0 {
+		ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid limit"})
+		return
+	}
+	offset, err := strconv.Atoi(ctx.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid offset"})
+		return
+	}
+	var submissions []models.Submission
+	if err := query.Order("created_at DESC").Limit(min(limit, maxSubmissionLimit)).Offset(offset).Find(&submissions).Error; err != nil {
+		auditLog.WithFields(logrus.Fields{
+			"event":  "get_submissions",
+			"status": "failure",
+			"reason": "database_error",
+			"ip":     ctx.ClientIP(),
+		}).Error("Database error in getSubmissions")
+		ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Database error"})
+		return
+	}
+	resp := make([]SubmissionResponse, 0, len(submissions))
+	for _, s := range submissions {
+		resp = append(resp, SubmissionResponse{
+			ID:          s.ID,
+			UserID:      s.UserID,
+			TeamID:      s.TeamID,
+			ChallengeID: s.ChallengeID,
+			FlagHash:    s.FlagHash,
+			Correct:     s.Correct,
+			IP:          s.IP,
+			SubmittedAt: s.CreatedAt,
+		})
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// GetSubmissionStats godoc
+// @Summary      Submission statistics per challenge
+// @Description  Counts right and wrong submissions per challenge, with the same filters as the submission list
+// @Security     BearerAuth
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        challenge_id  query     int     false  "Challenge ID"
+// @Param        team_id       query     int     false  "Team ID"
+// @Param        user_id       query     int     false  "User ID"
+// @Param        from          query     string  false  "Start of time range (RFC3339)"
+// @Param        to            query     string  false  "End of time range (RFC3339)"
+// @Success      200           {array}   SubmissionStats
+// @Failure      400           {object}  types.ErrorResponse
+// @Failure      500           {object}  types.ErrorResponse
+// @Router       /admin/submissions/stats [get]
+func GetSubmissionStats(ctx *gin.Context) {
+	auditLog := utils.Logger.WithField("type", "audit")
+	query, err := filterSubmissions(ctx, models.DB.Model(&models.Submission{}))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: err.Error()})
+		return
+	}
+	var stats []SubmissionStats
+	if err := query.Select(
+		"challenge_id, COUNT(*) AS total, " +
+			"COUNT(*) FILTER (WHERE correct) AS correct, " +
+			"COUNT(*) FILTER (WHERE NOT correct) AS wrong, " +
+			"COUNT(DISTINCT team_id) AS teams",
+	).Group("challenge_id").Order("wrong DESC").Scan(&stats).Error; err != nil {
+		auditLog.WithFields(logrus.Fields{
+			"event":  "get_submission_stats",
+			"status": "failure",
+			"reason": "database_error",
+			"ip":     ctx.ClientIP(),
+		}).Error("Database error in getSubmissionStats")
+		ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Database error"})
+		return
+	}
+	ctx.JSON(http.StatusOK, stats)
+}
+
+// GetFlagShareIncidents godoc
+// @Summary      List flag sharing incidents
+// @Description  Lists dynamic flags submitted by a team other than the one they were generated for, newest first
+// @Security     BearerAuth
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        challenge_id  query     int  false  "Challenge ID"
+// @Param        team_id       query     int  false  "Submitting or owning team ID"
+// @Success      200           {array}   FlagShareIncidentResponse
+// @Failure      400           {object}  types.ErrorResponse
+// @Failure      500           {object}  types.ErrorResponse
+// @Router       /admin/incidents [get]
+func GetFlagShareIncidents(ctx *gin.Context) {
+	auditLog := utils.Logger.WithField("type", "audit")
+	query := models.DB.Model(&models.FlagShareIncident{})
+	if raw := ctx.Query("challenge_id"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid challenge_id"})
+			return
+		}
+		query = query.Where("challenge_id = ?", uint(id))
+	}
+	if raw := ctx.Query("team_id"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid team_id"})
+			return
+		}
+		query = query.Where("submitter_team_id = ? OR owner_team_id = ?", uint(id), uint(id))
+	}
+	var incidents []models.FlagShareIncident
+	if err := query.Order("created_at DESC").Find(&incidents).Error; err != nil {
+		auditLog.WithFields(logrus.Fields{
+			"event":  "get_incidents",
+			"status": "failure",
+			"reason": "database_error",
+			"ip":     ctx.ClientIP(),
+		}).Error("Database error in getFlagShareIncidents")
+		ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Database error"})
+		return
+	}
+	resp := make([]FlagShareIncidentResponse, 0, len(incidents))
+	for _, i := range incidents {
+		resp = append(resp, FlagShareIncidentResponse{
+			ID:              i.ID,
+			ChallengeID:     i.ChallengeID,
+			SubmitterUserID: i.SubmitterUserID,
+			SubmitterTeamID: i.SubmitterTeamID,
+			OwnerTeamID:     i.OwnerTeamID,
+			FlagHash:        i.FlagHash,
+			Action:          i.Action,
+			IP:              i.IP,
+			DetectedAt:      i.CreatedAt,
+		})
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
@@ -28,10 +28,14 @@ func LoadUser(r *gin.RouterGroup) {
 	challengeRouter := adminRouter.Group("/challenges")
 	challengeRouter.GET("/", middleware.CacheMiddleware, handlers.GetAllChallenges)
 	challengeRouter.POST("/", handlers.AddChallenge)
+	challengeRouter.GET("/upcoming", handlers.GetUpcomingReleases)
 	challengeRouter.PATCH("/:id", handlers.UpdateChallenge)
 	challengeRouter.DELETE("/:id", handlers.DeleteChallenge)
 	challengeRouter.POST("/:id/visible", handlers.ChallengeVisible)
 	challengeRouter.POST("/:id/not-visible", handlers.ChallengeNotVisible)
+	challengeRouter.GET("/:id/attachments", handlers.GetAttachments)
+	challengeRouter.POST("/:id/attachments", handlers.UploadAttachment)
+	challengeRouter.DELETE("/:id/attachments/:attachment_id", handlers.DeleteAttachment)
 
 	// User management
 	userRouter := adminRouter.Group("/users")
@@ -55,9 +59,40 @@ func LoadUser(r *gin.RouterGroup) {
 	teamRouter.POST("/:id/blacklist", handlers.BlacklistTeam)
 	teamRouter.POST("/:id/unblacklist", handlers.UnblacklistTeam)
 
+	// Submission audit
+	submissionRouter := adminRouter.Group("/submissions")
+	submissionRouter.GET("/", handlers.GetSubmissions)
+	submissionRouter.GET("/stats", handlers.GetSubmissionStats)
+	adminRouter.GET("/incidents", handlers.GetFlagShareIncidents)
+
+	// Categories and difficulties
+	categoryRouter := adminRouter.Group("/categories")
+	categoryRouter.GET("/", handlers.GetCategories)
+	categoryRouter.POST("/", handlers.AddCategory)
+	categoryRouter.PATCH("/:id", handlers.UpdateCategory)
+	categoryRouter.DELETE("/:id", handlers.DeleteCategory)
+	difficultyRouter := adminRouter.Group("/difficulties")
+	difficultyRouter.GET("/", handlers.GetDifficulties)
+	difficultyRouter.POST("/", handlers.AddDifficulty)
+	difficultyRouter.PATCH("/:id", handlers.UpdateDifficulty)
+	difficultyRouter.DELETE("/:id", handlers.DeleteDifficulty)
+
+	// Announcements
+	announcementRouter := adminRouter.Group("/announcements")
+	announcementRouter.GET("/", handlers.GetAllAnnouncements)
+	announcementRouter.POST("/", handlers.AddAnnouncement)
+	announcementRouter.PATCH("/:id", handlers.UpdateAnnouncement)
+	announcementRouter.DELETE("/:id", handlers.DeleteAnnouncement)
+
+	// Leaderboard (live standings, unaffected by the freeze)
+	leaderboardRouter := adminRouter.Group("/leaderboard")
+	leaderboardRouter.GET("/user", handlers.GetLiveUserLeaderboard)
+	leaderboardRouter.GET("/team", handlers.GetLiveTeamLeaderboard)
+
 	// Container management
 	containerRouter := adminRouter.Group("/containers")
 	containerRouter.GET("/", handlers.GetAllSandboxes)
+	containerRouter.GET("/pool", handlers.GetWarmPool)
 	containerRouter.DELETE("/:id/stop", handlers.StopContainer)
 	containerRouter.DELETE("/teams/:id/stop", handlers.StopTeamContainer)
 	containerRouter.DELETE("/challenges/:id/stop", handlers.StopChallengeContainer)
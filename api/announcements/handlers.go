@@ -0,0 +1,36 @@
+package announcements
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/intraware/rodan/internal/types"
+	"github.com/intraware/rodan/internal/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// getAnnouncements godoc
+// @Summary      List announcements
+// @Description  Retrieves published announcements, pinned ones first. Supports If-Modified-Since.
+// @Tags         announcements
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  []announcementItem
+// @Success      304
+// @Failure      500  {object}  types.ErrorResponse
+// @Router       /announcements [get]
+func getAnnouncements(ctx *gin.Context) {
+	items, _, err := visibleAnnouncements()
+	if err != nil {
+		utils.Logger.WithField("type", "audit").WithFields(logrus.Fields{
+			"event":  "get_announcements",
+			"status": "failure",
+			"reason": "database_error",
+			"ip":     ctx.ClientIP(),
+			"error":  err.Error(),
+		}).Error("Database error in getAnnouncements")
+		ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Database error"})
+		return
+	}
+	ctx.JSON(http.StatusOK, items)
+}
@@ -0,0 +1,123 @@
+package announcements
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/intraware/rodan/internal/events"
+	"github.com/intraware/rodan/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+type announcementItem struct {
+	ID          uint      `json:"id"`
+	Title       string    `json:"title"`
+	Body        string    `json:"body"`
+	ChallengeID *uint     `json:"challenge_id,omitempty"`
+	Pinned      bool      `json:"pinned"`
+	PublishedAt time.Time `json:"published_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+var (
+	announcementCache atomic.Pointer[[]models.Announcement]
+	// lastChanged moves on every admin write, deletes included
+	lastChanged atomic.Value
+)
+
+// Invalidate drops the cached list after an admin change
+func Invalidate() {
+	announcementCache.Store(nil)
+	lastChanged.Store(time.Now().UTC())
+}
+
+func loadAnnouncements() ([]models.Announcement, error) {
+	if ptr := announcementCache.Load(); ptr != nil {
+		return *ptr, nil
+	}
+	var all []models.Announcement
+	if err := models.DB.Order("pinned DESC, visible_at DESC").Find(&all).Error; err != nil {
+		return nil, err
+	}
+	announcementCache.Store(&all)
+	return all, nil
+}
+
+// visibleAnnouncements returns what players may see now and when that view last changed.
+// Scheduled announcements bump the modification time the moment they become visible.
+func visibleAnnouncements() ([]announcementItem, time.Time, error) {
+	all, err := loadAnnouncements()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	now := time.Now()
+	lastMod, _ := lastChanged.Load().(time.Time)
+	items := make([]announcementItem, 0, len(all))
+	for _, a := range all {
+		if a.VisibleAt.After(now) {
+			continue
+		}
+		items = append(items, announcementItem{
+			ID:          a.ID,
+			Title:       a.Title,
+			Body:        a.Body,
+			ChallengeID: a.ChallengeID,
+			Pinned:      a.Pinned,
+			PublishedAt: a.VisibleAt,
+			UpdatedAt:   a.UpdatedAt,
+		})
+		if a.VisibleAt.After(lastMod) {
+			lastMod = a.VisibleAt
+		}
+		if a.UpdatedAt.After(lastMod) {
+			lastMod = a.UpdatedAt
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].Pinned != items[j].Pinned {
+			return items[i].Pinned
+		}
+		return items[i].PublishedAt.After(items[j].PublishedAt)
+	})
+	return items, lastMod, nil
+}
+
+// Publish pushes the announcement to the event stream once it is visible.
+// Scheduled ones are re-read when due so edits and deletes in between are respected.
+func Publish(a models.Announcement) {
+	if wait := time.Until(a.VisibleAt); wait > 0 {
+		time.AfterFunc(wait, func() {
+			var current models.Announcement
+			if err := models.DB.First(&current, a.ID).Error; err != nil || !current.VisibleAt.Equal(a.VisibleAt) {
+				return
+			}
+			publishEvent(current)
+		})
+		return
+	}
+	publishEvent(a)
+}
+
+// SchedulePending sets up the event of every announcement that is not visible yet.
+// The timers of Publish are lost on a restart, so this runs once at startup.
+func SchedulePending() {
+	var pending []models.Announcement
+	if err := models.DB.Where("visible_at > ?", time.Now()).Find(&pending).Error; err != nil {
+		logrus.Errorf("Failed to load scheduled announcements: %v", err)
+		return
+	}
+	for _, a := range pending {
+		Publish(a)
+	}
+}
+
+func publishEvent(a models.Announcement) {
+	events.Publish(events.Global(events.Announcement, events.AnnouncementData{
+		ID:          a.ID,
+		Title:       a.Title,
+		Message:     a.Body,
+		ChallengeID: a.ChallengeID,
+		Pinned:      a.Pinned,
+	}))
+}
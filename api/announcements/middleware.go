@@ -0,0 +1,32 @@
+package announcements
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/intraware/rodan/internal/types"
+)
+
+func lastModifiedMiddleware(ctx *gin.Context) {
+	_, lastMod, err := visibleAnnouncements()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Database error"})
+		ctx.Abort()
+		return
+	}
+	if lastMod.IsZero() {
+		ctx.Next()
+		return
+	}
+	if clientMod := ctx.GetHeader("If-Modified-Since"); clientMod != "" {
+		clientTime, err := http.ParseTime(clientMod)
+		if err == nil && !lastMod.Truncate(time.Second).After(clientTime) {
+			ctx.Status(http.StatusNotModified)
+			ctx.Abort()
+			return
+		}
+	}
+	ctx.Header("Last-Modified", lastMod.UTC().Format(http.TimeFormat))
+	ctx.Next()
+}
@@ -0,0 +1,11 @@
+package announcements
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/intraware/rodan/internal/utils/middleware"
+)
+
+func LoadAnnouncements(r *gin.RouterGroup) {
+	announcementRouter := r.Group("/announcements")
+	announcementRouter.GET("", lastModifiedMiddleware, middleware.CacheMiddleware, getAnnouncements)
+}
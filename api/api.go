@@ -2,8 +2,11 @@ package api
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/intraware/rodan/api/announcements"
 	"github.com/intraware/rodan/api/challenges"
+	"github.com/intraware/rodan/api/events"
 	"github.com/intraware/rodan/api/leaderboard"
+	"github.com/intraware/rodan/api/releases"
 	"github.com/intraware/rodan/api/shared"
 	"github.com/intraware/rodan/internal/utils/values"
 )
@@ -13,8 +16,12 @@ func LoadRoutes(r *gin.Engine) {
 
 	challenges.LoadChallenges(apiRouter)
 	leaderboard.LoadLeaderboard(apiRouter)
+	events.LoadEvents(apiRouter)
+	announcements.LoadAnnouncements(apiRouter)
 
 	shared.Init(values.GetConfig())
+	releases.Start()
+	announcements.SchedulePending()
 	apiRouter.GET("/ping", func(ctx *gin.Context) {
 		ctx.JSON(200, gin.H{"msg": "pong"})
 	})
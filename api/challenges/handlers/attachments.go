@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/intraware/rodan/api/shared"
+	"github.com/intraware/rodan/internal/models"
+	"github.com/intraware/rodan/internal/storage"
+	"github.com/intraware/rodan/internal/types"
+	"github.com/intraware/rodan/internal/utils"
+	"github.com/intraware/rodan/internal/utils/values"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const defaultURLExpiry = 10 * time.Minute
+
+func getChallengeAttachments(challengeID uint) ([]models.Attachment, error) {
+	if attachments, ok := shared.AttachmentCache.Get(challengeID); ok {
+		return attachments, nil
+	}
+	var attachments []models.Attachment
+	if err := models.DB.Where("challenge_id = ?", challengeID).Order("id").Find(&attachments).Error; err != nil {
+		return nil, err
+	}
+	shared.AttachmentCache.Set(challengeID, attachments)
+	return attachments, nil
+}
+
+// attachmentLinks signs a download link to every attachment of the challenge for the user
+func attachmentLinks(user models.User, challengeID uint) ([]fileLink, error) {
+	attachments, err := getChallengeAttachments(challengeID)
+	if err != nil || len(attachments) == 0 {
+		return nil, err
+	}
+	cfg := values.GetConfig()
+	expiry := cfg.App.Storage.URLExpiry
+	if expiry <= 0 {
+		expiry = defaultURLExpiry
+	}
+	expires := time.Now().Add(expiry).Unix()
+	links := make([]fileLink, 0, len(attachments))
+	for _, a := range attachments {
+		grant := storage.Grant{AttachmentID: a.ID, TeamID: *user.TeamID, UserID: user.ID, Expires: expires}
+		query := url.Values{}
+		query.Set("team", strconv.FormatUint(uint64(grant.TeamID), 10))
+		query.Set("user", strconv.FormatUint(uint64(grant.UserID), 10))
+		query.Set("expires", strconv.FormatInt(expires, 10))
+		query.Set("sig", storage.Sign(cfg.Server.Security.FileSecret, grant))
+		link := fileLink{
+			Name:    a.Name,
+			URL:     fmt.Sprintf("/api/challenge/%d/files/%d?%s", challengeID, a.ID, query.Encode()),
+			Expires: expires,
+		}
+		// a templated file differs per team, the stored template's size and hash would not match
+		if !a.Templated {
+			link.Size = a.Size
+			link.SHA256 = a.SHA256
+		}
+		links = append(links, link)
+	}
+	return links, nil
+}
+
+// VerifyDownload checks the signature of a download link and stands in for
+// AuthRequired, so links work from a plain browser download
+func VerifyDownload(ctx *gin.Context) {
+	auditLog := utils.Logger.WithField("type", "audit")
+	attachmentID, errA := strconv.ParseUint(ctx.Param("file_id"), 10, 64)
+	teamID, errT := strconv.ParseUint(ctx.Query("team"), 10, 64)
+	userID, errU := strconv.ParseUint(ctx.Query("user"), 10, 64)
+	expires, errE := strconv.ParseInt(ctx.Query("expires"), 10, 64)
+	grant := storage.Grant{AttachmentID: uint(attachmentID), TeamID: uint(teamID), UserID: uint(userID), Expires: expires}
+	secret := values.GetConfig().Server.Security.FileSecret
+	if errA != nil || errT != nil || errU != nil || errE != nil || !storage.Verify(secret, grant, ctx.Query("sig"), time.Now()) {
+		auditLog.WithFields(logrus.Fields{
+			"event":      "download_attachment",
+			"status":     "failure",
+			"reason":     "invalid_signature",
+			"attachment": ctx.Param("file_id"),
+			"ip":         ctx.ClientIP(),
+		}).Warn("Invalid or expired download link")
+		ctx.JSON(http.StatusForbidden, types.ErrorResponse{Error: "Invalid or expired link"})
+		ctx.Abort()
+		return
+	}
+	ctx.Set("user_id", grant.UserID)
+	ctx.Set("team_id", grant.TeamID)
+	ctx.Next()
+}
+
+// DownloadAttachment godoc
+// @Summary      Download a challenge attachment
+// @Description  Streams an attachment through a signed link from the challenge config
+// @Tags         challenges
+// @Produce      octet-stream
+// @Param        id       path      int     true  "Challenge ID"
+// @Param        file_id  path      int     true  "Attachment ID"
+// @Param        team     query     int     true  "Team ID"
+// @Param        user     query     int     true  "User ID"
+// @Param        expires  query     int     true  "Expiry (unix seconds)"
+// @Param        sig      query     string  true  "Signature"
+// @Success      200      {file}    binary
+// @Failure      403      {object}  types.ErrorResponse
+// @Failure      404      {object}  types.ErrorResponse
+// @Failure      500      {object}  types.ErrorResponse
+// @Router       /challenge/{id}/files/{file_id} [get]
+func DownloadAttachment(ctx *gin.Context) {
+	auditLog := utils.Logger.WithField("type", "audit")
+	userID := ctx.GetUint("user_id")
+	teamID := ctx.GetUint("team_id")
+	user, userCacheHit := shared.UserCache.Get(userID)
+	if !userCacheHit {
+		if err := models.DB.First(&user, userID).Error; err != nil {
+			ctx.JSON(http.StatusForbidden, types.ErrorResponse{Error: "Invalid or expired link"})
+			return
+		}
+		shared.UserCache.Set(userID, user)
+	}
+	// the link was issued to a team member, it dies with the membership
+	if user.TeamID == nil || *user.TeamID != teamID {
+		auditLog.WithFields(logrus.Fields{
+			"event":   "download_attachment",
+			"status":  "failure",
+			"reason":  "not_team_member",
+			"user_id": user.ID,
+			"team_id": teamID,
+			"ip":      ctx.ClientIP(),
+		}).Warn("Download link used outside its team")
+		ctx.JSON(http.StatusForbidden, types.ErrorResponse{Error: "User is no longer in this team"})
+		return
+	}
+	challengeID, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid challenge ID"})
+		return
+	}
+	challenge, challengeCacheHit := shared.ChallengeCache.Get(uint(challengeID))
+	if !challengeCacheHit {
+		if err := models.DB.Where("is_visible = ?", true).First(&challenge, challengeID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				ctx.JSON(http.StatusNotFound, types.ErrorResponse{Error: "Challenge not found"})
+				return
+			}
+			auditLog.WithFields(logrus.Fields{
+				"event":     "download_attachment",
+				"status":    "failure",
+				"reason":    "db_error_challenge_lookup",
+				"user_id":   user.ID,
+				"challenge": challengeID,
+				"ip":        ctx.ClientIP(),
+				"error":     err.Error(),
+			}).Error("Error fetching challenge from DB")
+			ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Database error"})
+			return
+		}
+		shared.ChallengeCache.Set(challenge.ID, challenge)
+	}
+	if !challenge.IsVisible {
+		ctx.JSON(http.StatusNotFound, types.ErrorResponse{Error: "Challenge not found"})
+		return
+	}
+	if !checkUnlocked(ctx, "download_attachment", user, challenge.ID) {
+		return
+	}
+	attachments, err := getChallengeAttachments(challenge.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Database error"})
+		return
+	}
+	fileID, _ := strconv.ParseUint(ctx.Param("file_id"), 10, 64)
+	var attachment *models.Attachment
+	for i := range attachments {
+		if attachments[i].ID == uint(fileID) {
+			attachment = &attachments[i]
+			break
+		}
+	}
+	if attachment == nil {
+		ctx.JSON(http.StatusNotFound, types.ErrorResponse{Error: "File not found"})
+		return
+	}
+	reader, err := storage.Open(ctx.Request.Context(), attachment.Key)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, storage.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		auditLog.WithFields(logrus.Fields{
+			"event":         "download_attachment",
+			"status":        "failure",
+			"reason":        "storage_error",
+			"user_id":       user.ID,
+			"challenge":     challenge.ID,
+			"attachment_id": attachment.ID,
+			"ip":            ctx.ClientIP(),
+			"error":         err.Error(),
+		}).Error("Failed to open attachment")
+		ctx.JSON(status, types.ErrorResponse{Error: "File not available"})
+		return
+	}
+	defer reader.Close()
+	var rendered []byte
+	if attachment.Templated {
+		template, err := storage.ReadTemplate(reader)
+		if err == nil {
+			rendered, err = storage.Render(template, attachment.Placeholder, getDynamicFlag(challenge.ID, teamID))
+		}
+		if err != nil {
+			auditLog.WithFields(logrus.Fields{
+				"event":         "download_attachment",
+				"status":        "failure",
+				"reason":        "template_error",
+				"user_id":       user.ID,
+				"challenge":     challenge.ID,
+				"attachment_id": attachment.ID,
+				"ip":            ctx.ClientIP(),
+				"error":         err.Error(),
+			}).Error("Failed to render templated attachment")
+			ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "File not available"})
+			return
+		}
+	}
+	auditLog.WithFields(logrus.Fields{
+		"event":         "download_attachment",
+		"status":        "success",
+		"user_id":       user.ID,
+		"team_id":       teamID,
+		"challenge":     challenge.ID,
+		"attachment_id": attachment.ID,
+		"templated":     attachment.Templated,
+		"ip":            ctx.ClientIP(),
+	}).Info("Attachment downloaded")
+	ctx.Header("Cache-Control", "private, no-store")
+	headers := map[string]string{
+		"Content-Disposition": fmt.Sprintf("attachment; filename=%q", attachment.Name),
+	}
+	if attachment.Templated {
+		ctx.DataFromReader(http.StatusOK, int64(len(rendered)), attachment.ContentType, bytes.NewReader(rendered), headers)
+		return
+	}
+	ctx.DataFromReader(http.StatusOK, attachment.Size, attachment.ContentType, reader, headers)
+}
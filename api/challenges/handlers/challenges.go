@@ -10,6 +10,8 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/intraware/rodan/api/leaderboard"
 	"github.com/intraware/rodan/api/shared"
+	"github.com/intraware/rodan/internal/events"
+	"github.com/intraware/rodan/internal/flags"
 	"github.com/intraware/rodan/internal/models"
 	"github.com/intraware/rodan/internal/notification"
 	"github.com/intraware/rodan/internal/sandbox"
@@ -22,40 +24,71 @@ import (
 
 // GetChallengeList godoc
 // @Summary      Get challenge list
-// @Description  Retrieves a list of visible challenges with basic information
+// @Description  Retrieves the visible challenges the team has unlocked, with points, solve counts, tags and whether the team solved them
 // @Security     BearerAuth
 // @Tags         challenges
 // @Accept       json
 // @Produce      json
-// @Success      200  {object}  []models.Challenge
+// @Success      200  {array}   shared.ChallengeListItem
+// @Failure      403  {object}  types.ErrorResponse
 // @Failure      500  {object}  types.ErrorResponse
 // @Router       /challenges [get]
 func GetChallengeList(ctx *gin.Context) {
 	auditLog := utils.Logger.WithField("type", "audit")
-	var challenges []models.Challenge
-	if err := models.DB.Select("id, name").Where("is_visible = ?", true).Find(&challenges).Error; err != nil {
+	userID := ctx.GetUint("user_id")
+	user, userCacheHit := shared.UserCache.Get(userID)
+	if !userCacheHit {
+		if err := models.DB.First(&user, userID).Error; err != nil {
+			auditLog.WithFields(logrus.Fields{
+				"event":   "get_challenge_list",
+				"status":  "failure",
+				"reason":  "db_error_user_lookup",
+				"user_id": userID,
+				"ip":      ctx.ClientIP(),
+				"error":   err.Error(),
+			}).Error("Failed to fetch user from DB")
+			ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Database error"})
+			return
+		}
+		shared.UserCache.Set(userID, user)
+	}
+	if user.TeamID == nil {
 		auditLog.WithFields(logrus.Fields{
-			"event":  "get_challenge_list",
-			"status": "failure",
-			"reason": "db_error",
-			"ip":     ctx.ClientIP(),
-			"error":  err.Error(),
-		}).Error("Failed to fetch challenges")
-		ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to fetch challenges"})
+			"event":   "get_challenge_list",
+			"status":  "failure",
+			"reason":  "no_team",
+			"user_id": user.ID,
+			"ip":      ctx.ClientIP(),
+		}).Warn("User is not part of a team")
+		ctx.JSON(http.StatusForbidden, types.ErrorResponse{Error: "User should belong to a team"})
 		return
 	}
-	var challengeList = make([]challengeItem, len(challenges))
-	for idx, challenge := range challenges {
-		challengeList[idx] = challengeItem{
-			ID:    challenge.ID,
-			Title: challenge.Name,
+	teamID := *user.TeamID
+	challengeList, listCacheHit := shared.ChallengeListCache.Get(teamID)
+	if !listCacheHit {
+		var err error
+		if challengeList, err = buildChallengeList(teamID); err != nil {
+			auditLog.WithFields(logrus.Fields{
+				"event":   "get_challenge_list",
+				"status":  "failure",
+				"reason":  "db_error",
+				"user_id": user.ID,
+				"team_id": teamID,
+				"ip":      ctx.ClientIP(),
+				"error":   err.Error(),
+			}).Error("Failed to fetch challenges")
+			ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to fetch challenges"})
+			return
 		}
+		shared.ChallengeListCache.Set(teamID, challengeList)
 	}
 	auditLog.WithFields(logrus.Fields{
-		"event":  "get_challenge_list",
-		"status": "success",
-		"ip":     ctx.ClientIP(),
-		"count":  len(challengeList),
+		"event":    "get_challenge_list",
+		"status":   "success",
+		"team_id":  teamID,
+		"ip":       ctx.ClientIP(),
+		"count":    len(challengeList),
+		"list_hit": listCacheHit,
 	}).Info("Fetched challenge list successfully")
 	ctx.JSON(http.StatusOK, challengeList)
 }
@@ -69,6 +102,7 @@ func GetChallengeList(ctx *gin.Context) {
 // @Produce      json
 // @Param        id   path      string  true  "Challenge ID"
 // @Success      200  {object}  models.Challenge
+// @Failure      403  {object}  types.ErrorResponse
 // @Failure      404  {object}  types.ErrorResponse
 // @Failure      500  {object}  types.ErrorResponse
 // @Router       /challenges/{id} [get]
@@ -170,19 +204,17 @@ func GetChallengeDetail(ctx *gin.Context) {
 		}
 		shared.ChallengeCache.Set(challengeID, challenge)
 	}
-	var points int
-	if val, err := calcPoints(challenge.PointsMin, challenge.PointsMax, challenge.ID); err != nil {
-		points = challenge.PointsMax
-	} else {
-		points = val
+	if !checkUnlocked(ctx, "get_challenge_detail", user, challengeID) {
+		return
 	}
+	points := calcPoints(challenge)
 	response := challengeDetail{
 		ID:         challenge.ID,
 		Name:       challenge.Name,
 		Author:     challenge.Author,
 		Desc:       challenge.Desc,
-		Category:   challenge.Category,
-		Difficulty: challenge.Difficulty,
+		Category:   shared.CategoryLabel(challenge.CategoryID),
+		Difficulty: shared.DifficultyLabel(challenge.DifficultyID),
 		Points:     points,
 		Solved:     solved,
 	}
@@ -310,7 +342,24 @@ func GetChallengeConfig(ctx *gin.Context) {
 		}
 		shared.ChallengeCache.Set(challengeID, challenge)
 	}
-	// for files .. store in links
+	if !checkUnlocked(ctx, "get_challenge_config", user, challengeID) {
+		return
+	}
+	files, err := attachmentLinks(user, challenge.ID)
+	if err != nil {
+		auditLog.WithFields(logrus.Fields{
+			"event":     "get_challenge_config",
+			"status":    "failure",
+			"reason":    "db_error_attachments",
+			"user_id":   user.ID,
+			"team_id":   *user.TeamID,
+			"challenge": challengeID,
+			"ip":        ctx.ClientIP(),
+			"error":     err.Error(),
+		}).Error("Failed to load challenge attachments")
+		ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to retrieve data from DB"})
+		return
+	}
 	var response challengeConfigResponse
 	if challenge.IsStatic {
 		staticConfig, staticConfigCacheHit := shared.StaticConfig.Get(challenge.ID)
@@ -332,6 +381,8 @@ func GetChallengeConfig(ctx *gin.Context) {
 				ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to retrieve data from DB"})
 				return
 			} else {
+				// the cache only serves links and ports, keep the flag hash out of it
+				staticConfig.Flag = ""
 				shared.StaticConfig.Set(challenge.ID, staticConfig)
 			}
 		}
@@ -339,10 +390,16 @@ func GetChallengeConfig(ctx *gin.Context) {
 			ID:       challenge.ID,
 			Links:    staticConfig.Links,
 			Ports:    staticConfig.Ports,
+			Files:    files,
 			IsStatic: true,
 		}
 		cacheTime := values.GetConfig().App.CacheDuration
-		ctx.Header("Cache-Control", fmt.Sprintf("public,max-age=%.0f", cacheTime.Seconds()))
+		// download links are signed for this user, shared caches must not hand them out
+		visibility := "public"
+		if len(files) > 0 {
+			visibility = "private"
+		}
+		ctx.Header("Cache-Control", fmt.Sprintf("%s,max-age=%.0f", visibility, cacheTime.Seconds()))
 		ctx.Header("Expires", time.Now().Add(cacheTime).Format(http.TimeFormat))
 		auditLog.WithFields(logrus.Fields{
 			"event":             "get_challenge_config",
@@ -359,7 +416,7 @@ func GetChallengeConfig(ctx *gin.Context) {
 			"ip":                ctx.ClientIP(),
 		}).Info("Fetched static challenge config successfully")
 	} else {
-		challengeSandbox, ok := shared.SandBoxMap.Get(*user.TeamID)
+		challengeSandbox, ok := shared.SandBoxMap.Get(shared.SandBoxKey{TeamID: *user.TeamID, ChallengeID: challengeID})
 		if !ok {
 			auditLog.WithFields(logrus.Fields{
 				"event":         "get_challenge_config",
@@ -418,6 +475,7 @@ func GetChallengeConfig(ctx *gin.Context) {
 		response = challengeConfigResponse{
 			ID:       challenge.ID,
 			Links:    sandboxMeta.Links,
+			Files:    files,
 			TimeLeft: sandboxMeta.TimeLeft,
 			IsStatic: false,
 		}
@@ -454,21 +512,21 @@ func GetChallengeConfig(ctx *gin.Context) {
 // @Failure      400   {object}  types.ErrorResponse
 // @Failure      403   {object}  types.ErrorResponse
 // @Failure      404   {object}  types.ErrorResponse
+// @Failure      429   {object}  types.ErrorResponse
 // @Failure      500   {object}  types.ErrorResponse
 // @Router       /challenges/{id}/submit [post]
 func SubmitFlag(ctx *gin.Context) {
 	auditLog := utils.Logger.WithField("type", "audit")
-	if !shared.GetSubmissions() {
+	if reason, msg, open := shared.SubmissionWindow(); !open {
 		auditLog.WithFields(logrus.Fields{
 			"event":  "submit_flag",
 			"status": "failure",
-			"reason": "submission_closed",
+			"reason": reason,
 			"ip":     ctx.ClientIP(),
-		}).Warn("Submissions are closed")
-		ctx.JSON(http.StatusForbidden, types.ErrorResponse{Error: "Submissions are closed"})
+		}).Warn(msg)
+		ctx.JSON(http.StatusForbidden, types.ErrorResponse{Error: msg})
 		return
 	}
-	cfg := values.GetConfig().App
 	challengeIDStr := ctx.Param("id")
 	id, err := strconv.ParseUint(challengeIDStr, 10, 64)
 	if err != nil {
@@ -537,6 +595,9 @@ func SubmitFlag(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "User must be in a team to submit flags"})
 		return
 	}
+	if !checkSubmitRate(ctx, user, challengeID) {
+		return
+	}
 	challenge, challengeCacheHit := shared.ChallengeCache.Get(challengeID)
 	if !challengeCacheHit {
 		if err := models.DB.Where("is_visible = ?", true).First(&challenge, challengeID).Error; err != nil {
@@ -568,6 +629,9 @@ func SubmitFlag(ctx *gin.Context) {
 		}
 		shared.ChallengeCache.Set(challengeID, challenge)
 	}
+	if !checkUnlocked(ctx, "submit_flag", user, challengeID) {
+		return
+	}
 	teamID := *user.TeamID
 	var existingSolve models.Solve
 	err = models.DB.Where("team_id = ? AND challenge_id = ?", teamID, challengeID).First(&existingSolve).Error
@@ -584,20 +648,33 @@ func SubmitFlag(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Challenge already solved by your team"})
 		return
 	}
-	var correctFlag string
+	cfg := values.GetConfig()
+	verifier := flags.NewVerifier(cfg.App.FlagFormat, cfg.Server.Security.FlagSecret, cfg.App.VerifyFloor)
+	teamFlag, err := usesTeamFlag(challenge)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to get static metadata from DB"})
+		return
+	}
+	var correct bool
 	var challengeType int8
-	if challenge.IsStatic {
-		if err := models.DB.Model(&challenge).Association("StaticConfig").Find(&challenge.StaticConfig); err != nil {
+	if teamFlag {
+		correct = verifier.Dynamic(getDynamicFlag(challengeID, teamID), req.Flag)
+	} else {
+		matchers, err := staticFlagMatchers(challenge)
+		if err != nil {
 			ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to get static metadata from DB"})
 			return
 		}
-		correctFlag = challenge.StaticConfig.Flag
-		challengeType = 0
-	} else {
-		correctFlag = getDynamicFlag(challengeID, teamID)
+		correct = verifier.Static(matchers, req.Flag)
+	}
+	if !challenge.IsStatic {
 		challengeType = 1
 	}
-	if req.Flag != correctFlag {
+	recordSubmission(ctx, user, challengeID, req.Flag, correct)
+	if !correct {
+		if teamFlag && handleFlagSharing(ctx, user, challengeID, req.Flag) {
+			return
+		}
 		auditLog.WithFields(logrus.Fields{
 			"event":     "submit_flag",
 			"status":    "failure",
@@ -613,131 +690,6 @@ func SubmitFlag(ctx *gin.Context) {
 		})
 		return
 	}
-	var flagValues []string
-	dynFlagMap.Range(func(key, value any) bool {
-		if req.Flag == value {
-			flagValues = append(flagValues, value.(string))
-		}
-		return true
-	})
-	if len(flagValues) > 0 && (cfg.Ban.UserBan || cfg.Ban.TeamBan) {
-		banReason := "submit_someone_flag"
-		tx := models.DB.Begin()
-		var count int64
-		if cfg.Ban.UserBan {
-			tx.Model(&models.BanHistory{}).
-				Where("user_id = ?", user.ID).
-				Count(&count)
-		} else if cfg.Ban.TeamBan {
-			tx.Model(&models.BanHistory{}).
-				Where("team_id = ?", user.TeamID).
-				Count(&count)
-		}
-		expiration := time.Now().Add(calcBanDuration(int(count))).Unix()
-		if err := tx.Error; err != nil {
-			ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Database error"})
-			return
-		}
-		var key string
-		var ban models.BanHistory
-		ban.Context = banReason
-		ban.ExpiresAt = expiration
-		if cfg.Ban.UserBan {
-			key = fmt.Sprintf("%d:%d", user.ID, *user.TeamID)
-			user.Ban = true
-			if err := tx.Save(&user).Error; err != nil {
-				tx.Rollback()
-				auditLog.WithFields(logrus.Fields{
-					"event":     "user_ban",
-					"status":    "failure",
-					"reason":    "db_error_solve",
-					"user_id":   user.ID,
-					"team_id":   teamID,
-					"challenge": challengeID,
-					"ip":        ctx.ClientIP(),
-					"error":     err.Error(),
-				}).Error("Failed to ban user")
-				ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to update user status"})
-				return
-			}
-			ban.UserID = &userID
-		}
-		if cfg.Ban.TeamBan {
-			key = fmt.Sprintf(":%d", teamID)
-			if err := tx.Model(&models.Team{}).Where("id = ?", *user.TeamID).Update("ban", true).Error; err != nil {
-				tx.Rollback()
-				auditLog.WithFields(logrus.Fields{
-					"event":     "team_ban",
-					"status":    "failure",
-					"reason":    "db_error_solve",
-					"user_id":   user.ID,
-					"team_id":   teamID,
-					"challenge": challengeID,
-					"ip":        ctx.ClientIP(),
-					"error":     err.Error(),
-				}).Error("Failed to ban team")
-				ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to update team status"})
-				return
-			}
-			ban.UserID = &userID
-			ban.TeamID = user.TeamID
-		}
-		if err := tx.Create(&ban).Error; err != nil {
-			tx.Rollback()
-			auditLog.WithFields(logrus.Fields{
-				"event":     "ban_history",
-				"status":    "failure",
-				"reason":    "db_error_solve",
-				"user_id":   user.ID,
-				"team_id":   teamID,
-				"challenge": challengeID,
-				"ip":        ctx.ClientIP(),
-				"error":     err.Error(),
-			}).Error("Failed to create ban history")
-			ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to record ban history"})
-			return
-		} else {
-			shared.BanHistoryCache.Set(key, ban)
-			var sandboxes []*sandbox.SandBox
-			rawSandBoxes := shared.SandBoxMap.DumpValues()
-			if cfg.Ban.UserBan {
-				for _, sandbox := range rawSandBoxes {
-					if sandbox.UserID == *ban.UserID {
-						sandboxes = append(sandboxes, sandbox)
-					}
-				}
-			} else if cfg.Ban.TeamBan {
-				for _, sandbox := range rawSandBoxes {
-					if sandbox.TeamID == *ban.TeamID {
-						sandboxes = append(sandboxes, sandbox)
-					}
-				}
-			}
-			stopAllContainers(sandboxes)
-		}
-		if err := tx.Commit().Error; err != nil {
-			ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to finalize ban"})
-			return
-		}
-		auditLog.WithFields(logrus.Fields{
-			"event":     "submit_flag",
-			"status":    "failure",
-			"reason":    "submit_other_flag",
-			"user_id":   user.ID,
-			"team_id":   teamID,
-			"challenge": challengeID,
-			"ip":        ctx.ClientIP(),
-		}).Error("Unauthorized flag submission — ban issued")
-		shared.UserCache.Delete(user.ID)
-		if user.TeamID != nil {
-			shared.TeamCache.Delete(*user.TeamID)
-		}
-		if values.GetConfig().App.Ban.UserBan {
-			ctx.JSON(http.StatusForbidden, types.ErrorResponse{Error: "Account got banned"})
-		} else {
-			ctx.JSON(http.StatusForbidden, types.ErrorResponse{Error: "Team account got banned"})
-		}
-	}
 	var solveCount int64
 	if err := models.DB.Model(&models.Solve{}).
 		Where("challenge_id = ?", challengeID).
@@ -768,6 +720,7 @@ func SubmitFlag(ctx *gin.Context) {
 		UserID:        userID,
 		ChallengeType: challengeType,
 		BloodCount:    bloodCount,
+		Points:        calcPoints(challenge),
 	}
 	if err := models.DB.Create(&solve).Error; err != nil {
 		auditLog.WithFields(logrus.Fields{
@@ -792,9 +745,21 @@ func SubmitFlag(ctx *gin.Context) {
 			"blood":     bloodCount,
 		}).Infof("Team got %d-blood on challenge", bloodCount)
 		if bloodCount == 1 {
-			notification.SendNotification(fmt.Sprintf("First Blood! %s solved %s", user.Username, challenge.Name))
+			notification.Send(notification.Payload{
+				Type:    string(events.FirstBlood),
+				Message: fmt.Sprintf("First Blood! %s solved %s", user.Username, challenge.Name),
+				Data: events.SolveData{
+					UserID:        user.ID,
+					Username:      user.Username,
+					TeamID:        teamID,
+					ChallengeID:   challenge.ID,
+					ChallengeName: challenge.Name,
+					Blood:         1,
+				},
+			})
 		}
 	}
+	publishSolve(user, teamID, challenge, bloodCount)
 	auditLog.WithFields(logrus.Fields{
 		"event":          "submit_flag",
 		"status":         "success",
@@ -808,6 +773,8 @@ func SubmitFlag(ctx *gin.Context) {
 		"ip":             ctx.ClientIP(),
 		"solved_at":      solve.CreatedAt,
 	}).Info("Flag submitted successfully")
+	invalidateSolveCount(challengeID)
+	shared.ChallengeListCache.Delete(teamID)
 	leaderboard.MarkLeaderboardDirty()
 	ctx.JSON(http.StatusOK, submitFlagResponse{
 		Correct: true,
@@ -12,6 +12,7 @@ import (
 	"github.com/intraware/rodan/internal/sandbox"
 	"github.com/intraware/rodan/internal/types"
 	"github.com/intraware/rodan/internal/utils"
+	"github.com/intraware/rodan/internal/utils/values"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 )
@@ -26,12 +27,24 @@ import (
 // @Param        id   path      string  true  "Challenge ID"
 // @Success      200  {object}  types.SuccessResponse
 // @Failure      400  {object}  types.ErrorResponse
+// @Failure      403  {object}  types.ErrorResponse
 // @Failure      404  {object}  types.ErrorResponse
 // @Failure      409  {object}  types.ErrorResponse
 // @Failure      500  {object}  types.ErrorResponse
+// @Failure      503  {object}  types.ErrorResponse
 // @Router       /challenges/{id}/start [post]
 func StartDynamicChallenge(ctx *gin.Context) {
 	auditLog := utils.Logger.WithField("type", "audit")
+	if reason, msg, open := shared.SubmissionWindow(); !open {
+		auditLog.WithFields(logrus.Fields{
+			"event":  "start_dynamic_challenge",
+			"status": "failure",
+			"reason": reason,
+			"ip":     ctx.ClientIP(),
+		}).Warn(msg)
+		ctx.JSON(http.StatusForbidden, types.ErrorResponse{Error: msg})
+		return
+	}
 	userID := ctx.GetUint("user_id")
 	user, userCacheHit := shared.UserCache.Get(userID)
 	if !userCacheHit {
@@ -143,6 +156,9 @@ func StartDynamicChallenge(ctx *gin.Context) {
 		}
 		shared.ChallengeCache.Set(challengeID, challenge)
 	}
+	if !checkUnlocked(ctx, "start_dynamic_challenge", user, challengeID) {
+		return
+	}
 	if challenge.IsStatic {
 		auditLog.WithFields(logrus.Fields{
 			"event":         "start_dynamic_challenge",
@@ -172,7 +188,7 @@ func StartDynamicChallenge(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to get dynamic metadata from DB"})
 		return
 	}
-	if challenge.DynamicConfig.DockerImage == "" {
+	if challenge.DynamicConfig.DockerImage == "" && len(challenge.DynamicConfig.Services) == 0 {
 		auditLog.WithFields(logrus.Fields{
 			"event":     "start_dynamic_challenge",
 			"status":    "failure",
@@ -185,13 +201,11 @@ func StartDynamicChallenge(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Invalid Docker Image is added to the list"})
 		return
 	}
-	challengeSandbox, ok := shared.SandBoxMap.Get(*user.TeamID)
-	if !ok {
-		flag := generateHashedFlag(challengeID, *user.TeamID)
-		challengeSandbox = sandbox.NewSandBox(userID, *user.TeamID, &challenge, flag)
-		shared.SandBoxMap.Set(*user.TeamID, challengeSandbox)
-	}
-	if challengeSandbox.Active {
+	unlock := lockTeamSandBoxes(*user.TeamID)
+	defer unlock()
+	sandboxKey := shared.SandBoxKey{TeamID: *user.TeamID, ChallengeID: challengeID}
+	challengeSandbox, ok := shared.SandBoxMap.Get(sandboxKey)
+	if ok && challengeSandbox.Active {
 		auditLog.WithFields(logrus.Fields{
 			"event":     "start_dynamic_challenge",
 			"status":    "failure",
@@ -204,8 +218,40 @@ func StartDynamicChallenge(ctx *gin.Context) {
 		ctx.JSON(http.StatusConflict, types.ErrorResponse{Error: "Container is already running"})
 		return
 	}
+	maxSandboxes := values.GetConfig().Docker.MaxSandboxesPerTeam
+	if maxSandboxes > 0 && shared.CountActiveSandBoxes(*user.TeamID) >= maxSandboxes {
+		auditLog.WithFields(logrus.Fields{
+			"event":         "start_dynamic_challenge",
+			"status":        "failure",
+			"reason":        "sandbox_limit_reached",
+			"user_id":       user.ID,
+			"team_id":       *user.TeamID,
+			"challenge":     challengeID,
+			"max_sandboxes": maxSandboxes,
+			"ip":            ctx.ClientIP(),
+		}).Warn("Team reached the running sandbox limit")
+		ctx.JSON(http.StatusForbidden, types.ErrorResponse{Error: fmt.Sprintf("Your team can only run %d containers at once", maxSandboxes)})
+		return
+	}
+	if !ok {
+		flag := generateHashedFlag(challengeID, *user.TeamID)
+		challengeSandbox = sandbox.NewSandBox(userID, *user.TeamID, &challenge, flag)
+		shared.SandBoxMap.Set(sandboxKey, challengeSandbox)
+	}
 	if err := challengeSandbox.Start(); err != nil {
-		if errors.Is(err, sandbox.ErrFailedToCreateContainer) {
+		if errors.Is(err, sandbox.ErrNoFreePorts) {
+			auditLog.WithFields(logrus.Fields{
+				"event":     "start_dynamic_challenge",
+				"status":    "failure",
+				"reason":    "no_free_ports",
+				"user_id":   user.ID,
+				"team_id":   *user.TeamID,
+				"challenge": challengeID,
+				"ip":        ctx.ClientIP(),
+			}).Error("Port range exhausted, cannot start the container")
+			ctx.JSON(http.StatusServiceUnavailable, types.ErrorResponse{Error: "No free ports left, try again later"})
+			return
+		} else if errors.Is(err, sandbox.ErrFailedToCreateContainer) {
 			auditLog.WithFields(logrus.Fields{
 				"event":     "start_dynamic_challenge",
 				"status":    "failure",
@@ -343,7 +389,7 @@ func StopDynamicChallenge(ctx *gin.Context) {
 		ctx.JSON(http.StatusForbidden, types.ErrorResponse{Error: "Static challenges cannot spawn dynamic containers."})
 		return
 	}
-	challengeSandbox, ok := shared.SandBoxMap.Get(*user.TeamID)
+	challengeSandbox, ok := shared.SandBoxMap.Get(shared.SandBoxKey{TeamID: *user.TeamID, ChallengeID: challengeID})
 	if !ok {
 		auditLog.WithFields(logrus.Fields{
 			"event":         "stop_dynamic_challenge",
@@ -544,7 +590,7 @@ func ExtendDynamicChallenge(ctx *gin.Context) {
 		ctx.JSON(http.StatusForbidden, types.ErrorResponse{Error: "Static challenges cannot spawn dynamic containers."})
 		return
 	}
-	challengeSandbox, ok := shared.SandBoxMap.Get(*user.TeamID)
+	challengeSandbox, ok := shared.SandBoxMap.Get(shared.SandBoxKey{TeamID: *user.TeamID, ChallengeID: challengeID})
 	if !ok {
 		auditLog.WithFields(logrus.Fields{
 			"event":         "extend_dynamic_challenge",
@@ -602,6 +648,7 @@ func ExtendDynamicChallenge(ctx *gin.Context) {
 // @Failure      404  {object}  types.ErrorResponse
 // @Failure      409  {object}  types.ErrorResponse
 // @Failure      500  {object}  types.ErrorResponse
+// @Failure      503  {object}  types.ErrorResponse
 // @Router       /challenges/{id}/regenerate [post]
 func RegenerateDynamicChallenge(ctx *gin.Context) {
 	auditLog := utils.Logger.WithField("type", "audit")
@@ -652,7 +699,7 @@ func RegenerateDynamicChallenge(ctx *gin.Context) {
 	}
 	challenge, challengeCacheHit := shared.ChallengeCache.Get(challengeID)
 	if !challengeCacheHit {
-		if err := models.DB.First(&challenge, challengeID).Error; err != nil {
+		if err := models.DB.Preload("DynamicConfig").First(&challenge, challengeID).Error; err != nil {
 			auditLog.WithFields(logrus.Fields{
 				"event":     "regenerate_dynamic_challenge",
 				"status":    "failure",
@@ -683,7 +730,7 @@ func RegenerateDynamicChallenge(ctx *gin.Context) {
 		ctx.JSON(http.StatusForbidden, types.ErrorResponse{Error: "Static challenges cannot spawn dynamic containers."})
 		return
 	}
-	challengeSandbox, ok := shared.SandBoxMap.Get(*user.TeamID)
+	challengeSandbox, ok := shared.SandBoxMap.Get(shared.SandBoxKey{TeamID: *user.TeamID, ChallengeID: challengeID})
 	if !ok {
 		auditLog.WithFields(logrus.Fields{
 			"event":         "regenerate_dynamic_challenge",
@@ -745,6 +792,20 @@ func RegenerateDynamicChallenge(ctx *gin.Context) {
 			}).Error("Failed to discard container during regeneration")
 			ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to discard container"})
 			return
+		} else if errors.Is(err, sandbox.ErrNoFreePorts) {
+			auditLog.WithFields(logrus.Fields{
+				"event":         "regenerate_dynamic_challenge",
+				"status":        "failure",
+				"reason":        "no_free_ports",
+				"user_id":       user.ID,
+				"team_id":       *user.TeamID,
+				"challenge":     challengeID,
+				"user_hit":      userCacheHit,
+				"challenge_hit": challengeCacheHit,
+				"ip":            ctx.ClientIP(),
+			}).Error("Port range exhausted during regeneration")
+			ctx.JSON(http.StatusServiceUnavailable, types.ErrorResponse{Error: "No free ports left, try again later"})
+			return
 		} else if errors.Is(err, sandbox.ErrFailedToCreateContainer) {
 			auditLog.WithFields(logrus.Fields{
 				"event":         "regenerate_dynamic_challenge",
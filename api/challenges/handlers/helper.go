@@ -3,16 +3,28 @@ package handlers
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"math"
+	"net/http"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/gin-gonic/gin"
 	"github.com/intraware/rodan/api/shared"
+	"github.com/intraware/rodan/internal/events"
+	"github.com/intraware/rodan/internal/flags"
 	"github.com/intraware/rodan/internal/models"
+	"github.com/intraware/rodan/internal/ratelimit"
 	"github.com/intraware/rodan/internal/sandbox"
+	"github.com/intraware/rodan/internal/scoring"
+	"github.com/intraware/rodan/internal/types"
+	"github.com/intraware/rodan/internal/utils"
 	"github.com/intraware/rodan/internal/utils/values"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 )
 
 const (
@@ -20,12 +32,6 @@ const (
 	maxBackoff     = 1 * time.Hour
 )
 
-var (
-	userCount     atomic.Int64
-	userUpdatedAt atomic.Int64
-	userBackoff   atomic.Int64
-)
-
 type challengeSolveStat struct {
 	SolveCount     atomic.Int64
 	LastUpdateUnix atomic.Int64
@@ -34,43 +40,10 @@ type challengeSolveStat struct {
 
 var challengeStats sync.Map
 
-func getUserCount() int {
-	now := time.Now()
-	userBlackList := shared.UserBlackList
-	teamBlackList := shared.TeamBlackList
-	last := time.Unix(0, userUpdatedAt.Load())
-	backoff := time.Duration(userBackoff.Load())
-	if backoff == 0 {
-		backoff = initialBackoff
-	}
-	if time.Since(last) < backoff {
-		return int(userCount.Load())
-	}
-	var count int64
-	err := models.DB.Where("id NOT IN ? AND team_id NOT IN ?", userBlackList, teamBlackList).Model(&models.User{}).Count(&count).Error
-	if err != nil {
-		return int(userCount.Load())
-	}
-
-	current := userCount.Load()
-	if int64(count) == current {
-		newBackoff := backoff * 2
-		newBackoff = max(maxBackoff, newBackoff)
-		userBackoff.Store(int64(newBackoff))
-	} else {
-		userCount.Store(int64(count))
-		userBackoff.Store(int64(initialBackoff))
-	}
-	userUpdatedAt.Store(now.UnixNano())
-	return int(count)
-}
-
 func getSolveCount(challengeID uint) int {
 	now := time.Now()
 	val, _ := challengeStats.LoadOrStore(challengeID, &challengeSolveStat{})
 	stat := val.(*challengeSolveStat)
-	userBlackList := shared.UserBlackList
-	teamBlackList := shared.TeamBlackList
 	last := time.Unix(0, stat.LastUpdateUnix.Load())
 	backoff := time.Duration(stat.Backoff.Load())
 	if backoff == 0 {
@@ -80,17 +53,13 @@ func getSolveCount(challengeID uint) int {
 		return int(stat.SolveCount.Load())
 	}
 	var count int64
-	err := models.DB.Model(&models.Solve{}).
-		Where("challenge_id = ? AND user_id NOT IN ? AND team_id NOT IN ?", challengeID, userBlackList, teamBlackList).
-		Count(&count).Error
-	if err != nil {
+	query := models.DB.Model(&models.Solve{}).Scopes(countedSolves).Where("challenge_id = ?", challengeID)
+	if err := query.Count(&count).Error; err != nil {
 		return int(stat.SolveCount.Load())
 	}
 	current := stat.SolveCount.Load()
 	if int64(count) == current {
-		newBackoff := backoff * 2
-		newBackoff = max(maxBackoff, newBackoff)
-		stat.Backoff.Store(int64(newBackoff))
+		stat.Backoff.Store(int64(min(backoff*2, maxBackoff)))
 	} else {
 		stat.SolveCount.Store(int64(count))
 		stat.Backoff.Store(int64(initialBackoff))
@@ -99,36 +68,53 @@ func getSolveCount(challengeID uint) int {
 	return int(count)
 }
 
-func calcPoints(minPoints, maxPoints int, challengeID uint) (int, error) {
-	solves := getSolveCount(challengeID)
-	users := getUserCount()
-	score := smoothScore(solves, maxPoints, minPoints, users, 3, 2.25)
-	return score, nil
+// countedSolves leaves out solves of blacklisted users and teams, which do not count
+// towards a challenge's solves
+func countedSolves(db *gorm.DB) *gorm.DB {
+	if userBlackList := shared.UserBlackList; len(userBlackList) > 0 {
+		db = db.Where("user_id NOT IN (?)", userBlackList)
+	}
+	if teamBlackList := shared.TeamBlackList; len(teamBlackList) > 0 {
+		db = db.Where("team_id NOT IN (?)", teamBlackList)
+	}
+	return db
 }
 
-//solves	Current number of solves
-//maxPoints	Score when 0–offset users solved
-//minPoints	Floor score (e.g. 100)
-//total	Expected max solves (e.g. 300)
-//offset	First N solvers get full score
-//power	Controls how sharp the decay is
+// invalidateSolveCount forces the next getSolveCount to hit the DB
+func invalidateSolveCount(challengeID uint) {
+	challengeStats.Delete(challengeID)
+}
 
-// First `offset` solvers get full score. After that, score decays smoothly.
-func smoothScore(solves int, maxPoints int, minPoints int, total int, offset int, power float64) int {
-	if solves <= offset {
-		return maxPoints
-	}
-	if solves > total {
-		solves = total
-	}
-	x := float64(solves-offset) / float64(total-offset)
-	score := float64(maxPoints) - float64(maxPoints-minPoints)*math.Pow(x, power)
-	score = max(float64(minPoints), score)
-	return int(math.Round(score))
+func calcPoints(challenge models.Challenge) int {
+	return scoring.ChallengePoints(challenge, getSolveCount(challenge.ID), scoring.PlayerCount())
 }
 
 var dynFlagMap sync.Map
 
+// publishSolve announces a solve and any blood. While the scoreboard is frozen
+// only the solving team hears about it so the public standings do not leak.
+func publishSolve(user models.User, teamID uint, challenge models.Challenge, bloodCount uint) {
+	data := events.SolveData{
+		UserID:        user.ID,
+		Username:      user.Username,
+		TeamID:        teamID,
+		ChallengeID:   challenge.ID,
+		ChallengeName: challenge.Name,
+		Blood:         int(bloodCount),
+	}
+	publish := func(kind events.Type) {
+		if shared.ScoreboardFrozen() {
+			events.Publish(events.ForTeam(teamID, kind, data))
+		} else {
+			events.Publish(events.Global(kind, data))
+		}
+	}
+	publish(events.Solve)
+	if kind, ok := events.Blood(int(bloodCount)); ok {
+		publish(kind)
+	}
+}
+
 func getDynamicFlag(challengeID, teamID uint) string {
 	key := fmt.Sprintf("%d:%d", challengeID, teamID)
 	if val, ok := dynFlagMap.Load(key); ok {
@@ -144,7 +130,76 @@ func generateHashedFlag(challengeID, teamID uint) string {
 	input := fmt.Sprintf("%d%s%d", teamID, cfg.Server.Security.FlagSecret, challengeID)
 	hash := sha256.Sum256([]byte(input))
 	hashHex := hex.EncodeToString(hash[:])
-	return fmt.Sprintf("%s{%s}", cfg.App.FlagFormat, hashHex[:32])
+	return flags.Wrap(cfg.App.FlagFormat, hashHex[:32])
+}
+
+// staticFlagMatchers loads the accepted flags of a static challenge. Challenges created
+// before flag matchers existed only have StaticConfig.Flag, which is matched exactly.
+func staticFlagMatchers(challenge models.Challenge) ([]models.FlagMatcher, error) {
+	var matchers []models.FlagMatcher
+	if err := models.DB.Where("challenge_id = ?", challenge.ID).Find(&matchers).Error; err != nil {
+		return nil, err
+	}
+	if len(matchers) > 0 {
+		return matchers, nil
+	}
+	var static models.StaticConfig
+	if err := models.DB.Where("challenge_id = ?", challenge.ID).First(&static).Error; err != nil {
+		return nil, err
+	}
+	if static.Flag == "" {
+		return nil, nil
+	}
+	return []models.FlagMatcher{{ChallengeID: challenge.ID, Flag: static.Flag, Mode: flags.ModeExact}}, nil
+}
+
+// usesTeamFlag reports whether the challenge's flag is derived per team, which is
+// always the case for dynamic challenges and opt-in for static ones
+func usesTeamFlag(challenge models.Challenge) (bool, error) {
+	if !challenge.IsStatic {
+		return true, nil
+	}
+	static, ok := shared.StaticConfig.Get(challenge.ID)
+	if !ok {
+		if err := models.DB.Where("challenge_id = ?", challenge.ID).First(&static).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return false, nil
+			}
+			return false, err
+		}
+		static.Flag = ""
+		shared.StaticConfig.Set(challenge.ID, static)
+	}
+	return static.PerTeam, nil
+}
+
+// hashSubmittedFlag keeps submitted flags out of the audit tables while still letting
+// identical submissions from different teams be matched up. It is keyed with the flag
+// secret so the recorded hashes cannot be brute forced on their own.
+func hashSubmittedFlag(flag string) string {
+	return flags.Hash(values.GetConfig().Server.Security.FlagSecret, flag)
+}
+
+func recordSubmission(ctx *gin.Context, user models.User, challengeID uint, flag string, correct bool) {
+	submission := models.Submission{
+		UserID:      user.ID,
+		TeamID:      *user.TeamID,
+		ChallengeID: challengeID,
+		FlagHash:    hashSubmittedFlag(flag),
+		Correct:     correct,
+		IP:          ctx.ClientIP(),
+	}
+	if err := models.DB.Create(&submission).Error; err != nil {
+		utils.Logger.WithField("type", "audit").WithFields(logrus.Fields{
+			"event":     "record_submission",
+			"status":    "failure",
+			"reason":    "db_error",
+			"user_id":   user.ID,
+			"challenge": challengeID,
+			"ip":        ctx.ClientIP(),
+			"error":     err.Error(),
+		}).Error("Failed to record flag submission")
+	}
 }
 
 func calcBanDuration(strikes int) time.Duration {
@@ -160,6 +215,154 @@ func calcBanDuration(strikes int) time.Duration {
 	return duration
 }
 
+// issueBan records an escalating ban for the user or their team, depending on the ban
+// config, and stops the sandboxes they have running
+func issueBan(user models.User, reason string) error {
+	cfg := values.GetConfig().App.Ban
+	return recordBan(&user.ID, *user.TeamID, cfg.UserBan, cfg.TeamBan, reason)
+}
+
+// recordBan bans the user and/or the team, escalating from their previous bans. userID
+// may be nil for a team only ban.
+func recordBan(userID *uint, teamID uint, banUser, banTeam bool, reason string) error {
+	tx := models.DB.Begin()
+	var count int64
+	query := tx.Model(&models.BanHistory{})
+	if banUser && userID != nil {
+		query = query.Where("user_id = ?", *userID)
+	} else {
+		query = query.Where("team_id = ?", teamID)
+	}
+	if err := query.Count(&count).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	ban := models.BanHistory{
+		UserID:    userID,
+		Context:   reason,
+		ExpiresAt: time.Now().Add(calcBanDuration(int(count) + 1)).Unix(),
+	}
+	var key string
+	if banUser && userID != nil {
+		key = fmt.Sprintf("%d:%d", *userID, teamID)
+		if err := tx.Model(&models.User{}).Where("id = ?", *userID).Update("ban", true).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if banTeam {
+		key = fmt.Sprintf(":%d", teamID)
+		ban.TeamID = &teamID
+		if err := tx.Model(&models.Team{}).Where("id = ?", teamID).Update("ban", true).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err := tx.Create(&ban).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+	shared.BanHistoryCache.Set(key, ban)
+	if userID != nil {
+		shared.UserCache.Delete(*userID)
+	}
+	shared.TeamCache.Delete(teamID)
+	var sandboxes []*sandbox.SandBox
+	for _, box := range shared.SandBoxMap.DumpValues() {
+		if (banUser && userID != nil && box.UserID == *userID) || (banTeam && box.TeamID == teamID) {
+			sandboxes = append(sandboxes, box)
+		}
+	}
+	return stopAllContainers(sandboxes)
+}
+
+// checkSubmitRate applies the per user, team and challenge buckets. It writes the
+// 429 (or the ban once violations pile up) itself and returns false in that case.
+func checkSubmitRate(ctx *gin.Context, user models.User, challengeID uint) bool {
+	auditLog := utils.Logger.WithField("type", "audit")
+	cfg := values.GetConfig().App
+	limits := cfg.RateLimit
+	if !limits.Enabled {
+		return true
+	}
+	teamID := *user.TeamID
+	allowed, retryAfter := shared.SubmitLimiter.Allow(time.Now(),
+		ratelimit.Check{
+			Key:  fmt.Sprintf("user:%d", user.ID),
+			Rule: ratelimit.Rule{Burst: limits.User.Burst, Every: limits.User.RefillEvery},
+		},
+		ratelimit.Check{
+			Key:  fmt.Sprintf("team:%d", teamID),
+			Rule: ratelimit.Rule{Burst: limits.Team.Burst, Every: limits.Team.RefillEvery},
+		},
+		ratelimit.Check{
+			Key:  fmt.Sprintf("challenge:%d:%d", teamID, challengeID),
+			Rule: ratelimit.Rule{Burst: limits.Challenge.Burst, Every: limits.Challenge.RefillEvery},
+		},
+	)
+	if allowed {
+		return true
+	}
+	violationKey := strconv.FormatUint(uint64(user.ID), 10)
+	violations := ratelimit.Count(shared.RateLimitViolations, violationKey, shared.ViolationWindow)
+	auditLog.WithFields(logrus.Fields{
+		"event":       "submit_flag",
+		"status":      "failure",
+		"reason":      "rate_limited",
+		"user_id":     user.ID,
+		"team_id":     teamID,
+		"challenge":   challengeID,
+		"retry_after": retryAfter.String(),
+		"violations":  violations,
+		"ip":          ctx.ClientIP(),
+	}).Warn("Flag submission rate limited")
+	if limits.BanAfter > 0 && violations >= limits.BanAfter && (cfg.Ban.UserBan || cfg.Ban.TeamBan) {
+		shared.RateLimitViolations.Delete(violationKey)
+		if err := issueBan(user, "submission_rate_limit"); err != nil {
+			auditLog.WithFields(logrus.Fields{
+				"event":   "rate_limit_ban",
+				"status":  "failure",
+				"reason":  "db_error_ban",
+				"user_id": user.ID,
+				"team_id": teamID,
+				"ip":      ctx.ClientIP(),
+				"error":   err.Error(),
+			}).Error("Failed to ban after repeated rate limit violations")
+		} else {
+			auditLog.WithFields(logrus.Fields{
+				"event":      "rate_limit_ban",
+				"status":     "success",
+				"user_id":    user.ID,
+				"team_id":    teamID,
+				"violations": violations,
+				"ip":         ctx.ClientIP(),
+			}).Warn("Ban issued after repeated rate limit violations")
+			if cfg.Ban.UserBan {
+				ctx.JSON(http.StatusForbidden, types.ErrorResponse{Error: "Account got banned"})
+			} else {
+				ctx.JSON(http.StatusForbidden, types.ErrorResponse{Error: "Team account got banned"})
+			}
+			return false
+		}
+	}
+	ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	ctx.JSON(http.StatusTooManyRequests, types.ErrorResponse{Error: "Too many submissions, slow down"})
+	return false
+}
+
+var teamSandBoxLocks sync.Map
+
+// lockTeamSandBoxes serialises sandbox creation per team so the running limit can't be raced
+func lockTeamSandBoxes(teamID uint) func() {
+	val, _ := teamSandBoxLocks.LoadOrStore(teamID, &sync.Mutex{})
+	mu := val.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
 func stopAllContainers(banSandbox []*sandbox.SandBox) (err error) {
 	err = nil
 	for _, sandbox := range banSandbox {
@@ -170,3 +373,99 @@ func stopAllContainers(banSandbox []*sandbox.SandBox) (err error) {
 	}
 	return
 }
+
+func parseHintParams(ctx *gin.Context, event string, userID uint) (challengeID, hintID uint, ok bool) {
+	auditLog := utils.Logger.WithField("type", "audit")
+	challengeIDStr := ctx.Param("id")
+	cid, err := strconv.ParseUint(challengeIDStr, 10, 64)
+	if err != nil {
+		auditLog.WithFields(logrus.Fields{
+			"event":     event,
+			"status":    "failure",
+			"reason":    "invalid_challenge_id",
+			"user_id":   userID,
+			"challenge": challengeIDStr,
+			"ip":        ctx.ClientIP(),
+		}).Warn("Invalid challenge ID in request")
+		ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid challenge ID"})
+		return
+	}
+	hintIDStr := ctx.Param("hint_id")
+	hid, err := strconv.ParseUint(hintIDStr, 10, 64)
+	if err != nil {
+		auditLog.WithFields(logrus.Fields{
+			"event":     event,
+			"status":    "failure",
+			"reason":    "invalid_hint_id",
+			"user_id":   userID,
+			"challenge": cid,
+			"hint_id":   hintIDStr,
+			"ip":        ctx.ClientIP(),
+		}).Warn("Invalid hint ID in request")
+		ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid hint ID"})
+		return
+	}
+	return uint(cid), uint(hid), true
+}
+
+// getVisibleHint loads a hint and makes sure it belongs to a visible challenge
+func getVisibleHint(ctx *gin.Context, event string, userID, challengeID, hintID uint) (hint models.Hint, ok bool) {
+	auditLog := utils.Logger.WithField("type", "audit")
+	if _, hit := shared.ChallengeCache.Get(challengeID); !hit {
+		var challenge models.Challenge
+		if err := models.DB.Where("is_visible = ?", true).First(&challenge, challengeID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				auditLog.WithFields(logrus.Fields{
+					"event":     event,
+					"status":    "failure",
+					"reason":    "challenge_not_found",
+					"user_id":   userID,
+					"challenge": challengeID,
+					"ip":        ctx.ClientIP(),
+				}).Warn("Challenge not found")
+				ctx.JSON(http.StatusNotFound, types.ErrorResponse{Error: "Challenge not found"})
+				return
+			}
+			auditLog.WithFields(logrus.Fields{
+				"event":     event,
+				"status":    "failure",
+				"reason":    "db_error_challenge_lookup",
+				"user_id":   userID,
+				"challenge": challengeID,
+				"ip":        ctx.ClientIP(),
+				"error":     err.Error(),
+			}).Error("Error fetching challenge from DB")
+			ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Database error"})
+			return
+		}
+		shared.ChallengeCache.Set(challengeID, challenge)
+	}
+	if err := models.DB.Where("challenge_id = ?", challengeID).First(&hint, hintID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			auditLog.WithFields(logrus.Fields{
+				"event":     event,
+				"status":    "failure",
+				"reason":    "hint_not_found",
+				"user_id":   userID,
+				"challenge": challengeID,
+				"hint_id":   hintID,
+				"ip":        ctx.ClientIP(),
+			}).Warn("Hint not found")
+			ctx.JSON(http.StatusNotFound, types.ErrorResponse{Error: "Hint not found"})
+			return
+		}
+		auditLog.WithFields(logrus.Fields{
+			"event":     event,
+			"status":    "failure",
+			"reason":    "db_error_hint_lookup",
+			"user_id":   userID,
+			"challenge": challengeID,
+			"hint_id":   hintID,
+			"ip":        ctx.ClientIP(),
+			"error":     err.Error(),
+		}).Error("Error fetching hint from DB")
+		ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Database error"})
+		return
+	}
+	return hint, true
+}
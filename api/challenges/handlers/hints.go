@@ -1,7 +1,400 @@
 package handlers
 
-import "github.com/gin-gonic/gin"
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
 
-func ListHints(ctx *gin.Context) {}
-func GetHint(ctx *gin.Context)   {}
-func BuyHint(ctx *gin.Context)   {}
+	"github.com/gin-gonic/gin"
+	"github.com/intraware/rodan/api/leaderboard"
+	"github.com/intraware/rodan/api/shared"
+	"github.com/intraware/rodan/internal/models"
+	"github.com/intraware/rodan/internal/types"
+	"github.com/intraware/rodan/internal/utils"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// ListHints godoc
+// @Summary      List hints of a challenge
+// @Description  Retrieves the hints of a challenge with their cost only, the content is never included
+// @Security     BearerAuth
+// @Tags         hints
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "Challenge ID"
+// @Success      200  {object}  []hintItem
+// @Failure      400  {object}  types.ErrorResponse
+// @Failure      404  {object}  types.ErrorResponse
+// @Failure      500  {object}  types.ErrorResponse
+// @Router       /challenges/{id}/hint/list [get]
+func ListHints(ctx *gin.Context) {
+	auditLog := utils.Logger.WithField("type", "audit")
+	challengeIDStr := ctx.Param("id")
+	id, err := strconv.ParseUint(challengeIDStr, 10, 64)
+	if err != nil {
+		auditLog.WithFields(logrus.Fields{
+			"event":     "list_hints",
+			"status":    "failure",
+			"reason":    "invalid_challenge_id",
+			"challenge": challengeIDStr,
+			"ip":        ctx.ClientIP(),
+		}).Warn("Invalid challenge ID in request")
+		ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid challenge ID"})
+		return
+	}
+	challengeID := uint(id)
+	challenge, challengeCacheHit := shared.ChallengeCache.Get(challengeID)
+	if !challengeCacheHit {
+		if err := models.DB.Where("is_visible = ?", true).First(&challenge, challengeID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				auditLog.WithFields(logrus.Fields{
+					"event":     "list_hints",
+					"status":    "failure",
+					"reason":    "challenge_not_found",
+					"challenge": challengeID,
+					"ip":        ctx.ClientIP(),
+				}).Warn("Challenge not found")
+				ctx.JSON(http.StatusNotFound, types.ErrorResponse{Error: "Challenge not found"})
+				return
+			}
+			auditLog.WithFields(logrus.Fields{
+				"event":     "list_hints",
+				"status":    "failure",
+				"reason":    "db_error_challenge_lookup",
+				"challenge": challengeID,
+				"ip":        ctx.ClientIP(),
+				"error":     err.Error(),
+			}).Error("Error fetching challenge from DB")
+			ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Database error"})
+			return
+		}
+		shared.ChallengeCache.Set(challengeID, challenge)
+	}
+	var hints []models.Hint
+	if err := models.DB.Select("id, points, challenge_id").
+		Where("challenge_id = ?", challengeID).
+		Order("points, id").
+		Find(&hints).Error; err != nil {
+		auditLog.WithFields(logrus.Fields{
+			"event":     "list_hints",
+			"status":    "failure",
+			"reason":    "db_error_hint_lookup",
+			"challenge": challengeID,
+			"ip":        ctx.ClientIP(),
+			"error":     err.Error(),
+		}).Error("Error fetching hints from DB")
+		ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Database error"})
+		return
+	}
+	hintList := make([]hintItem, len(hints))
+	for idx, hint := range hints {
+		hintList[idx] = hintItem{
+			ID:     hint.ID,
+			Points: hint.Points,
+		}
+	}
+	auditLog.WithFields(logrus.Fields{
+		"event":         "list_hints",
+		"status":        "success",
+		"challenge":     challengeID,
+		"count":         len(hintList),
+		"challenge_hit": challengeCacheHit,
+		"ip":            ctx.ClientIP(),
+	}).Info("Fetched hint list successfully")
+	ctx.JSON(http.StatusOK, hintList)
+}
+
+// GetHint godoc
+// @Summary      Get a hint
+// @Description  Retrieves the content of a hint, only if the user's team has bought it
+// @Security     BearerAuth
+// @Tags         hints
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string  true  "Challenge ID"
+// @Param        hint_id  path      string  true  "Hint ID"
+// @Success      200      {object}  hintDetail
+// @Failure      400      {object}  types.ErrorResponse
+// @Failure      402      {object}  types.ErrorResponse
+// @Failure      403      {object}  types.ErrorResponse
+// @Failure      404      {object}  types.ErrorResponse
+// @Failure      500      {object}  types.ErrorResponse
+// @Router       /challenges/{id}/hint/{hint_id} [get]
+func GetHint(ctx *gin.Context) {
+	auditLog := utils.Logger.WithField("type", "audit")
+	userID := ctx.GetUint("user_id")
+	user, userCacheHit := shared.UserCache.Get(userID)
+	if !userCacheHit {
+		if err := models.DB.First(&user, userID).Error; err != nil {
+			auditLog.WithFields(logrus.Fields{
+				"event":    "get_hint",
+				"status":   "failure",
+				"reason":   "db_error_user_lookup",
+				"user_id":  userID,
+				"ip":       ctx.ClientIP(),
+				"error":    err.Error(),
+				"user_hit": userCacheHit,
+			}).Error("Failed to fetch user from DB")
+			ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Database error"})
+			return
+		}
+		shared.UserCache.Set(userID, user)
+	}
+	if user.TeamID == nil {
+		auditLog.WithFields(logrus.Fields{
+			"event":    "get_hint",
+			"status":   "failure",
+			"reason":   "no_team",
+			"user_id":  user.ID,
+			"ip":       ctx.ClientIP(),
+			"user_hit": userCacheHit,
+		}).Warn("User is not part of a team")
+		ctx.JSON(http.StatusForbidden, types.ErrorResponse{Error: "User should belong to a team"})
+		return
+	}
+	challengeID, hintID, ok := parseHintParams(ctx, "get_hint", user.ID)
+	if !ok {
+		return
+	}
+	hint, ok := getVisibleHint(ctx, "get_hint", user.ID, challengeID, hintID)
+	if !ok {
+		return
+	}
+	key := fmt.Sprintf("%d:%d", *user.TeamID, hintID)
+	purchased, hintCacheHit := shared.TeamHintCache.Get(key)
+	if !hintCacheHit {
+		err := models.DB.Where("team_id = ? AND hint_id = ?", *user.TeamID, hintID).First(&models.HintPurchase{}).Error
+		if err == nil {
+			purchased = true
+			shared.TeamHintCache.Set(key, true)
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			auditLog.WithFields(logrus.Fields{
+				"event":     "get_hint",
+				"status":    "failure",
+				"reason":    "db_error_purchase_lookup",
+				"user_id":   user.ID,
+				"team_id":   *user.TeamID,
+				"challenge": challengeID,
+				"hint_id":   hintID,
+				"ip":        ctx.ClientIP(),
+				"error":     err.Error(),
+			}).Error("Error checking hint purchase")
+			ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Database error"})
+			return
+		}
+	}
+	if !purchased {
+		auditLog.WithFields(logrus.Fields{
+			"event":     "get_hint",
+			"status":    "failure",
+			"reason":    "not_purchased",
+			"user_id":   user.ID,
+			"team_id":   *user.TeamID,
+			"challenge": challengeID,
+			"hint_id":   hintID,
+			"hint_hit":  hintCacheHit,
+			"ip":        ctx.ClientIP(),
+		}).Warn("Hint is not bought by the team")
+		ctx.JSON(http.StatusPaymentRequired, types.ErrorResponse{Error: "Hint is not bought by your team"})
+		return
+	}
+	auditLog.WithFields(logrus.Fields{
+		"event":     "get_hint",
+		"status":    "success",
+		"user_id":   user.ID,
+		"team_id":   *user.TeamID,
+		"challenge": challengeID,
+		"hint_id":   hintID,
+		"user_hit":  userCacheHit,
+		"hint_hit":  hintCacheHit,
+		"ip":        ctx.ClientIP(),
+	}).Info("Fetched hint successfully")
+	ctx.JSON(http.StatusOK, hintDetail{
+		ID:          hint.ID,
+		ChallengeID: hint.ChallengeID,
+		Points:      hint.Points,
+		Context:     hint.Context,
+	})
+}
+
+// BuyHint godoc
+// @Summary      Buy a hint
+// @Description  Buys a hint for the user's team, the cost is subtracted from the team's score
+// @Security     BearerAuth
+// @Tags         hints
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string  true  "Challenge ID"
+// @Param        hint_id  path      string  true  "Hint ID"
+// @Success      200      {object}  hintDetail
+// @Failure      400      {object}  types.ErrorResponse
+// @Failure      403      {object}  types.ErrorResponse
+// @Failure      404      {object}  types.ErrorResponse
+// @Failure      409      {object}  types.ErrorResponse
+// @Failure      500      {object}  types.ErrorResponse
+// @Router       /challenges/{id}/hint/{hint_id}/buy [post]
+func BuyHint(ctx *gin.Context) {
+	auditLog := utils.Logger.WithField("type", "audit")
+	if reason, msg, open := shared.SubmissionWindow(); !open {
+		auditLog.WithFields(logrus.Fields{
+			"event":  "buy_hint",
+			"status": "failure",
+			"reason": reason,
+			"ip":     ctx.ClientIP(),
+		}).Warn(msg)
+		ctx.JSON(http.StatusForbidden, types.ErrorResponse{Error: msg})
+		return
+	}
+	userID := ctx.GetUint("user_id")
+	user, userCacheHit := shared.UserCache.Get(userID)
+	if !userCacheHit {
+		if err := models.DB.First(&user, userID).Error; err != nil {
+			auditLog.WithFields(logrus.Fields{
+				"event":    "buy_hint",
+				"status":   "failure",
+				"reason":   "db_error_user_lookup",
+				"user_id":  userID,
+				"ip":       ctx.ClientIP(),
+				"error":    err.Error(),
+				"user_hit": userCacheHit,
+			}).Error("Failed to fetch user from DB")
+			ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Database error"})
+			return
+		}
+		shared.UserCache.Set(userID, user)
+	}
+	if user.TeamID == nil {
+		auditLog.WithFields(logrus.Fields{
+			"event":    "buy_hint",
+			"status":   "failure",
+			"reason":   "no_team",
+			"user_id":  user.ID,
+			"ip":       ctx.ClientIP(),
+			"user_hit": userCacheHit,
+		}).Warn("User is not part of a team")
+		ctx.JSON(http.StatusForbidden, types.ErrorResponse{Error: "User should belong to a team"})
+		return
+	}
+	challengeID, hintID, ok := parseHintParams(ctx, "buy_hint", user.ID)
+	if !ok {
+		return
+	}
+	hint, ok := getVisibleHint(ctx, "buy_hint", user.ID, challengeID, hintID)
+	if !ok {
+		return
+	}
+	teamID := *user.TeamID
+	tx := models.DB.Begin()
+	if err := tx.Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Database error"})
+		return
+	}
+	var count int64
+	if err := tx.Model(&models.HintPurchase{}).
+		Where("team_id = ? AND hint_id = ?", teamID, hintID).
+		Count(&count).Error; err != nil {
+		tx.Rollback()
+		auditLog.WithFields(logrus.Fields{
+			"event":     "buy_hint",
+			"status":    "failure",
+			"reason":    "db_error_purchase_lookup",
+			"user_id":   user.ID,
+			"team_id":   teamID,
+			"challenge": challengeID,
+			"hint_id":   hintID,
+			"ip":        ctx.ClientIP(),
+			"error":     err.Error(),
+		}).Error("Error checking hint purchase")
+		ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Database error"})
+		return
+	}
+	if count > 0 {
+		tx.Rollback()
+		shared.TeamHintCache.Set(fmt.Sprintf("%d:%d", teamID, hintID), true)
+		auditLog.WithFields(logrus.Fields{
+			"event":     "buy_hint",
+			"status":    "failure",
+			"reason":    "already_purchased",
+			"user_id":   user.ID,
+			"team_id":   teamID,
+			"challenge": challengeID,
+			"hint_id":   hintID,
+			"ip":        ctx.ClientIP(),
+		}).Warn("Hint already bought by the team")
+		ctx.JSON(http.StatusConflict, types.ErrorResponse{Error: "Hint already bought by your team"})
+		return
+	}
+	purchase := models.HintPurchase{
+		HintID:      hintID,
+		TeamID:      teamID,
+		UserID:      user.ID,
+		ChallengeID: challengeID,
+		Points:      hint.Points,
+	}
+	if err := tx.Create(&purchase).Error; err != nil {
+		tx.Rollback()
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			auditLog.WithFields(logrus.Fields{
+				"event":     "buy_hint",
+				"status":    "failure",
+				"reason":    "already_purchased",
+				"user_id":   user.ID,
+				"team_id":   teamID,
+				"challenge": challengeID,
+				"hint_id":   hintID,
+				"ip":        ctx.ClientIP(),
+			}).Warn("Hint already bought by the team")
+			ctx.JSON(http.StatusConflict, types.ErrorResponse{Error: "Hint already bought by your team"})
+			return
+		}
+		auditLog.WithFields(logrus.Fields{
+			"event":     "buy_hint",
+			"status":    "failure",
+			"reason":    "db_error_purchase",
+			"user_id":   user.ID,
+			"team_id":   teamID,
+			"challenge": challengeID,
+			"hint_id":   hintID,
+			"ip":        ctx.ClientIP(),
+			"error":     err.Error(),
+		}).Error("Failed to record hint purchase")
+		ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to buy hint"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		auditLog.WithFields(logrus.Fields{
+			"event":     "buy_hint",
+			"status":    "failure",
+			"reason":    "db_error_commit",
+			"user_id":   user.ID,
+			"team_id":   teamID,
+			"challenge": challengeID,
+			"hint_id":   hintID,
+			"ip":        ctx.ClientIP(),
+			"error":     err.Error(),
+		}).Error("Failed to commit hint purchase")
+		ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to buy hint"})
+		return
+	}
+	shared.TeamHintCache.Set(fmt.Sprintf("%d:%d", teamID, hintID), true)
+	leaderboard.MarkLeaderboardDirty()
+	auditLog.WithFields(logrus.Fields{
+		"event":     "buy_hint",
+		"status":    "success",
+		"user_id":   user.ID,
+		"team_id":   teamID,
+		"challenge": challengeID,
+		"hint_id":   hintID,
+		"points":    hint.Points,
+		"user_hit":  userCacheHit,
+		"ip":        ctx.ClientIP(),
+	}).Info("Hint bought successfully")
+	ctx.JSON(http.StatusOK, hintDetail{
+		ID:          hint.ID,
+		ChallengeID: hint.ChallengeID,
+		Points:      hint.Points,
+		Context:     hint.Context,
+	})
+}
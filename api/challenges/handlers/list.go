@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"sort"
+
+	"github.com/intraware/rodan/api/shared"
+	"github.com/intraware/rodan/internal/models"
+	"github.com/intraware/rodan/internal/scoring"
+)
+
+type challengeSolves struct {
+	ChallengeID uint
+	Solves      int
+}
+
+// buildChallengeList renders the team's board with a fixed number of queries, however
+// many challenges there are: the challenges, all solve counts, the team's solves and
+// the prerequisites
+func buildChallengeList(teamID uint) ([]shared.ChallengeListItem, error) {
+	var challenges []models.Challenge
+	if err := models.DB.Select("id, name, category_id, difficulty_id, points_min, points_max, scoring, decay, tags").
+		Where("is_visible = ?", true).
+		Order("id").
+		Find(&challenges).Error; err != nil {
+		return nil, err
+	}
+	var counts []challengeSolves
+	if err := models.DB.Model(&models.Solve{}).
+		Scopes(countedSolves).
+		Select("challenge_id, COUNT(*) AS solves").
+		Group("challenge_id").
+		Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	solves := make(map[uint]int, len(counts))
+	for _, c := range counts {
+		solves[c.ChallengeID] = c.Solves
+	}
+	progress, err := loadTeamProgress(teamID)
+	if err != nil {
+		return nil, err
+	}
+	prereqs, err := getPrerequisites()
+	if err != nil {
+		return nil, err
+	}
+	players := scoring.PlayerCount()
+	list := make([]shared.ChallengeListItem, 0, len(challenges))
+	for _, challenge := range challenges {
+		if !progress.meets(prereqs[challenge.ID]) {
+			continue
+		}
+		tags := challenge.Tags
+		if tags == nil {
+			tags = []string{}
+		}
+		list = append(list, shared.ChallengeListItem{
+			ID:         challenge.ID,
+			Title:      challenge.Name,
+			Category:   shared.CategoryLabel(challenge.CategoryID),
+			Difficulty: shared.DifficultyLabel(challenge.DifficultyID),
+			Points:     scoring.ChallengePoints(challenge, solves[challenge.ID], players),
+			Solves:     solves[challenge.ID],
+			Tags:       tags,
+			Solved:     progress.solved[challenge.ID],
+		})
+	}
+	// boards are grouped the way the categories are ordered, then by difficulty
+	order := func(l *shared.Label) int {
+		if l == nil {
+			return 0
+		}
+		return l.Order
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		if a, b := order(list[i].Category), order(list[j].Category); a != b {
+			return a < b
+		}
+		return order(list[i].Difficulty) < order(list[j].Difficulty)
+	})
+	return list, nil
+}
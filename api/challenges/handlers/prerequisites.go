@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/intraware/rodan/api/shared"
+	"github.com/intraware/rodan/internal/models"
+	"github.com/intraware/rodan/internal/types"
+	"github.com/intraware/rodan/internal/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// teamProgress is what a team has achieved so far, as far as prerequisites care
+type teamProgress struct {
+	solved         map[uint]bool
+	categoryPoints map[uint]int
+}
+
+// loadTeamProgress counts category points as they were awarded at solve time, so a
+// challenge stays unlocked when the solved ones decay afterwards
+func loadTeamProgress(teamID uint) (teamProgress, error) {
+	progress := teamProgress{solved: map[uint]bool{}, categoryPoints: map[uint]int{}}
+	var solves []models.Solve
+	if err := models.DB.Select("challenge_id, points").Where("team_id = ?", teamID).Find(&solves).Error; err != nil {
+		return progress, err
+	}
+	if len(solves) == 0 {
+		return progress, nil
+	}
+	solvedIDs := make([]uint, 0, len(solves))
+	for _, solve := range solves {
+		solvedIDs = append(solvedIDs, solve.ChallengeID)
+	}
+	var challenges []models.Challenge
+	if err := models.DB.Select("id, category_id, points_min, points_max, scoring, decay").Where("id IN ?", solvedIDs).Find(&challenges).Error; err != nil {
+		return progress, err
+	}
+	byID := make(map[uint]models.Challenge, len(challenges))
+	for _, challenge := range challenges {
+		byID[challenge.ID] = challenge
+	}
+	for _, solve := range solves {
+		challenge, ok := byID[solve.ChallengeID]
+		if !ok {
+			continue
+		}
+		progress.solved[challenge.ID] = true
+		if challenge.CategoryID == nil {
+			continue
+		}
+		points := solve.Points
+		if points == 0 {
+			// solved before awarded points were recorded
+			points = calcPoints(challenge)
+		}
+		progress.categoryPoints[*challenge.CategoryID] += points
+	}
+	return progress, nil
+}
+
+func (p teamProgress) meets(reqs []models.ChallengePrerequisite) bool {
+	for _, req := range reqs {
+		if req.RequiresID != nil && !p.solved[*req.RequiresID] {
+			return false
+		}
+		if req.CategoryID != nil && p.categoryPoints[*req.CategoryID] < req.MinPoints {
+			return false
+		}
+	}
+	return true
+}
+
+// getPrerequisites returns the prerequisites of every challenge that has some
+func getPrerequisites() (map[uint][]models.ChallengePrerequisite, error) {
+	var rows []models.ChallengePrerequisite
+	if err := models.DB.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	byChallenge := make(map[uint][]models.ChallengePrerequisite)
+	for _, row := range rows {
+		byChallenge[row.ChallengeID] = append(byChallenge[row.ChallengeID], row)
+	}
+	return byChallenge, nil
+}
+
+func getChallengePrerequisites(challengeID uint) ([]models.ChallengePrerequisite, error) {
+	if reqs, ok := shared.PrerequisiteCache.Get(challengeID); ok {
+		return reqs, nil
+	}
+	var reqs []models.ChallengePrerequisite
+	if err := models.DB.Where("challenge_id = ?", challengeID).Find(&reqs).Error; err != nil {
+		return nil, err
+	}
+	shared.PrerequisiteCache.Set(challengeID, reqs)
+	return reqs, nil
+}
+
+// checkUnlocked writes a 403 (or 500) and returns false when the team has not met
+// the prerequisites of the challenge yet
+func checkUnlocked(ctx *gin.Context, event string, user models.User, challengeID uint) bool {
+	auditLog := utils.Logger.WithField("type", "audit")
+	teamID := *user.TeamID
+	reqs, err := getChallengePrerequisites(challengeID)
+	if err == nil && len(reqs) == 0 {
+		return true
+	}
+	var progress teamProgress
+	if err == nil {
+		progress, err = loadTeamProgress(teamID)
+	}
+	if err != nil {
+		auditLog.WithFields(logrus.Fields{
+			"event":     event,
+			"status":    "failure",
+			"reason":    "db_error_prerequisites",
+			"user_id":   user.ID,
+			"team_id":   teamID,
+			"challenge": challengeID,
+			"ip":        ctx.ClientIP(),
+			"error":     err.Error(),
+		}).Error("Failed to check challenge prerequisites")
+		ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Database error"})
+		return false
+	}
+	if progress.meets(reqs) {
+		return true
+	}
+	auditLog.WithFields(logrus.Fields{
+		"event":     event,
+		"status":    "failure",
+		"reason":    "challenge_locked",
+		"user_id":   user.ID,
+		"team_id":   teamID,
+		"challenge": challengeID,
+		"ip":        ctx.ClientIP(),
+	}).Warn("Challenge prerequisites not met")
+	ctx.JSON(http.StatusForbidden, types.ErrorResponse{Error: "Challenge is locked"})
+	return false
+}
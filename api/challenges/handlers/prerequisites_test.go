@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/intraware/rodan/internal/models"
+)
+
+func TestTeamProgressMeets(t *testing.T) {
+	id := func(v uint) *uint { return &v }
+	progress := teamProgress{
+		solved:         map[uint]bool{1: true, 2: true},
+		categoryPoints: map[uint]int{10: 300},
+	}
+	cases := []struct {
+		name string
+		reqs []models.ChallengePrerequisite
+		want bool
+	}{
+		{"none", nil, true},
+		{"solved challenge", []models.ChallengePrerequisite{{RequiresID: id(1)}}, true},
+		{"unsolved challenge", []models.ChallengePrerequisite{{RequiresID: id(3)}}, false},
+		{"below threshold", []models.ChallengePrerequisite{{CategoryID: id(10), MinPoints: 301}}, false},
+		{"at threshold", []models.ChallengePrerequisite{{CategoryID: id(10), MinPoints: 300}}, true},
+		{"above threshold", []models.ChallengePrerequisite{{CategoryID: id(10), MinPoints: 100}}, true},
+		{"other category", []models.ChallengePrerequisite{{CategoryID: id(11), MinPoints: 1}}, false},
+		{"all met", []models.ChallengePrerequisite{{RequiresID: id(1)}, {RequiresID: id(2)}, {CategoryID: id(10), MinPoints: 300}}, true},
+		{"one missing", []models.ChallengePrerequisite{{RequiresID: id(1)}, {CategoryID: id(10), MinPoints: 500}}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := progress.meets(tc.reqs); got != tc.want {
+				t.Fatalf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/intraware/rodan/api/shared"
+	"github.com/intraware/rodan/internal/config"
+	"github.com/intraware/rodan/internal/models"
+	"github.com/intraware/rodan/internal/notification"
+	"github.com/intraware/rodan/internal/types"
+	"github.com/intraware/rodan/internal/utils"
+	"github.com/intraware/rodan/internal/utils/values"
+	"github.com/sirupsen/logrus"
+)
+
+type flagSharingData struct {
+	IncidentID      uint   `json:"incident_id"`
+	ChallengeID     uint   `json:"challenge_id"`
+	SubmitterUserID uint   `json:"submitter_user_id"`
+	SubmitterTeamID uint   `json:"submitter_team_id"`
+	OwnerTeamID     uint   `json:"owner_team_id"`
+	Action          string `json:"action"`
+}
+
+// dynamicFlagLen is the number of hex characters inside a generated flag
+const dynamicFlagLen = 32
+
+// looksLikeDynamicFlag rejects submissions that cannot be any team's flag, so plain
+// wrong guesses never reach the owner lookup
+func looksLikeDynamicFlag(format, flag string) bool {
+	inner, ok := strings.CutPrefix(flag, format+"{")
+	if !ok {
+		return false
+	}
+	inner, ok = strings.CutSuffix(inner, "}")
+	if !ok || len(inner) != dynamicFlagLen {
+		return false
+	}
+	_, err := hex.DecodeString(inner)
+	return err == nil
+}
+
+// flagOwners maps the dynamic flag of every team back to the team for one challenge.
+// It is built on first use and topped up with teams that registered since. The flags
+// depend on the flag format, a reload that changes it starts the index over.
+type flagOwners struct {
+	mu       sync.Mutex
+	format   string
+	owners   map[string]uint
+	lastTeam uint
+}
+
+// findFlagOwner works out which team a dynamic flag was generated for
+func findFlagOwner(challengeID uint, flag string) (uint, bool, error) {
+	format := values.GetConfig().App.FlagFormat
+	if !looksLikeDynamicFlag(format, flag) {
+		return 0, false, nil
+	}
+	val, _ := shared.FlagOwnerIndex.LoadOrStore(challengeID, &flagOwners{})
+	index := val.(*flagOwners)
+	index.mu.Lock()
+	defer index.mu.Unlock()
+	if index.owners == nil || index.format != format {
+		index.format, index.owners, index.lastTeam = format, make(map[string]uint), 0
+	}
+	if owner, ok := index.owners[flag]; ok {
+		return owner, true, nil
+	}
+	var teamIDs []uint
+	if err := models.DB.Model(&models.Team{}).Where("id > ?", index.lastTeam).Order("id").Pluck("id", &teamIDs).Error; err != nil {
+		return 0, false, err
+	}
+	for _, teamID := range teamIDs {
+		// generated directly, caching every team's flag in dynFlagMap is not needed here
+		index.owners[generateHashedFlag(challengeID, teamID)] = teamID
+		index.lastTeam = teamID
+	}
+	owner, ok := index.owners[flag]
+	return owner, ok, nil
+}
+
+// handleFlagSharing checks whether a wrong dynamic flag belongs to another team and,
+// if so, records the incident and applies the configured action. It returns true
+// when it has already written the response.
+func handleFlagSharing(ctx *gin.Context, user models.User, challengeID uint, flag string) bool {
+	auditLog := utils.Logger.WithField("type", "audit")
+	cfg := values.GetConfig().App
+	teamID := *user.TeamID
+	ownerID, found, err := findFlagOwner(challengeID, flag)
+	if err != nil {
+		auditLog.WithFields(logrus.Fields{
+			"event":     "flag_sharing",
+			"status":    "failure",
+			"reason":    "db_error_teams",
+			"user_id":   user.ID,
+			"team_id":   teamID,
+			"challenge": challengeID,
+			"ip":        ctx.ClientIP(),
+			"error":     err.Error(),
+		}).Error("Failed to look up flag owner")
+		return false
+	}
+	if !found || ownerID == teamID {
+		return false
+	}
+	action := cfg.FlagSharing.Action
+	if action == "" {
+		action = config.FlagSharingAlert
+	}
+	incident := models.FlagShareIncident{
+		ChallengeID:     challengeID,
+		SubmitterUserID: user.ID,
+		SubmitterTeamID: teamID,
+		OwnerTeamID:     ownerID,
+		FlagHash:        hashSubmittedFlag(flag),
+		Action:          action,
+		IP:              ctx.ClientIP(),
+	}
+	if err := models.DB.Create(&incident).Error; err != nil {
+		auditLog.WithFields(logrus.Fields{
+			"event":     "flag_sharing",
+			"status":    "failure",
+			"reason":    "db_error_incident",
+			"user_id":   user.ID,
+			"team_id":   teamID,
+			"owner":     ownerID,
+			"challenge": challengeID,
+			"ip":        ctx.ClientIP(),
+			"error":     err.Error(),
+		}).Error("Failed to record flag sharing incident")
+	}
+	auditLog.WithFields(logrus.Fields{
+		"event":     "flag_sharing",
+		"status":    "detected",
+		"reason":    "submit_other_flag",
+		"user_id":   user.ID,
+		"team_id":   teamID,
+		"owner":     ownerID,
+		"challenge": challengeID,
+		"action":    action,
+		"ip":        ctx.ClientIP(),
+	}).Warn("Team submitted another team's flag")
+	// in the background, a slow notification backend must not hold up the submission
+	go notification.Send(notification.Payload{
+		Type:    "flag_sharing",
+		Message: fmt.Sprintf("%s (team %d) submitted the flag of team %d on challenge %d", user.Username, teamID, ownerID, challengeID),
+		Data: flagSharingData{
+			IncidentID:      incident.ID,
+			ChallengeID:     challengeID,
+			SubmitterUserID: user.ID,
+			SubmitterTeamID: teamID,
+			OwnerTeamID:     ownerID,
+			Action:          action,
+		},
+	})
+	if action == config.FlagSharingAlert {
+		return false
+	}
+	banUser, banTeam := cfg.Ban.UserBan, cfg.Ban.TeamBan
+	if !banUser && !banTeam {
+		banUser = true
+	}
+	if err := recordBan(&user.ID, teamID, banUser, banTeam, "submit_someone_flag"); err != nil {
+		auditLog.WithFields(logrus.Fields{
+			"event":     "flag_sharing_ban",
+			"status":    "failure",
+			"reason":    "db_error_ban",
+			"user_id":   user.ID,
+			"team_id":   teamID,
+			"challenge": challengeID,
+			"ip":        ctx.ClientIP(),
+			"error":     err.Error(),
+		}).Error("Failed to ban flag submitter")
+		ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to record ban"})
+		return true
+	}
+	if action == config.FlagSharingBanBoth {
+		if err := recordBan(nil, ownerID, false, true, "shared_flag"); err != nil {
+			auditLog.WithFields(logrus.Fields{
+				"event":     "flag_sharing_ban",
+				"status":    "failure",
+				"reason":    "db_error_ban",
+				"team_id":   ownerID,
+				"challenge": challengeID,
+				"ip":        ctx.ClientIP(),
+				"error":     err.Error(),
+			}).Error("Failed to ban flag owner team")
+		}
+	}
+	auditLog.WithFields(logrus.Fields{
+		"event":     "flag_sharing_ban",
+		"status":    "success",
+		"user_id":   user.ID,
+		"team_id":   teamID,
+		"owner":     ownerID,
+		"challenge": challengeID,
+		"action":    action,
+		"ip":        ctx.ClientIP(),
+	}).Warn("Ban issued for flag sharing")
+	if banTeam {
+		ctx.JSON(http.StatusForbidden, types.ErrorResponse{Error: "Team account got banned"})
+	} else {
+		ctx.JSON(http.StatusForbidden, types.ErrorResponse{Error: "Account got banned"})
+	}
+	return true
+}
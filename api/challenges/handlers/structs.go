@@ -1,19 +1,16 @@
 package handlers
 
-type challengeItem struct {
-	ID    uint   `json:"id"`
-	Title string `json:"title"`
-}
+import "github.com/intraware/rodan/api/shared"
 
 type challengeDetail struct {
-	ID         uint   `json:"id"`
-	Name       string `json:"name"`
-	Author     string `json:"author"`
-	Desc       string `json:"desc"`
-	Category   int8   `json:"category"`
-	Difficulty int8   `json:"difficulty"`
-	Points     int    `json:"points"`
-	Solved     bool   `json:"solved"`
+	ID         uint          `json:"id"`
+	Name       string        `json:"name"`
+	Author     string        `json:"author"`
+	Desc       string        `json:"desc"`
+	Category   *shared.Label `json:"category,omitempty"`
+	Difficulty *shared.Label `json:"difficulty,omitempty"`
+	Points     int           `json:"points"`
+	Solved     bool          `json:"solved"`
 }
 
 type submitFlagRequest struct {
@@ -26,9 +23,31 @@ type submitFlagResponse struct {
 }
 
 type challengeConfigResponse struct {
-	ID       uint     `json:"id"`
-	Links    []string `json:"links,omitempty"`
-	Ports    []int    `json:"ports,omitempty"`
-	TimeLeft int64    `json:"timeleft,omitempty"`
-	IsStatic bool     `json:"is_static"`
+	ID       uint       `json:"id"`
+	Links    []string   `json:"links,omitempty"`
+	Ports    []int      `json:"ports,omitempty"`
+	Files    []fileLink `json:"files,omitempty"`
+	TimeLeft int64      `json:"timeleft,omitempty"`
+	IsStatic bool       `json:"is_static"`
+}
+
+// fileLink is a signed download link to an attachment, valid until Expires
+type fileLink struct {
+	Name    string `json:"name"`
+	Size    int64  `json:"size,omitempty"`
+	SHA256  string `json:"sha256,omitempty"`
+	URL     string `json:"url"`
+	Expires int64  `json:"expires"`
+}
+
+type hintItem struct {
+	ID     uint `json:"id"`
+	Points int  `json:"points"`
+}
+
+type hintDetail struct {
+	ID          uint   `json:"id"`
+	ChallengeID uint   `json:"challenge_id"`
+	Points      int    `json:"points"`
+	Context     string `json:"context"`
 }
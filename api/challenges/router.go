@@ -7,11 +7,16 @@ import (
 )
 
 func LoadChallenges(r *gin.RouterGroup) {
-	challengeRouter := r.Group("/challenge", middleware.BanMiddleware)
-	challengeRouter.GET("/list", middleware.CacheMiddleware, handlers.GetChallengeList) // TODO: gotta support chained challenges
+	challengeRouter := r.Group("/challenge", middleware.BanMiddleware, middleware.EventStartedMiddleware)
+
+	// download links are signed, VerifyDownload stands in for the bearer token
+	fileRouter := r.Group("/challenge", middleware.EventStartedMiddleware)
+	fileRouter.GET("/:id/files/:file_id", handlers.VerifyDownload, middleware.BanMiddleware, handlers.DownloadAttachment)
 
 	// Protected routes
 	protectedRouter := challengeRouter.Group("/", middleware.AuthRequired)
+	// the list depends on the team's unlocked challenges, so it is neither public nor cached
+	protectedRouter.GET("/list", handlers.GetChallengeList)
 	protectedRouter.GET("/:id", middleware.CacheMiddleware, handlers.GetChallengeDetail)
 	protectedRouter.GET("/:id/config", handlers.GetChallengeConfig)
 	protectedRouter.POST("/:id/submit", handlers.SubmitFlag)
@@ -21,7 +26,7 @@ func LoadChallenges(r *gin.RouterGroup) {
 	protectedRouter.POST("/:id/extend", handlers.ExtendDynamicChallenge)
 	protectedRouter.POST("/:id/regenerate", handlers.RegenerateDynamicChallenge)
 
-	// Hint routes (all protected) TODO: have to implement this
+	// Hint routes (all protected)
 	hintRouter := protectedRouter.Group("/:id/hint", middleware.AuthRequired)
 	hintRouter.GET("/list", middleware.CacheMiddleware, handlers.ListHints)
 	hintRouter.GET("/:hint_id", handlers.GetHint)
@@ -0,0 +1,135 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/intraware/rodan/api/shared"
+	eventbus "github.com/intraware/rodan/internal/events"
+	"github.com/intraware/rodan/internal/models"
+	"github.com/intraware/rodan/internal/types"
+	"github.com/intraware/rodan/internal/utils"
+	"github.com/intraware/rodan/internal/utils/values"
+	"github.com/sirupsen/logrus"
+)
+
+const defaultKeepAlive = 15 * time.Second
+
+// streamEvents godoc
+// @Summary      Stream live events
+// @Description  Server-Sent Events stream of solves, bloods, releases, announcements and sandbox expiry warnings. Send Last-Event-ID to resume.
+// @Security     BearerAuth
+// @Tags         events
+// @Produce      text/event-stream
+// @Param        channel        query   string  false  "all (default), global or team"
+// @Param        ticket         query   string  false  "Ticket from /events/ticket, instead of the Authorization header"
+// @Param        Last-Event-ID  header  string  false  "Resume after this event ID"
+// @Success      200  {object}  eventbus.Event
+// @Failure      400  {object}  types.ErrorResponse
+// @Failure      401  {object}  types.ErrorResponse
+// @Failure      500  {object}  types.ErrorResponse
+// @Router       /events [get]
+func streamEvents(ctx *gin.Context) {
+	auditLog := utils.Logger.WithField("type", "audit")
+	userID := ctx.GetUint("user_id")
+	user, userCacheHit := shared.UserCache.Get(userID)
+	if !userCacheHit {
+		if err := models.DB.First(&user, userID).Error; err != nil {
+			auditLog.WithFields(logrus.Fields{
+				"event":   "stream_events",
+				"status":  "failure",
+				"reason":  "db_error_user_lookup",
+				"user_id": userID,
+				"ip":      ctx.ClientIP(),
+				"error":   err.Error(),
+			}).Error("Failed to fetch user from DB")
+			ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Database Error"})
+			return
+		}
+		shared.UserCache.Set(userID, user)
+	}
+	filter := eventbus.Filter{TeamID: user.TeamID}
+	switch channel := ctx.DefaultQuery("channel", "all"); channel {
+	case "all":
+		filter.Scope = eventbus.ScopeAll
+	case "global":
+		filter.Scope = eventbus.ScopeGlobal
+	case "team":
+		if user.TeamID == nil {
+			ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "User is not in a team"})
+			return
+		}
+		filter.Scope = eventbus.ScopeTeam
+	default:
+		ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid channel"})
+		return
+	}
+	lastID := ctx.GetHeader("Last-Event-ID")
+	if lastID == "" {
+		lastID = ctx.Query("last_event_id")
+	}
+	resumeFrom, _ := strconv.ParseUint(lastID, 10, 64)
+	sub, backlog := eventbus.Subscribe(filter, resumeFrom)
+	defer sub.Close()
+	auditLog.WithFields(logrus.Fields{
+		"event":       "stream_events",
+		"status":      "success",
+		"user_id":     user.ID,
+		"channel":     ctx.DefaultQuery("channel", "all"),
+		"resume_from": resumeFrom,
+		"backlog":     len(backlog),
+		"ip":          ctx.ClientIP(),
+	}).Info("Event stream opened")
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+	for _, evt := range backlog {
+		if err := writeEvent(ctx, evt); err != nil {
+			return
+		}
+	}
+	ctx.Writer.Flush()
+
+	keepAlive := values.GetConfig().App.Stream.KeepAlive
+	if keepAlive <= 0 {
+		keepAlive = defaultKeepAlive
+	}
+	ticker := time.NewTicker(keepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Request.Context().Done():
+			return
+		case evt, ok := <-sub.C:
+			if !ok {
+				// dropped for falling behind, the client reconnects with Last-Event-ID
+				return
+			}
+			if err := writeEvent(ctx, evt); err != nil {
+				return
+			}
+			ctx.Writer.Flush()
+		case <-ticker.C:
+			if _, err := fmt.Fprint(ctx.Writer, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			ctx.Writer.Flush()
+		}
+	}
+}
+
+func writeEvent(ctx *gin.Context, evt eventbus.Event) error {
+	payload, err := json.Marshal(evt)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(ctx.Writer, "id: %d\nevent: %s\ndata: %s\n\n", evt.ID, evt.Type, payload)
+	return err
+}
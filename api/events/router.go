@@ -0,0 +1,11 @@
+package events
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/intraware/rodan/internal/utils/middleware"
+)
+
+func LoadEvents(r *gin.RouterGroup) {
+	r.GET("/events", streamAuth, streamEvents)
+	r.POST("/events/ticket", middleware.AuthRequired, issueTicket)
+}
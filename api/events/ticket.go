@@ -0,0 +1,79 @@
+package events
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/intraware/rodan/internal/types"
+	"github.com/intraware/rodan/internal/utils"
+	"github.com/intraware/rodan/internal/utils/middleware"
+	"github.com/intraware/rodan/internal/utils/values"
+)
+
+// browsers cannot set headers on an EventSource, so the stream also takes a ticket in
+// the query string. Tickets only open the stream and expire quickly, the session token
+// itself never ends up in a URL.
+const (
+	ticketAudience = "rodan-events"
+	ticketTTL      = time.Minute
+)
+
+type ticketResponse struct {
+	Ticket    string `json:"ticket"`
+	ExpiresIn int64  `json:"expires_in"`
+}
+
+// issueTicket godoc
+// @Summary      Get an event stream ticket
+// @Description  Returns a short-lived ticket for /events?ticket=..., for clients like EventSource that cannot send an Authorization header
+// @Security     BearerAuth
+// @Tags         events
+// @Produce      json
+// @Success      200  {object}  ticketResponse
+// @Failure      500  {object}  types.ErrorResponse
+// @Router       /events/ticket [post]
+func issueTicket(ctx *gin.Context) {
+	now := time.Now()
+	claims := &utils.Claims{
+		UserID:   ctx.GetUint("user_id"),
+		Username: ctx.GetString("username"),
+		TeamID:   ctx.GetUint("team_id"),
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{ticketAudience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(ticketTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			Issuer:    "rodan",
+		},
+	}
+	ticket, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(values.GetConfig().Server.Security.JWTSecret))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to issue ticket"})
+		return
+	}
+	ctx.JSON(http.StatusOK, ticketResponse{Ticket: ticket, ExpiresIn: int64(ticketTTL.Seconds())})
+}
+
+// streamAuth accepts a ticket from the query string and otherwise falls back to the
+// usual bearer token
+func streamAuth(ctx *gin.Context) {
+	ticket := ctx.Query("ticket")
+	if ticket == "" {
+		middleware.AuthRequired(ctx)
+		return
+	}
+	claims := &utils.Claims{}
+	token, err := jwt.ParseWithClaims(ticket, claims, func(*jwt.Token) (any, error) {
+		return []byte(values.GetConfig().Server.Security.JWTSecret), nil
+	}, jwt.WithAudience(ticketAudience), jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil || !token.Valid {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid ticket"})
+		ctx.Abort()
+		return
+	}
+	ctx.Set("user_id", claims.UserID)
+	ctx.Set("username", claims.Username)
+	ctx.Set("team_id", claims.TeamID)
+	ctx.Next()
+}
@@ -2,7 +2,6 @@ package leaderboard
 
 import (
 	"log"
-	"math"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -11,6 +10,7 @@ import (
 
 	"github.com/intraware/rodan/api/shared"
 	"github.com/intraware/rodan/internal/models"
+	"github.com/intraware/rodan/internal/scoring"
 	"github.com/intraware/rodan/internal/utils/values"
 )
 
@@ -30,6 +30,11 @@ var (
 	userLeaderboardCache atomic.Pointer[[]UserPoints]
 	teamLeaderboardCache atomic.Pointer[[]TeamPoints]
 
+	// standings as of the configured freeze time, served publicly once it passes
+	frozenUserLeaderboardCache atomic.Pointer[[]UserPoints]
+	frozenTeamLeaderboardCache atomic.Pointer[[]TeamPoints]
+	frozenCutoff               atomic.Value
+
 	cacheDirtyFlag   atomic.Bool
 	dirtyTriggerLock sync.Mutex
 	dirtyTimer       *time.Timer
@@ -47,28 +52,9 @@ func MarkLeaderboardDirty() {
 	})
 }
 
-func smoothScore(solves int, maxPoints int, minPoints int, total int, offset int, power float64) int {
-	if solves <= offset {
-		return maxPoints
-	}
-	if solves > total {
-		solves = total
-	}
-	x := float64(solves-offset) / float64(total-offset)
-	score := float64(maxPoints) - float64(maxPoints-minPoints)*math.Pow(x, power)
-	score = max(score, float64(minPoints))
-	return int(math.Round(score))
-}
-
-func timeAdjustedScoreWithCreatedAt(
-	solveTime time.Time,
-	allSolveTimes []time.Time,
-	minPoints, maxPoints, offset int,
-	power float64,
-) float64 {
+// timeAdjustedScore adds a tiny bonus to earlier solves so ties are broken by solve time
+func timeAdjustedScore(solveTime time.Time, allSolveTimes []time.Time, base int) float64 {
 	totalSolves := len(allSolveTimes)
-	solvesSoFar := totalSolves
-	base := float64(smoothScore(solvesSoFar, maxPoints, minPoints, totalSolves, offset, power))
 	sort.Slice(allSolveTimes, func(i, j int) bool {
 		return allSolveTimes[i].Before(allSolveTimes[j])
 	})
@@ -81,10 +67,38 @@ func timeAdjustedScoreWithCreatedAt(
 	}
 	bonus := float64(totalSolves-rank) / float64(totalSolves+1)
 	bonus *= 1e-9
-	return base + bonus
+	return float64(base) + bonus
 }
 
 func updateLeaderboards() {
+	userLeaderboard, teamLeaderboard, err := buildLeaderboards(time.Time{})
+	if err != nil {
+		log.Println("[leaderboard] DB error:", err)
+		return
+	}
+	userLeaderboardCache.Store(&userLeaderboard)
+	teamLeaderboardCache.Store(&teamLeaderboard)
+	freeze := values.GetConfig().App.Event.Freeze
+	if freeze.IsZero() {
+		frozenUserLeaderboardCache.Store(nil)
+		frozenTeamLeaderboardCache.Store(nil)
+	} else {
+		frozenUsers, frozenTeams, err := buildLeaderboards(freeze)
+		if err != nil {
+			log.Println("[leaderboard] DB error:", err)
+			return
+		}
+		frozenUserLeaderboardCache.Store(&frozenUsers)
+		frozenTeamLeaderboardCache.Store(&frozenTeams)
+	}
+	frozenCutoff.Store(freeze)
+	LastModified.Store(time.Now().UTC())
+	log.Println("[leaderboard] cache updated")
+}
+
+// buildLeaderboards computes standings from solves and hint purchases made before cutoff.
+// A zero cutoff includes everything.
+func buildLeaderboards(cutoff time.Time) ([]UserPoints, []TeamPoints, error) {
 	var solves []models.Solve
 	userBlackList := shared.UserBlackList
 	teamBlackList := shared.TeamBlackList
@@ -95,10 +109,12 @@ func updateLeaderboards() {
 	if len(teamBlackList) > 0 {
 		query = query.Where("team_id NOT IN (?)", teamBlackList)
 	}
+	if !cutoff.IsZero() {
+		query = query.Where("created_at < ?", cutoff)
+	}
 	err := query.Where("blacklist != TRUE").Order("challenge_id, created_at").Find(&solves).Error
 	if err != nil {
-		log.Println("[leaderboard] DB error:", err)
-		return
+		return nil, nil, err
 	}
 	challengeSolves := make(map[uint][]models.Solve)
 	solveTimes := make(map[uint][]time.Time)
@@ -127,24 +143,35 @@ func updateLeaderboards() {
 			}
 		}
 	}
-	offset := values.GetConfig().App.Leaderboard.FullPointsThreshold
-	power := values.GetConfig().App.Leaderboard.DecaySharpness
+	players := scoring.PlayerCount()
 	for cid, solveList := range challengeSolves {
 		times := solveTimes[cid]
 		challenge := challengeToMeta[cid]
+		base := scoring.ChallengePoints(challenge, len(times), players)
 		for _, s := range solveList {
-			points := timeAdjustedScoreWithCreatedAt(
-				s.CreatedAt,
-				times,
-				challenge.PointsMin,
-				challenge.PointsMax,
-				offset,
-				power,
-			)
+			points := timeAdjustedScore(s.CreatedAt, times, base)
 			userScores[s.UserID] += points
 			userToTeam[s.UserID] = s.TeamID
 		}
 	}
+	var purchases []models.HintPurchase
+	hintQuery := models.DB
+	if len(userBlackList) > 0 {
+		hintQuery = hintQuery.Where("user_id NOT IN (?)", userBlackList)
+	}
+	if len(teamBlackList) > 0 {
+		hintQuery = hintQuery.Where("team_id NOT IN (?)", teamBlackList)
+	}
+	if !cutoff.IsZero() {
+		hintQuery = hintQuery.Where("created_at < ?", cutoff)
+	}
+	if err := hintQuery.Find(&purchases).Error; err != nil {
+		return nil, nil, err
+	}
+	for _, p := range purchases {
+		userScores[p.UserID] -= float64(p.Points)
+		userToTeam[p.UserID] = p.TeamID
+	}
 	userToName := make(map[uint]string)
 	var missingIDs []uint
 	for uid := range userScores {
@@ -183,7 +210,6 @@ func updateLeaderboards() {
 	sort.Slice(userLeaderboard, func(i, j int) bool {
 		return userLeaderboard[i].Points > userLeaderboard[j].Points
 	})
-	userLeaderboardCache.Store(&userLeaderboard)
 	teamScores := make(map[uint]float64)
 	teamIDSet := make(map[uint]struct{})
 	for uid, pts := range userScores {
@@ -221,12 +247,16 @@ func updateLeaderboards() {
 	sort.Slice(teamLeaderboard, func(i, j int) bool {
 		return teamLeaderboard[i].Points > teamLeaderboard[j].Points
 	})
-	teamLeaderboardCache.Store(&teamLeaderboard)
-	LastModified.Store(time.Now().UTC())
-	log.Println("[leaderboard] cache updated")
+	return userLeaderboard, teamLeaderboard, nil
 }
 
 func maybeRefreshLeaderboard() {
+	// a reloaded freeze time invalidates the frozen standings
+	freeze := values.GetConfig().App.Event.Freeze
+	if cutoff, ok := frozenCutoff.Load().(time.Time); ok && !cutoff.Equal(freeze) {
+		frozenCutoff.Store(freeze)
+		cacheDirtyFlag.Store(true)
+	}
 	if cacheDirtyFlag.Load() {
 		if cacheDirtyFlag.Swap(false) {
 			go updateLeaderboards()
@@ -234,16 +264,44 @@ func maybeRefreshLeaderboard() {
 	}
 }
 
+// GetCachedUserLeaderboard returns the public standings, which stop moving once the scoreboard is frozen
 func GetCachedUserLeaderboard() []UserPoints {
 	maybeRefreshLeaderboard()
 	ptr := userLeaderboardCache.Load()
+	if shared.ScoreboardFrozen() {
+		ptr = frozenUserLeaderboardCache.Load()
+	}
 	if ptr == nil {
 		return nil
 	}
 	return *ptr
 }
 
+// GetCachedTeamLeaderboard returns the public standings, which stop moving once the scoreboard is frozen
 func GetCachedTeamLeaderboard() []TeamPoints {
+	maybeRefreshLeaderboard()
+	ptr := teamLeaderboardCache.Load()
+	if shared.ScoreboardFrozen() {
+		ptr = frozenTeamLeaderboardCache.Load()
+	}
+	if ptr == nil {
+		return nil
+	}
+	return *ptr
+}
+
+// GetLiveUserLeaderboard ignores the freeze and is meant for admins only
+func GetLiveUserLeaderboard() []UserPoints {
+	maybeRefreshLeaderboard()
+	ptr := userLeaderboardCache.Load()
+	if ptr == nil {
+		return nil
+	}
+	return *ptr
+}
+
+// GetLiveTeamLeaderboard ignores the freeze and is meant for admins only
+func GetLiveTeamLeaderboard() []TeamPoints {
 	maybeRefreshLeaderboard()
 	ptr := teamLeaderboardCache.Load()
 	if ptr == nil {
@@ -8,6 +8,8 @@ import (
 func LoadLeaderboard(r *gin.RouterGroup) {
 	lbRouter := r.Group("/leaderboard")
 	lbRouter.Use(LastModifiedMiddleware)
+	// build the standings on first request instead of waiting for the next solve
+	cacheDirtyFlag.Store(true)
 	lbConfig := values.GetConfig().App.Leaderboard
 	if lbConfig.User {
 		lbRouter.GET("/user", getUserLeaderboard)
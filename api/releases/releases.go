@@ -0,0 +1,143 @@
+package releases
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/intraware/rodan/api/shared"
+	"github.com/intraware/rodan/internal/events"
+	"github.com/intraware/rodan/internal/models"
+	"github.com/intraware/rodan/internal/notification"
+	"github.com/intraware/rodan/internal/sandbox"
+	"github.com/intraware/rodan/internal/utils"
+	"github.com/intraware/rodan/internal/utils/values"
+	"github.com/sirupsen/logrus"
+)
+
+const defaultInterval = 15 * time.Second
+
+var startOnce sync.Once
+
+// Announce tells players a challenge is out. Nothing is sent before the event starts,
+// everything released by then shows up together.
+func Announce(challenge models.Challenge) {
+	if !shared.EventStarted() {
+		return
+	}
+	events.Publish(events.Global(events.ChallengeReleased, events.ChallengeData{
+		ChallengeID: challenge.ID,
+		Name:        challenge.Name,
+	}))
+	// the http delivery retries in line, a slow webhook must not hold up the scheduler
+	// or the admin request that released the challenge
+	go notification.Send(notification.Payload{
+		Type:    string(events.ChallengeReleased),
+		Message: fmt.Sprintf("New challenge released: %s", challenge.Name),
+		Data: events.ChallengeData{
+			ChallengeID: challenge.ID,
+			Name:        challenge.Name,
+		},
+	})
+}
+
+// Start runs the release scheduler in the background, once
+func Start() {
+	startOnce.Do(func() {
+		go func() {
+			for {
+				Tick(time.Now())
+				interval := values.GetConfig().App.ReleaseInterval
+				if interval <= 0 {
+					interval = defaultInterval
+				}
+				time.Sleep(interval)
+			}
+		}()
+	})
+}
+
+// Tick releases and hides every challenge that is due. The schedule is cleared as it
+// is applied, so a challenge hidden by hand afterwards stays hidden, and the update is
+// conditional so only one instance announces a release.
+func Tick(now time.Time) {
+	log := utils.Logger.WithField("type", "audit")
+	changed := false
+	var due []models.Challenge
+	if err := models.DB.Where("release_at IS NOT NULL AND release_at <= ?", now).Find(&due).Error; err != nil {
+		log.WithFields(logrus.Fields{
+			"event":  "challenge_release",
+			"status": "failure",
+			"reason": "db_error",
+			"error":  err.Error(),
+		}).Error("Failed to load due challenge releases")
+		return
+	}
+	for _, challenge := range due {
+		res := models.DB.Model(&models.Challenge{}).
+			Where("id = ? AND release_at IS NOT NULL", challenge.ID).
+			Updates(map[string]any{"is_visible": true, "release_at": nil})
+		if res.Error != nil {
+			log.WithFields(logrus.Fields{
+				"event":        "challenge_release",
+				"status":       "failure",
+				"reason":       "db_error",
+				"challenge_id": challenge.ID,
+				"error":        res.Error.Error(),
+			}).Error("Failed to release challenge")
+			continue
+		}
+		if res.RowsAffected == 0 {
+			continue
+		}
+		changed = true
+		shared.ChallengeCache.Delete(challenge.ID)
+		log.WithFields(logrus.Fields{
+			"event":        "challenge_release",
+			"status":       "success",
+			"challenge_id": challenge.ID,
+		}).Info("Scheduled challenge released")
+		if !challenge.IsVisible {
+			Announce(challenge)
+		}
+	}
+	var expired []models.Challenge
+	if err := models.DB.Where("hide_at IS NOT NULL AND hide_at <= ?", now).Find(&expired).Error; err != nil {
+		log.WithFields(logrus.Fields{
+			"event":  "challenge_hide",
+			"status": "failure",
+			"reason": "db_error",
+			"error":  err.Error(),
+		}).Error("Failed to load due challenge hides")
+		return
+	}
+	for _, challenge := range expired {
+		res := models.DB.Model(&models.Challenge{}).
+			Where("id = ? AND hide_at IS NOT NULL", challenge.ID).
+			Updates(map[string]any{"is_visible": false, "hide_at": nil})
+		if res.Error != nil {
+			log.WithFields(logrus.Fields{
+				"event":        "challenge_hide",
+				"status":       "failure",
+				"reason":       "db_error",
+				"challenge_id": challenge.ID,
+				"error":        res.Error.Error(),
+			}).Error("Failed to hide challenge")
+			continue
+		}
+		if res.RowsAffected == 0 {
+			continue
+		}
+		changed = true
+		shared.ChallengeCache.Delete(challenge.ID)
+		log.WithFields(logrus.Fields{
+			"event":        "challenge_hide",
+			"status":       "success",
+			"challenge_id": challenge.ID,
+		}).Info("Scheduled challenge hidden")
+	}
+	if changed {
+		shared.ChallengeListCache.Reset()
+		sandbox.RefreshWarmPool()
+	}
+}
@@ -1,13 +1,39 @@
 package shared
 
 import (
+	"time"
+
 	"github.com/intraware/rodan/internal/cache"
 	"github.com/intraware/rodan/internal/models"
+	"github.com/intraware/rodan/internal/ratelimit"
 )
 
 var UserCache cache.Cache[uint, models.User]
 var TeamCache cache.Cache[uint, models.Team]
 var ChallengeCache cache.Cache[uint, models.Challenge]
 var TeamSolvedCache cache.Cache[string, bool]
+var TeamHintCache cache.Cache[string, bool]
 var StaticConfig cache.Cache[uint, models.StaticConfig]
 var BanHistoryCache cache.Cache[string, models.BanHistory]
+var PrerequisiteCache cache.Cache[uint, []models.ChallengePrerequisite]
+var AttachmentCache cache.Cache[uint, []models.Attachment]
+var ChallengeListCache cache.Cache[uint, []ChallengeListItem]
+var CategoryCache cache.Cache[uint, models.Category]
+var DifficultyCache cache.Cache[uint, models.Difficulty]
+var RateLimitCache cache.Cache[string, ratelimit.Bucket]
+var RateLimitViolations cache.Cache[string, int]
+var ViolationWindow time.Duration // how long RateLimitViolations counts
+
+var SubmitLimiter *ratelimit.Limiter
+
+// ChallengeListItem is one challenge on a team's board, cached per team
+type ChallengeListItem struct {
+	ID         uint     `json:"id"`
+	Title      string   `json:"title"`
+	Category   *Label   `json:"category,omitempty"`
+	Difficulty *Label   `json:"difficulty,omitempty"`
+	Points     int      `json:"points"`
+	Solves     int      `json:"solves"`
+	Tags       []string `json:"tags"`
+	Solved     bool     `json:"solved"`
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/intraware/rodan/internal/cache"
 	"github.com/intraware/rodan/internal/config"
 	"github.com/intraware/rodan/internal/models"
+	"github.com/intraware/rodan/internal/ratelimit"
 )
 
 func ptr[T any](v T) *T { return &v }
@@ -38,6 +39,12 @@ func Init(config *config.Config) {
 		Revaluate:     ptr(false),
 		Prefix:        "team-solved-cache",
 	})
+	TeamHintCache = cache.NewCache[string, bool](&cache.CacheOpts{
+		TimeToLive:    0,
+		CleanInterval: ptr(time.Hour * 2),
+		Revaluate:     ptr(false),
+		Prefix:        "team-hint-cache",
+	})
 	StaticConfig = cache.NewCache[uint, models.StaticConfig](&cache.CacheOpts{
 		TimeToLive:    3 * time.Minute,
 		CleanInterval: ptr(time.Hour * 2),
@@ -50,6 +57,54 @@ func Init(config *config.Config) {
 		Revaluate:     ptr(true),
 		Prefix:        "ban-history-cache",
 	})
+	PrerequisiteCache = cache.NewCache[uint, []models.ChallengePrerequisite](&cache.CacheOpts{
+		TimeToLive:    3 * time.Minute,
+		CleanInterval: ptr(time.Hour * 2),
+		Revaluate:     ptr(true),
+		Prefix:        "prerequisite-cache",
+	})
+	AttachmentCache = cache.NewCache[uint, []models.Attachment](&cache.CacheOpts{
+		TimeToLive:    10 * time.Minute,
+		CleanInterval: ptr(time.Hour * 2),
+		Revaluate:     ptr(true),
+		Prefix:        "attachment-cache",
+	})
+	// solve counts and points move with every solve, so team boards are only kept briefly
+	ChallengeListCache = cache.NewCache[uint, []ChallengeListItem](&cache.CacheOpts{
+		TimeToLive:    15 * time.Second,
+		CleanInterval: ptr(time.Hour * 2),
+		Revaluate:     ptr(false),
+		Prefix:        "challenge-list-cache",
+	})
+	CategoryCache = cache.NewCache[uint, models.Category](&cache.CacheOpts{
+		TimeToLive:    10 * time.Minute,
+		CleanInterval: ptr(time.Hour * 2),
+		Revaluate:     ptr(true),
+		Prefix:        "category-cache",
+	})
+	DifficultyCache = cache.NewCache[uint, models.Difficulty](&cache.CacheOpts{
+		TimeToLive:    10 * time.Minute,
+		CleanInterval: ptr(time.Hour * 2),
+		Revaluate:     ptr(true),
+		Prefix:        "difficulty-cache",
+	})
+	RateLimitCache = cache.NewCache[string, ratelimit.Bucket](&cache.CacheOpts{
+		TimeToLive:    time.Hour,
+		CleanInterval: ptr(time.Hour * 2),
+		Revaluate:     ptr(false),
+		Prefix:        "rate-limit-cache",
+	})
+	ViolationWindow = config.App.RateLimit.ViolationWindow
+	if ViolationWindow <= 0 {
+		ViolationWindow = 10 * time.Minute
+	}
+	RateLimitViolations = cache.NewCache[string, int](&cache.CacheOpts{
+		TimeToLive:    ViolationWindow,
+		CleanInterval: ptr(time.Hour * 2),
+		Revaluate:     ptr(false),
+		Prefix:        "rate-limit-violations",
+	})
+	SubmitLimiter = ratelimit.New(RateLimitCache)
 }
 
 func init() {
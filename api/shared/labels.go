@@ -0,0 +1,45 @@
+package shared
+
+import "github.com/intraware/rodan/internal/models"
+
+// Label is a resolved category or difficulty as it is embedded in challenge responses
+type Label struct {
+	ID    uint   `json:"id"`
+	Name  string `json:"name"`
+	Color string `json:"color,omitempty"`
+	Order int    `json:"order"`
+}
+
+func toLabel(l models.Label) *Label {
+	return &Label{ID: l.ID, Name: l.Name, Color: l.Color, Order: l.SortOrder}
+}
+
+// CategoryLabel resolves a challenge's category, nil when it has none or it is gone
+func CategoryLabel(id *uint) *Label {
+	if id == nil {
+		return nil
+	}
+	category, ok := CategoryCache.Get(*id)
+	if !ok {
+		if err := models.DB.First(&category, *id).Error; err != nil {
+			return nil
+		}
+		CategoryCache.Set(*id, category)
+	}
+	return toLabel(category.Label)
+}
+
+// DifficultyLabel resolves a challenge's difficulty, nil when it has none or it is gone
+func DifficultyLabel(id *uint) *Label {
+	if id == nil {
+		return nil
+	}
+	difficulty, ok := DifficultyCache.Get(*id)
+	if !ok {
+		if err := models.DB.First(&difficulty, *id).Error; err != nil {
+			return nil
+		}
+		DifficultyCache.Set(*id, difficulty)
+	}
+	return toLabel(difficulty.Label)
+}
@@ -1,13 +1,26 @@
 package shared
 
 import (
+	"context"
+	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/intraware/rodan/internal/sandbox"
 	"github.com/intraware/rodan/internal/utils/maps"
+	"github.com/intraware/rodan/internal/utils/values"
 )
 
-var SandBoxMap = maps.NewVMap[uint, []*sandbox.SandBox]()
+type SandBoxKey struct {
+	TeamID      uint
+	ChallengeID uint
+}
+
+var SandBoxMap = maps.NewVMap[SandBoxKey, *sandbox.SandBox]()
+
+// FlagOwnerIndex maps dynamic flags back to their team per challenge ID, it is filled
+// by the flag submission handler. Deleting a challenge's entry rebuilds it on next use.
+var FlagOwnerIndex sync.Map
 
 var UserBlackList []uint
 var TeamBlackList []uint
@@ -21,3 +34,55 @@ func SetSubmissions(allowSub bool) {
 func GetSubmissions() bool {
 	return allowSubmissions.Load()
 }
+
+// EventStarted reports whether the configured event window has opened
+func EventStarted() bool {
+	return values.GetConfig().App.Event.Started(time.Now())
+}
+
+// ScoreboardFrozen reports whether public standings should stop updating
+func ScoreboardFrozen() bool {
+	return values.GetConfig().App.Event.Frozen(time.Now())
+}
+
+// SubmissionWindow combines the admin toggle with the event window. When closed it
+// returns the audit reason and the message to show the player.
+func SubmissionWindow() (reason, msg string, open bool) {
+	event := values.GetConfig().App.Event
+	now := time.Now()
+	switch {
+	case !allowSubmissions.Load():
+		return "submission_closed", "Submissions are closed", false
+	case !event.Started(now):
+		return "event_not_started", "Event has not started yet", false
+	case event.Ended(now):
+		return "event_ended", "Event has ended", false
+	}
+	return "", "", true
+}
+
+func GetTeamSandBoxes(teamID uint) (boxes []*sandbox.SandBox) {
+	for _, box := range SandBoxMap.DumpValues() {
+		if box.TeamID == teamID {
+			boxes = append(boxes, box)
+		}
+	}
+	return
+}
+
+func CountActiveSandBoxes(teamID uint) (count int) {
+	for _, box := range GetTeamSandBoxes(teamID) {
+		if box.Active {
+			count++
+		}
+	}
+	return
+}
+
+func RecoverSandBoxes(ctx context.Context) (int, error) {
+	boxes, err := sandbox.Init(ctx)
+	for _, box := range boxes {
+		SandBoxMap.Set(SandBoxKey{TeamID: box.TeamID, ChallengeID: box.ChallengeMeta.ID}, box)
+	}
+	return len(boxes), err
+}
@@ -2,20 +2,33 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/intraware/rodan/api"
+	"github.com/intraware/rodan/api/shared"
 	"github.com/intraware/rodan/internal/cache"
+	"github.com/intraware/rodan/internal/events"
+	"github.com/intraware/rodan/internal/flags"
 	"github.com/intraware/rodan/internal/models"
+	"github.com/intraware/rodan/internal/notification"
+	"github.com/intraware/rodan/internal/proxy"
+	"github.com/intraware/rodan/internal/sandbox"
 	"github.com/intraware/rodan/internal/utils"
 	"github.com/intraware/rodan/internal/utils/docker"
 	"github.com/intraware/rodan/internal/utils/middleware"
 	"github.com/intraware/rodan/internal/utils/values"
 )
 
+const shutdownTimeout = 10 * time.Second
+
 func Run() {
 	configFile := os.Getenv("CONFIG_FILE")
 	if err := values.InitWithViper(configFile); err != nil {
@@ -24,10 +37,41 @@ func Run() {
 	cfg := values.GetConfig()
 	ctx := context.Background()
 	models.InitDB(cfg)
+	if migrated, err := flags.MigratePlaintext(models.DB, cfg.Server.Security.FlagSecret); err != nil {
+		log.Fatalf("Failed to hash static flags: %v", err)
+	} else if migrated > 0 {
+		fmt.Printf("[ENGINE] Hashed %d plaintext static flags\n", migrated)
+	}
 	utils.NewLogger(cfg.Server.Production)
+	events.Init(cfg.App.Stream.HistorySize)
+	// port leases live in redis too, so it has to be up before any container is touched
+	if !cfg.App.AppCache.InApp {
+		cache.InitRedis(ctx)
+	}
 	if err := docker.SetupDockerClient(); err != nil {
 		log.Fatalf("Failed to setup Docker client: %v", err)
 	}
+	if removed, err := sandbox.RemoveWarmLeftovers(ctx); err != nil {
+		log.Printf("Failed to remove leftover warm containers: %v", err)
+	} else if removed > 0 {
+		fmt.Printf("[ENGINE] Removed %d warm containers left from the last run\n", removed)
+	}
+	if reserved, err := docker.ReconcilePorts(ctx); err != nil {
+		log.Printf("Failed to reconcile host ports: %v", err)
+	} else {
+		fmt.Printf("[ENGINE] Reserved %d host ports of existing containers\n", reserved)
+	}
+	if cfg.Docker.Proxy.Enabled {
+		if err := proxy.Start(cfg.Docker.Proxy); err != nil {
+			log.Fatalf("Failed to start sandbox proxy: %v", err)
+		}
+		fmt.Printf("[ENGINE] Sandbox proxy serving *.%s\n", cfg.Docker.Proxy.Domain)
+	}
+	if recovered, err := shared.RecoverSandBoxes(ctx); err != nil {
+		log.Printf("Failed to recover sandboxes: %v", err)
+	} else {
+		fmt.Printf("[ENGINE] Recovered %d running sandboxes\n", recovered)
+	}
 	if cfg.Server.Production {
 		gin.SetMode(gin.ReleaseMode)
 	} else {
@@ -38,9 +82,27 @@ func Run() {
 	r.Use(middleware.CORS(cfg.Server))
 	r.Use(gin.Recovery())
 	api.LoadRoutes(r)
-	if !cfg.App.AppCache.InApp {
-		cache.InitRedis(ctx)
+	srv := &http.Server{
+		Addr:    fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port),
+		Handler: r,
 	}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Failed to start server: %v", err)
+		}
+	}()
 	fmt.Printf("[ENGINE] Server started at %s:%d\n", cfg.Server.Host, cfg.Server.Port)
-	r.Run(fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port))
+	stop, cancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	<-stop.Done()
+	fmt.Println("[ENGINE] Shutting down")
+	shutdownCtx, cancelShutdown := context.WithTimeout(ctx, shutdownTimeout)
+	defer cancelShutdown()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Failed to shut down the server: %v", err)
+	}
+	// queued and batched notifications are sent or written to the retry buffer
+	if err := notification.Close(); err != nil {
+		log.Printf("Failed to close notifications: %v", err)
+	}
 }
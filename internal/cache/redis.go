@@ -2,7 +2,9 @@ package cache
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/intraware/rodan/internal/utils/values"
 	redis_cache "github.com/intraware/rodan/pkg/cache"
@@ -18,6 +20,7 @@ type redisCache[K comparable, V any] struct {
 
 type RedisClient struct {
 	redis *redis_cache.Cache
+	ring  *redis.Ring
 	ctx   context.Context
 }
 
@@ -40,6 +43,7 @@ func InitRedis(ctx context.Context) {
 	})
 	redisTemp := RedisClient{
 		redis: redisCache,
+		ring:  ring,
 		ctx:   ctx,
 	}
 	redisObj = redisTemp
@@ -97,3 +101,65 @@ func (r *redisCache[K, V]) Reset() { // TODO: change the function to handle the
 		r.client.redis.DeletePrefix(r.client.ctx, prefixString)
 	}
 }
+
+var errRedisNotReady = errors.New("redis is not initialised")
+
+// Claim sets key only when nobody holds it yet and reports whether this call did.
+// Instances sharing the redis use it to agree on who owns something.
+func Claim(key, owner string, ttl time.Duration) (bool, error) {
+	if redisObj.ring == nil {
+		return false, errRedisNotReady
+	}
+	return redisObj.ring.SetNX(redisObj.ctx, key, owner, ttl).Result()
+}
+
+// refreshScript renews a claim only while owner still holds it
+var refreshScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+
+// unclaimScript deletes a claim only while owner still holds it
+var unclaimScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// Refresh renews owner's claim on key, reporting false when the claim ran out or
+// another owner holds it
+func Refresh(key, owner string, ttl time.Duration) (bool, error) {
+	if redisObj.ring == nil {
+		return false, errRedisNotReady
+	}
+	return refreshScript.Run(redisObj.ctx, redisObj.ring, []string{key}, owner, ttl.Milliseconds()).Bool()
+}
+
+// Unclaim gives up owner's claim on key, a claim that meanwhile went to someone else
+// is left alone
+func Unclaim(key, owner string) error {
+	if redisObj.ring == nil {
+		return errRedisNotReady
+	}
+	return unclaimScript.Run(redisObj.ctx, redisObj.ring, []string{key}, owner).Err()
+}
+
+// Eval runs script on the given keys of c as one step, so instances sharing the redis
+// cannot interleave. ok is false when c is not kept in redis, the caller then has to
+// do the same in process.
+func Eval[K comparable, V any](c Cache[K, V], script *redis.Script, keys []K, args ...any) (result any, ok bool, err error) {
+	r, isRedis := c.(*redisCache[K, V])
+	if !isRedis {
+		return nil, false, nil
+	}
+	if r.client.ring == nil {
+		return nil, true, errRedisNotReady
+	}
+	keyStrs := make([]string, len(keys))
+	for i, key := range keys {
+		keyStrs[i] = fmt.Sprintf("%s_%d_%v", r.prefix, r.version, key)
+	}
+	result, err = script.Run(r.client.ctx, r.client.ring, keyStrs, args...).Result()
+	return result, true, err
+}
@@ -21,19 +21,62 @@ type ServerConfig struct {
 }
 
 type SecurityConfig struct {
-	JWTSecret  		string `mapstructure:"jwt-secret" reload:"true"`
-	FlagSecret 		string `mapstructure:"flag-secret" reload:"true"`
+	JWTSecret      string `mapstructure:"jwt-secret" reload:"true"`
+	FlagSecret     string `mapstructure:"flag-secret"` // keys every stored flag hash, never reloaded
 	AdminJWTSecret string `mapstructure:"admin-jwt-secret" reload:"true"`
+	FileSecret     string `mapstructure:"file-secret" reload:"true"` // a random one is made per process when left out
 }
 
 type DockerConfig struct {
-	SocketURL        string          `mapstructure:"socket-url"`
-	PortRange        DockerPortRange `mapstructure:"port-range"`
-	ContainerTimeout time.Duration   `mapstructure:"container-timeout"`
-	PoolSize         int             `mapstructure:"pool-size"`
-	CleanOrphaned    bool            `mapstructure:"clean-orphaned"`
-	BindingHost      string          `mapstructure:"binding-host"`
-	PortsMaxRetry    int             `mapstructure:"port-retry-times"`
+	SocketURL           string          `mapstructure:"socket-url"`
+	PortRange           DockerPortRange `mapstructure:"port-range"`
+	ContainerTimeout    time.Duration   `mapstructure:"container-timeout"`
+	PoolSize            int             `mapstructure:"pool-size"`
+	WarmPoolSize        int             `mapstructure:"warm-pool-size"`     // per challenge, challenges override it with warm_pool
+	WarmPoolInterval    time.Duration   `mapstructure:"warm-pool-interval"` // how often the warm pool is checked even when nothing happened
+	CleanOrphaned       bool            `mapstructure:"clean-orphaned"`
+	BindingHost         string          `mapstructure:"binding-host"`
+	PortLeaseTTL        time.Duration   `mapstructure:"port-lease-ttl"` // how long a port claimed in redis outlives a crashed instance
+	InstanceID          string          `mapstructure:"instance-id"`    // labels this instance's containers, must differ between instances sharing a docker host
+	MaxSandboxesPerTeam int             `mapstructure:"max-sandboxes-per-team"`
+	Sandbox             SandboxConfig   `mapstructure:"sandbox"`
+	Proxy               ProxyConfig     `mapstructure:"proxy"`
+}
+
+// ProxyConfig is the built-in sandbox proxy. When enabled sandbox ports are not
+// published on the host, players reach them as <token>.<domain> instead: HTTP ports
+// through http-listen by Host header, everything else through the TLS gateway by SNI.
+// Routes are kept in memory, so the domain must point at the instance running the sandboxes.
+type ProxyConfig struct {
+	Enabled        bool   `mapstructure:"enabled"`
+	Domain         string `mapstructure:"domain"`
+	HTTPListen     string `mapstructure:"http-listen"`
+	TLSListen      string `mapstructure:"tls-listen"`
+	TLSCert        string `mapstructure:"tls-cert"`
+	TLSKey         string `mapstructure:"tls-key"`
+	HTTPScheme     string `mapstructure:"http-scheme"`      // scheme in links, https when a TLS terminator sits in front
+	HTTPPublicPort int    `mapstructure:"http-public-port"` // port in links, 0 leaves it out
+	TLSPublicPort  int    `mapstructure:"tls-public-port"`
+}
+
+// SandboxConfig holds the resource and security settings every sandbox container gets
+// unless its challenge overrides them. Zero limits mean unlimited, the max-* values cap
+// what a challenge may ask for, allowed-cap-add the capabilities it may add back.
+// Settings left out of the config file get the defaults from values.setDefaults.
+type SandboxConfig struct {
+	MemoryMB           int64    `mapstructure:"memory-mb"`
+	CPUs               float64  `mapstructure:"cpus"`
+	PidsLimit          int64    `mapstructure:"pids-limit"`
+	MaxMemoryMB        int64    `mapstructure:"max-memory-mb"`
+	MaxCPUs            float64  `mapstructure:"max-cpus"`
+	MaxPidsLimit       int64    `mapstructure:"max-pids-limit"`
+	CapDrop            []string `mapstructure:"cap-drop"`
+	CapAdd             []string `mapstructure:"cap-add"`
+	AllowedCapAdd      []string `mapstructure:"allowed-cap-add"`
+	ReadOnly           bool     `mapstructure:"read-only"`
+	Tmpfs              []string `mapstructure:"tmpfs"`
+	SeccompProfile     string   `mapstructure:"seccomp-profile"`
+	AllowNewPrivileges bool     `mapstructure:"allow-new-privileges"`
 }
 
 type DockerPortRange struct {
@@ -52,14 +95,79 @@ type DatabaseConfig struct {
 }
 
 type AppConfig struct {
-	TokenExpiry   time.Duration      `mapstructure:"token-expiry" reload:"true"`
-	Leaderboard   LeaderboardConfig  `mapstructure:"leaderboard" reload:"true"`
-	FlagFormat    string             `mapstructure:"flag-format" reload:"true"`
-	CacheDuration time.Duration      `mapstructure:"frontend-cache-duration" reload:"true"`
-	Ban           BanConfig          `mapstructure:"ban" reload:"true"`
-	AppCache      CacheConfig        `mapstructure:"cache"`
-	Notification  NotificationConfig `mapstructure:"notifications" reload:"true"`
-	Auth          AuthServiceConfig  `mapstructure:"auth-service" reload:"true"`
+	TokenExpiry     time.Duration      `mapstructure:"token-expiry" reload:"true"`
+	Leaderboard     LeaderboardConfig  `mapstructure:"leaderboard" reload:"true"`
+	FlagFormat      string             `mapstructure:"flag-format" reload:"true"`
+	VerifyFloor     time.Duration      `mapstructure:"flag-verify-floor" reload:"true"` // 25ms when left out, 0 turns it off
+	ReleaseInterval time.Duration      `mapstructure:"release-check-interval" reload:"true"`
+	CacheDuration   time.Duration      `mapstructure:"frontend-cache-duration" reload:"true"`
+	Ban             BanConfig          `mapstructure:"ban" reload:"true"`
+	AppCache        CacheConfig        `mapstructure:"cache"`
+	Notification    NotificationConfig `mapstructure:"notifications" reload:"true"`
+	Auth            AuthServiceConfig  `mapstructure:"auth-service" reload:"true"`
+	Event           EventConfig        `mapstructure:"event" reload:"true"`
+	Stream          StreamConfig       `mapstructure:"stream" reload:"true"`
+	RateLimit       RateLimitConfig    `mapstructure:"rate-limit" reload:"true"`
+	FlagSharing     FlagSharingConfig  `mapstructure:"flag-sharing" reload:"true"`
+	Storage         StorageConfig      `mapstructure:"storage" reload:"true"`
+}
+
+// StorageConfig is where challenge attachments are kept
+type StorageConfig struct {
+	Backend       string        `mapstructure:"backend"`
+	LocalDir      string        `mapstructure:"local-dir"`
+	MaxUploadSize int64         `mapstructure:"max-upload-size" reload:"true"`
+	URLExpiry     time.Duration `mapstructure:"url-expiry" reload:"true"`
+}
+
+const (
+	FlagSharingAlert        = "alert"
+	FlagSharingBanSubmitter = "ban-submitter"
+	FlagSharingBanBoth      = "ban-both"
+)
+
+type FlagSharingConfig struct {
+	Action string `mapstructure:"action" reload:"true"`
+}
+
+type RateLimitConfig struct {
+	Enabled         bool          `mapstructure:"enabled" reload:"true"`
+	User            BucketConfig  `mapstructure:"user" reload:"true"`
+	Team            BucketConfig  `mapstructure:"team" reload:"true"`
+	Challenge       BucketConfig  `mapstructure:"challenge" reload:"true"`
+	BanAfter        int           `mapstructure:"ban-after" reload:"true"`
+	ViolationWindow time.Duration `mapstructure:"violation-window"`
+}
+
+// BucketConfig is a token bucket: burst submissions at once, one more every refill-every
+type BucketConfig struct {
+	Burst       int           `mapstructure:"burst" reload:"true"`
+	RefillEvery time.Duration `mapstructure:"refill-every" reload:"true"`
+}
+
+type StreamConfig struct {
+	HistorySize    int           `mapstructure:"history-size"`
+	KeepAlive      time.Duration `mapstructure:"keep-alive" reload:"true"`
+	SandboxWarning time.Duration `mapstructure:"sandbox-warning" reload:"true"`
+}
+
+// EventConfig describes the competition window. Zero values leave that bound open.
+type EventConfig struct {
+	Start  time.Time `mapstructure:"start" reload:"true"`
+	End    time.Time `mapstructure:"end" reload:"true"`
+	Freeze time.Time `mapstructure:"freeze" reload:"true"`
+}
+
+func (e EventConfig) Started(now time.Time) bool {
+	return e.Start.IsZero() || !now.Before(e.Start)
+}
+
+func (e EventConfig) Ended(now time.Time) bool {
+	return !e.End.IsZero() && !now.Before(e.End)
+}
+
+func (e EventConfig) Frozen(now time.Time) bool {
+	return !e.Freeze.IsZero() && !now.Before(e.Freeze)
 }
 
 type AuthServiceConfig struct {
@@ -100,19 +208,31 @@ type BanConfig struct {
 }
 
 type NotificationConfig struct {
-	Enabled        bool                    `mapstructure:"enabled" reload:"true"`
-	DeliveryMethod string                  `mapstructure:"delivery-method" reload:"true"`
-	DefaultRetry   int                     `mapstructure:"default-retry" reload:"true"`
-	RetryDelay     time.Duration           `mapstructure:"retry-delay" reload:"true"`
-	Timeout        time.Duration           `mapstructure:"timeout" reload:"true"`
-	HTTP           *HTTPNotificationConfig `mapstructure:"http" reload:"true"`
+	Enabled        bool                     `mapstructure:"enabled" reload:"true"`
+	DeliveryMethod string                   `mapstructure:"delivery-method" reload:"true"`
+	DefaultRetry   int                      `mapstructure:"default-retry" reload:"true"`
+	RetryDelay     time.Duration            `mapstructure:"retry-delay" reload:"true"`
+	Timeout        time.Duration            `mapstructure:"timeout" reload:"true"`
+	HTTP           *HTTPNotificationConfig  `mapstructure:"http" reload:"true"`
+	Kafka          *KafkaNotificationConfig `mapstructure:"kafka" reload:"true"`
+}
+
+// KafkaNotificationConfig is read when the notifier is built, a reload that changes
+// it closes the old notifier and builds a new one
+type KafkaNotificationConfig struct {
+	Brokers      []string      `mapstructure:"brokers" reload:"true"`
+	Topic        string        `mapstructure:"topic" reload:"true"`
+	BatchSize    int           `mapstructure:"batch-size" reload:"true"`
+	BatchTimeout time.Duration `mapstructure:"batch-timeout" reload:"true"`
+	BufferDir    string        `mapstructure:"buffer-dir" reload:"true"`
+	MaxBuffered  int           `mapstructure:"max-buffered" reload:"true"`
 }
 
 type HTTPNotificationConfig struct {
 	URL          string `mapstructure:"url" reload:"true"`
 	Endpoint     string `mapstructure:"endpoint" reload:"true"`
 	APIKey       string `mapstructure:"api-key" reload:"true"`
-	HashedAPIKey string `mapstructure"-"`
+	HashedAPIKey string `mapstructure:"-"`
 }
 
 func (cfg *Config) Validate() error {
@@ -123,6 +243,86 @@ func (cfg *Config) Validate() error {
 	if lb.FullPointsThreshold < 0 {
 		return fmt.Errorf("full-points-threshold must be >= 0")
 	}
+	if cfg.Docker.MaxSandboxesPerTeam < 0 {
+		return fmt.Errorf("max-sandboxes-per-team must be >= 0")
+	}
+	sb := cfg.Docker.Sandbox
+	if sb.MemoryMB < 0 || sb.CPUs < 0 || sb.PidsLimit < 0 || sb.MaxMemoryMB < 0 || sb.MaxCPUs < 0 || sb.MaxPidsLimit < 0 {
+		return fmt.Errorf("docker sandbox limits must be >= 0")
+	}
+	if pr := cfg.Docker.PortRange; !cfg.Docker.Proxy.Enabled && (pr.Start < 1 || pr.End > 65535 || pr.Start > pr.End) {
+		return fmt.Errorf("docker port-range must be within 1-65535 with start <= end")
+	}
+	if cfg.Docker.WarmPoolSize < 0 || cfg.Docker.WarmPoolInterval < 0 {
+		return fmt.Errorf("docker warm-pool-size and warm-pool-interval must be >= 0")
+	}
+	if cfg.Docker.PortLeaseTTL < 0 {
+		return fmt.Errorf("docker port-lease-ttl must be >= 0")
+	}
+	if proxy := cfg.Docker.Proxy; proxy.Enabled {
+		if proxy.Domain == "" {
+			return fmt.Errorf("docker proxy needs a domain")
+		}
+		if proxy.HTTPListen == "" && proxy.TLSListen == "" {
+			return fmt.Errorf("docker proxy needs http-listen or tls-listen")
+		}
+		if proxy.TLSListen != "" && (proxy.TLSCert == "" || proxy.TLSKey == "") {
+			return fmt.Errorf("docker proxy tls-listen needs tls-cert and tls-key")
+		}
+	}
+	if cfg.App.Stream.HistorySize < 0 {
+		return fmt.Errorf("stream history-size must be >= 0")
+	}
+	rl := cfg.App.RateLimit
+	for _, bucket := range []BucketConfig{rl.User, rl.Team, rl.Challenge} {
+		if bucket.Burst < 0 || bucket.RefillEvery < 0 {
+			return fmt.Errorf("rate-limit burst and refill-every must be >= 0")
+		}
+	}
+	if rl.BanAfter < 0 {
+		return fmt.Errorf("rate-limit ban-after must be >= 0")
+	}
+	if cfg.App.ReleaseInterval < 0 {
+		return fmt.Errorf("release-check-interval must be >= 0")
+	}
+	if cfg.App.VerifyFloor < 0 {
+		return fmt.Errorf("flag-verify-floor must be >= 0")
+	}
+	switch cfg.App.FlagSharing.Action {
+	case "", FlagSharingAlert, FlagSharingBanSubmitter, FlagSharingBanBoth:
+	default:
+		return fmt.Errorf("flag-sharing action must be one of alert, ban-submitter, ban-both")
+	}
+	storage := cfg.App.Storage
+	switch storage.Backend {
+	case "", "local":
+	default:
+		return fmt.Errorf("storage backend must be local")
+	}
+	if storage.MaxUploadSize < 0 || storage.URLExpiry < 0 {
+		return fmt.Errorf("storage max-upload-size and url-expiry must be >= 0")
+	}
+	notif := cfg.App.Notification
+	if notif.Enabled && notif.DeliveryMethod == "kafka" {
+		if notif.Kafka == nil || len(notif.Kafka.Brokers) == 0 || notif.Kafka.Topic == "" {
+			return fmt.Errorf("kafka notifications need brokers and a topic")
+		}
+		if notif.Kafka.BatchSize < 0 || notif.Kafka.MaxBuffered < 0 {
+			return fmt.Errorf("kafka batch-size and max-buffered must be >= 0")
+		}
+	}
+	event := cfg.App.Event
+	if !event.Start.IsZero() && !event.End.IsZero() && !event.End.After(event.Start) {
+		return fmt.Errorf("event end must be after event start")
+	}
+	if !event.Freeze.IsZero() {
+		if !event.Start.IsZero() && event.Freeze.Before(event.Start) {
+			return fmt.Errorf("event freeze must not be before event start")
+		}
+		if !event.End.IsZero() && event.Freeze.After(event.End) {
+			return fmt.Errorf("event freeze must not be after event end")
+		}
+	}
 	cache := cfg.App.AppCache
 	if cache.InApp {
 		if cache.ServiceType != "redis" {
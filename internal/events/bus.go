@@ -0,0 +1,131 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	defaultHistorySize = 1024
+	subscriberBuffer   = 64
+)
+
+type Scope int
+
+const (
+	ScopeAll Scope = iota
+	ScopeGlobal
+	ScopeTeam
+)
+
+type Filter struct {
+	Scope  Scope
+	TeamID *uint
+}
+
+func (f Filter) matches(evt Event) bool {
+	if evt.TeamID == nil {
+		return f.Scope != ScopeTeam
+	}
+	return f.Scope != ScopeGlobal && f.TeamID != nil && *f.TeamID == *evt.TeamID
+}
+
+// Bus fans events out to subscribers and keeps a bounded history so clients can resume
+type Bus struct {
+	mu          sync.Mutex
+	nextID      uint64
+	history     []Event
+	historySize int
+	subs        map[*Subscription]struct{}
+}
+
+type Subscription struct {
+	C      <-chan Event
+	ch     chan Event
+	filter Filter
+	bus    *Bus
+	once   sync.Once
+}
+
+func NewBus(historySize int) *Bus {
+	if historySize <= 0 {
+		historySize = defaultHistorySize
+	}
+	return &Bus{
+		historySize: historySize,
+		subs:        make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish stamps the event with the next ID and delivers it. Subscribers that fall
+// too far behind are dropped; they can reconnect with Last-Event-ID.
+func (b *Bus) Publish(evt Event) Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.nextID++
+	evt.ID = b.nextID
+	if evt.CreatedAt.IsZero() {
+		evt.CreatedAt = time.Now()
+	}
+	b.history = append(b.history, evt)
+	if len(b.history) > b.historySize {
+		b.history = b.history[len(b.history)-b.historySize:]
+	}
+	for sub := range b.subs {
+		if !sub.filter.matches(evt) {
+			continue
+		}
+		select {
+		case sub.ch <- evt:
+		default:
+			b.drop(sub)
+		}
+	}
+	return evt
+}
+
+// Subscribe registers a subscriber and returns the retained events after lastID
+func (b *Bus) Subscribe(filter Filter, lastID uint64) (*Subscription, []Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var backlog []Event
+	if lastID > 0 {
+		for _, evt := range b.history {
+			if evt.ID > lastID && filter.matches(evt) {
+				backlog = append(backlog, evt)
+			}
+		}
+	}
+	ch := make(chan Event, subscriberBuffer)
+	sub := &Subscription{C: ch, ch: ch, filter: filter, bus: b}
+	b.subs[sub] = struct{}{}
+	return sub, backlog
+}
+
+func (b *Bus) drop(sub *Subscription) {
+	sub.once.Do(func() {
+		delete(b.subs, sub)
+		close(sub.ch)
+	})
+}
+
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	s.bus.drop(s)
+}
+
+var bus = NewBus(defaultHistorySize)
+
+// Init replaces the default bus, call it before any subscriber connects
+func Init(historySize int) {
+	bus = NewBus(historySize)
+}
+
+func Publish(evt Event) Event {
+	return bus.Publish(evt)
+}
+
+func Subscribe(filter Filter, lastID uint64) (*Subscription, []Event) {
+	return bus.Subscribe(filter, lastID)
+}
@@ -0,0 +1,74 @@
+package events
+
+import "time"
+
+type Type string
+
+const (
+	Solve             Type = "solve"
+	FirstBlood        Type = "first_blood"
+	SecondBlood       Type = "second_blood"
+	ThirdBlood        Type = "third_blood"
+	ChallengeReleased Type = "challenge_released"
+	Announcement      Type = "announcement"
+	SandboxExpiring   Type = "sandbox_expiring"
+)
+
+// Blood maps a solve position to its blood event, ok is false past third blood
+func Blood(position int) (Type, bool) {
+	switch position {
+	case 1:
+		return FirstBlood, true
+	case 2:
+		return SecondBlood, true
+	case 3:
+		return ThirdBlood, true
+	}
+	return "", false
+}
+
+// Event is a single message on the bus. A nil TeamID makes it global.
+type Event struct {
+	ID        uint64    `json:"id"`
+	Type      Type      `json:"type"`
+	TeamID    *uint     `json:"team_id,omitempty"`
+	Data      any       `json:"data"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type SolveData struct {
+	UserID        uint   `json:"user_id"`
+	Username      string `json:"username"`
+	TeamID        uint   `json:"team_id"`
+	ChallengeID   uint   `json:"challenge_id"`
+	ChallengeName string `json:"challenge_name"`
+	Blood         int    `json:"blood,omitempty"`
+}
+
+type ChallengeData struct {
+	ChallengeID uint   `json:"challenge_id"`
+	Name        string `json:"name"`
+}
+
+type AnnouncementData struct {
+	ID          uint   `json:"id"`
+	Title       string `json:"title"`
+	Message     string `json:"message"`
+	ChallengeID *uint  `json:"challenge_id,omitempty"`
+	Pinned      bool   `json:"pinned"`
+}
+
+type SandboxData struct {
+	ChallengeID uint      `json:"challenge_id"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// Global builds an event delivered to every subscriber
+func Global(kind Type, data any) Event {
+	return Event{Type: kind, Data: data}
+}
+
+// ForTeam builds an event delivered only to the given team
+func ForTeam(teamID uint, kind Type, data any) Event {
+	return Event{Type: kind, TeamID: &teamID, Data: data}
+}
@@ -0,0 +1,108 @@
+package flags
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/intraware/rodan/internal/models"
+)
+
+const (
+	ModeExact           = "exact"
+	ModeCaseInsensitive = "case-insensitive"
+	ModeRegex           = "regex"
+)
+
+// compiled regex matchers, keyed by the final (wrapped and anchored) pattern
+var patterns sync.Map
+
+// Wrap puts the flag inside the configured flag format, e.g. rodan{inner}
+func Wrap(format, inner string) string {
+	return fmt.Sprintf("%s{%s}", format, inner)
+}
+
+func pattern(m models.FlagMatcher, format string) string {
+	expr := m.Flag
+	if m.WrapFormat {
+		expr = regexp.QuoteMeta(format+"{") + "(?:" + expr + ")" + regexp.QuoteMeta("}")
+	}
+	return "^(?:" + expr + ")$"
+}
+
+func compile(expr string) (*regexp.Regexp, error) {
+	if re, ok := patterns.Load(expr); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	patterns.Store(expr, re)
+	return re, nil
+}
+
+// Validate checks a matcher before it is stored
+func Validate(m models.FlagMatcher) error {
+	if m.Flag == "" {
+		return errors.New("flag must not be empty")
+	}
+	if IsHashed(m.Flag) && m.Mode == ModeRegex {
+		return errors.New("a hashed flag cannot be used as a regex")
+	}
+	switch m.Mode {
+	case "", ModeExact, ModeCaseInsensitive:
+		return nil
+	case ModeRegex:
+		if _, err := regexp.Compile(pattern(m, "format")); err != nil {
+			return fmt.Errorf("invalid flag regex: %w", err)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown flag mode %q, expected one of %s, %s, %s", m.Mode, ModeExact, ModeCaseInsensitive, ModeRegex)
+}
+
+// Matches reports whether the submitted flag is accepted by the matcher. The secret is
+// only needed for matchers whose flag is stored hashed.
+func Matches(m models.FlagMatcher, submitted, format, secret string) bool {
+	if m.TrimSpace {
+		submitted = strings.TrimSpace(submitted)
+	}
+	if IsHashed(m.Flag) {
+		return matchesHashed(m, submitted, format, secret)
+	}
+	switch m.Mode {
+	case ModeRegex:
+		re, err := compile(pattern(m, format))
+		if err != nil {
+			return false
+		}
+		return re.MatchString(submitted)
+	case ModeCaseInsensitive:
+		expected := m.Flag
+		if m.WrapFormat {
+			expected = Wrap(format, expected)
+		}
+		return Equal(strings.ToLower(submitted), strings.ToLower(expected))
+	default:
+		expected := m.Flag
+		if m.WrapFormat {
+			expected = Wrap(format, expected)
+		}
+		return Equal(submitted, expected)
+	}
+}
+
+// MatchAny reports whether any of the matchers accepts the submitted flag. Every
+// matcher is tried, so which one matched does not show in the timing.
+func MatchAny(matchers []models.FlagMatcher, submitted, format, secret string) bool {
+	matched := false
+	for _, m := range matchers {
+		if Matches(m, submitted, format, secret) {
+			matched = true
+		}
+	}
+	return matched
+}
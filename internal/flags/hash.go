@@ -0,0 +1,102 @@
+package flags
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+
+	"github.com/intraware/rodan/internal/models"
+	"gorm.io/gorm"
+)
+
+// hashPrefix marks a stored flag as an HMAC rather than plaintext
+const hashPrefix = "hmac-sha256:"
+
+// Hash keys the flag with the flag secret, so a leaked table cannot be brute forced
+// without the secret as well. Rotating flag-secret invalidates every stored static flag.
+func Hash(secret, flag string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(flag))
+	return hashPrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+func IsHashed(flag string) bool {
+	return strings.HasPrefix(flag, hashPrefix)
+}
+
+// Hashable reports whether the matcher can be stored as a hash. Regex patterns are
+// kept as they are, they cannot be matched otherwise.
+func Hashable(m models.FlagMatcher) bool {
+	return m.Mode != ModeRegex && !IsHashed(m.Flag)
+}
+
+// Seal replaces the plaintext flag of an exact or case-insensitive matcher with its hash
+func Seal(m models.FlagMatcher, secret string) models.FlagMatcher {
+	if !Hashable(m) {
+		return m
+	}
+	flag := m.Flag
+	if m.TrimSpace {
+		flag = strings.TrimSpace(flag)
+	}
+	if m.Mode == ModeCaseInsensitive {
+		flag = strings.ToLower(flag)
+	}
+	m.Flag = Hash(secret, flag)
+	return m
+}
+
+// matchesHashed normalizes the submission the same way Seal normalized the flag and
+// compares the hashes in constant time
+func matchesHashed(m models.FlagMatcher, submitted, format, secret string) bool {
+	if m.WrapFormat {
+		prefix, suffix := format+"{", "}"
+		if len(submitted) < len(prefix)+len(suffix) || !strings.HasSuffix(submitted, suffix) {
+			return false
+		}
+		head := submitted[:len(prefix)]
+		if head != prefix && !(m.Mode == ModeCaseInsensitive && strings.EqualFold(head, prefix)) {
+			return false
+		}
+		submitted = submitted[len(prefix) : len(submitted)-len(suffix)]
+	}
+	if m.Mode == ModeCaseInsensitive {
+		submitted = strings.ToLower(submitted)
+	}
+	return subtle.ConstantTimeCompare([]byte(Hash(secret, submitted)), []byte(m.Flag)) == 1
+}
+
+// MigratePlaintext hashes static flags and flag matchers that were stored before
+// flags were hashed. Rows that are already hashed are left alone, so it is safe to
+// run on every start.
+func MigratePlaintext(db *gorm.DB, secret string) (int, error) {
+	migrated := 0
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var statics []models.StaticConfig
+		if err := tx.Where("flag <> '' AND flag NOT LIKE ?", hashPrefix+"%").Find(&statics).Error; err != nil {
+			return err
+		}
+		for _, static := range statics {
+			if err := tx.Model(&models.StaticConfig{}).
+				Where("challenge_id = ?", static.ChallengeID).
+				Update("flag", Hash(secret, static.Flag)).Error; err != nil {
+				return err
+			}
+			migrated++
+		}
+		var matchers []models.FlagMatcher
+		if err := tx.Where("mode <> ? AND flag NOT LIKE ?", ModeRegex, hashPrefix+"%").Find(&matchers).Error; err != nil {
+			return err
+		}
+		for _, m := range matchers {
+			if err := tx.Model(&m).Update("flag", Seal(m, secret).Flag).Error; err != nil {
+				return err
+			}
+			migrated++
+		}
+		return nil
+	})
+	return migrated, err
+}
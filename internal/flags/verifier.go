@@ -0,0 +1,48 @@
+package flags
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"time"
+
+	"github.com/intraware/rodan/internal/models"
+)
+
+// Equal compares two flags in constant time. Both sides are hashed first, so neither
+// their length nor a shared prefix changes how long the comparison takes.
+func Equal(a, b string) bool {
+	ha := sha256.Sum256([]byte(a))
+	hb := sha256.Sum256([]byte(b))
+	return subtle.ConstantTimeCompare(ha[:], hb[:]) == 1
+}
+
+// Verifier checks submitted flags. Every check takes at least Floor, so a right and
+// a wrong answer cannot be told apart by how long verification took. Regex matchers
+// are the exception to the constant time comparison, the floor still applies to them.
+type Verifier struct {
+	Format string
+	Secret string
+	Floor  time.Duration
+}
+
+func NewVerifier(format, secret string, floor time.Duration) Verifier {
+	return Verifier{Format: format, Secret: secret, Floor: floor}
+}
+
+// Static checks a submission against the accepted flags of a static challenge
+func (v Verifier) Static(matchers []models.FlagMatcher, submitted string) bool {
+	defer v.pad(time.Now())
+	return MatchAny(matchers, submitted, v.Format, v.Secret)
+}
+
+// Dynamic checks a submission against the flag generated for the team
+func (v Verifier) Dynamic(expected, submitted string) bool {
+	defer v.pad(time.Now())
+	return Equal(expected, submitted)
+}
+
+func (v Verifier) pad(start time.Time) {
+	if rest := v.Floor - time.Since(start); rest > 0 {
+		time.Sleep(rest)
+	}
+}
@@ -0,0 +1,140 @@
+package flags_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/intraware/rodan/internal/flags"
+	"github.com/intraware/rodan/internal/models"
+)
+
+const (
+	testFormat = "rodan"
+	testSecret = "s3cret"
+)
+
+func TestVerifierStaticModes(t *testing.T) {
+	v := flags.NewVerifier(testFormat, testSecret, 0)
+	cases := []struct {
+		name      string
+		matcher   models.FlagMatcher
+		submitted string
+		want      bool
+	}{
+		{"exact", models.FlagMatcher{Flag: "rodan{abc}"}, "rodan{abc}", true},
+		{"exact wrong case", models.FlagMatcher{Flag: "rodan{abc}"}, "rodan{ABC}", false},
+		{"exact untrimmed", models.FlagMatcher{Flag: "rodan{abc}"}, " rodan{abc}\n", false},
+		{"exact trimmed", models.FlagMatcher{Flag: "rodan{abc}", TrimSpace: true}, " rodan{abc}\n", true},
+		{"case-insensitive", models.FlagMatcher{Flag: "rodan{abc}", Mode: flags.ModeCaseInsensitive}, "RODAN{AbC}", true},
+		{"wrapped", models.FlagMatcher{Flag: "abc", WrapFormat: true}, "rodan{abc}", true},
+		{"wrapped bare", models.FlagMatcher{Flag: "abc", WrapFormat: true}, "abc", false},
+		{"regex", models.FlagMatcher{Flag: `rodan\{[0-9]+\}`, Mode: flags.ModeRegex}, "rodan{1234}", true},
+		{"regex anchored", models.FlagMatcher{Flag: `rodan\{[0-9]+\}`, Mode: flags.ModeRegex}, "xrodan{1234}", false},
+		{"regex wrapped", models.FlagMatcher{Flag: `[a-z]+`, Mode: flags.ModeRegex, WrapFormat: true}, "rodan{abc}", true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			matchers := []models.FlagMatcher{tc.matcher}
+			if got := v.Static(matchers, tc.submitted); got != tc.want {
+				t.Fatalf("plaintext: expected %v, got %v", tc.want, got)
+			}
+			sealed := []models.FlagMatcher{flags.Seal(tc.matcher, testSecret)}
+			if got := v.Static(sealed, tc.submitted); got != tc.want {
+				t.Fatalf("hashed: expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestVerifierStaticAcceptsAnyFlag(t *testing.T) {
+	v := flags.NewVerifier(testFormat, testSecret, 0)
+	matchers := []models.FlagMatcher{
+		flags.Seal(models.FlagMatcher{Flag: "rodan{first}"}, testSecret),
+		flags.Seal(models.FlagMatcher{Flag: "rodan{second}"}, testSecret),
+	}
+	for _, flag := range []string{"rodan{first}", "rodan{second}"} {
+		if !v.Static(matchers, flag) {
+			t.Fatalf("expected %s to be accepted", flag)
+		}
+	}
+	if v.Static(matchers, "rodan{third}") {
+		t.Fatal("expected rodan{third} to be rejected")
+	}
+}
+
+func TestVerifierDynamic(t *testing.T) {
+	v := flags.NewVerifier(testFormat, testSecret, 0)
+	if !v.Dynamic("rodan{0123456789abcdef}", "rodan{0123456789abcdef}") {
+		t.Fatal("expected the team flag to be accepted")
+	}
+	for _, wrong := range []string{"", "rodan{0123456789abcde}", "rodan{0123456789abcdef}x", "RODAN{0123456789abcdef}"} {
+		if v.Dynamic("rodan{0123456789abcdef}", wrong) {
+			t.Fatalf("expected %q to be rejected", wrong)
+		}
+	}
+}
+
+func TestEqual(t *testing.T) {
+	// Equal hashes both sides before the constant time compare, timing it here would
+	// only measure the scheduler, so only the outcome is checked
+	long := strings.Repeat("a", 1<<16)
+	cases := []struct {
+		name string
+		a, b string
+		want bool
+	}{
+		{"same", "rodan{abc}", "rodan{abc}", true},
+		{"both empty", "", "", true},
+		{"empty submission", "rodan{abc}", "", false},
+		{"different length", "rodan{abc}", "rodan{abcd}", false},
+		{"first byte differs", long, "b" + long[1:], false},
+		{"last byte differs", long, long[:len(long)-1] + "b", false},
+		{"long same", long, long, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := flags.Equal(tc.a, tc.b); got != tc.want {
+				t.Fatalf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestVerifierFloorHidesOutcome(t *testing.T) {
+	// the fastest of a few runs is compared, so a busy machine running the tests only
+	// makes single runs slow and not the result flaky
+	const (
+		floor  = 50 * time.Millisecond
+		window = floor / 2
+		runs   = 5
+	)
+	v := flags.NewVerifier(testFormat, testSecret, floor)
+	correct := "rodan{0123456789abcdef0123456789abcdef}"
+	matchers := []models.FlagMatcher{flags.Seal(models.FlagMatcher{Flag: correct}, testSecret)}
+	submissions := map[string]string{
+		"right":        correct,
+		"wrong prefix": "x" + correct[1:],
+		"wrong suffix": correct[:len(correct)-2] + "x}",
+		"empty":        "",
+	}
+	for name, submitted := range submissions {
+		checks := map[string]func() bool{
+			"static":  func() bool { return v.Static(matchers, submitted) },
+			"dynamic": func() bool { return v.Dynamic(correct, submitted) },
+		}
+		for kind, check := range checks {
+			fastest := time.Duration(1<<63 - 1)
+			for range runs {
+				start := time.Now()
+				if got := check(); got != (name == "right") {
+					t.Fatalf("%s %s: unexpected outcome %v", kind, name, got)
+				}
+				fastest = min(fastest, time.Since(start))
+			}
+			if fastest < floor || fastest >= floor+window {
+				t.Fatalf("%s %s: took %v, outside the %v to %v window", kind, name, fastest, floor, floor+window)
+			}
+		}
+	}
+}
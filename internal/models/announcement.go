@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Announcement struct {
+	gorm.Model
+	Title       string    `json:"title" gorm:"column:title;not null"`
+	Body        string    `json:"body" gorm:"column:body;type:text"`
+	ChallengeID *uint     `json:"challenge_id,omitempty" gorm:"column:challenge_id;index"`
+	Pinned      bool      `json:"pinned" gorm:"column:pinned;default:false"`
+	VisibleAt   time.Time `json:"visible_at" gorm:"column:visible_at;index"`
+}
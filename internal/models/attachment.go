@@ -0,0 +1,18 @@
+package models
+
+import "gorm.io/gorm"
+
+// Attachment is a file handed out with a challenge. The contents live in the storage
+// backend under Key. A templated attachment has Placeholder swapped for the team's
+// flag on every download.
+type Attachment struct {
+	gorm.Model
+	ChallengeID uint   `json:"challenge_id" gorm:"index"`
+	Name        string `json:"name"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	SHA256      string `json:"sha256"`
+	Key         string `json:"-" gorm:"uniqueIndex"`
+	Templated   bool   `json:"templated"`
+	Placeholder string `json:"placeholder,omitempty"`
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// Label is the shared shape of categories and difficulties
+type Label struct {
+	gorm.Model
+	Name      string `json:"name" gorm:"uniqueIndex"`
+	Color     string `json:"color"`
+	SortOrder int    `json:"order"`
+}
+
+// Base gives generic code access to the label fields of a Category or Difficulty
+func (l *Label) Base() *Label {
+	return l
+}
+
+type Category struct {
+	Label
+}
+
+type Difficulty struct {
+	Label
+}
+
+// migrateLegacyLabels moves the int8 category and difficulty columns of older databases
+// onto the Category and Difficulty tables, with one row per value that was in use.
+// The old columns are dropped afterwards, so it only does work once.
+func migrateLegacyLabels() error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		legacy := []struct {
+			table, column string
+			prefix        string
+			label         func(tx *gorm.DB, name string, order int64) (uint, error)
+		}{
+			{"challenges", "category", "Category", firstOrCreateLabel[Category]},
+			{"challenge_prerequisites", "category", "Category", firstOrCreateLabel[Category]},
+			{"challenges", "difficulty", "Difficulty", firstOrCreateLabel[Difficulty]},
+		}
+		for _, l := range legacy {
+			if !tx.Migrator().HasColumn(l.table, l.column) {
+				continue
+			}
+			var values []int64
+			if err := tx.Table(l.table).Distinct(l.column).Where(l.column+" IS NOT NULL").Pluck(l.column, &values).Error; err != nil {
+				return err
+			}
+			for _, value := range values {
+				id, err := l.label(tx, fmt.Sprintf("%s %d", l.prefix, value), value)
+				if err != nil {
+					return err
+				}
+				if err := tx.Table(l.table).Where(l.column+" = ?", value).Update(l.column+"_id", id).Error; err != nil {
+					return err
+				}
+			}
+			if err := tx.Migrator().DropColumn(l.table, l.column); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func firstOrCreateLabel[T any](tx *gorm.DB, name string, order int64) (uint, error) {
+	var row T
+	// a map condition is copied onto the row when it has to be created, a string one is not
+	if err := tx.Where(map[string]any{"name": name}).Attrs(map[string]any{"sort_order": order}).FirstOrCreate(&row).Error; err != nil {
+		return 0, err
+	}
+	var id uint
+	err := tx.Model(new(T)).Where("name = ?", name).Select("id").Scan(&id).Error
+	return id, err
+}
+
+// purgeDeletedLabels removes the categories and difficulties older versions soft
+// deleted, their names would otherwise stay taken by the unique index
+func purgeDeletedLabels() error {
+	for _, model := range []any{&Category{}, &Difficulty{}} {
+		if err := DB.Unscoped().Where("deleted_at IS NOT NULL").Delete(model).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
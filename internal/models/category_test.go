@@ -0,0 +1,182 @@
+package models
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// useTestSchema points DB at a fresh schema of the database in DATABASE_URL, the test
+// is skipped when it is not set
+func useTestSchema(t *testing.T) {
+	t.Helper()
+	dsn := os.Getenv("DATABASE_URL")
+	if dsn == "" {
+		t.Skip("DATABASE_URL is not set")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+		TranslateError: true,
+		Logger:         logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// one connection, so the search path set below applies to every query
+	sqlDB.SetMaxOpenConns(1)
+	schema := fmt.Sprintf("rodan_test_%d", time.Now().UnixNano())
+	if err := db.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		t.Fatalf("failed to create schema: %v", err)
+	}
+	if err := db.Exec("SET search_path TO " + schema).Error; err != nil {
+		t.Fatalf("failed to set search path: %v", err)
+	}
+	previous := DB
+	DB = db
+	t.Cleanup(func() {
+		DB = previous
+		db.Exec("DROP SCHEMA " + schema + " CASCADE")
+		sqlDB.Close()
+	})
+}
+
+func mustExec(t *testing.T, sql string, args ...any) {
+	t.Helper()
+	if err := DB.Exec(sql, args...).Error; err != nil {
+		t.Fatalf("%s: %v", sql, err)
+	}
+}
+
+func labelIDs[T any](t *testing.T) map[string]uint {
+	t.Helper()
+	var rows []struct {
+		ID        uint
+		Name      string
+		SortOrder int
+	}
+	if err := DB.Model(new(T)).Select("id, name, sort_order").Find(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	ids := make(map[string]uint, len(rows))
+	for _, row := range rows {
+		if row.Name == "" {
+			t.Fatalf("label %d was created without a name", row.ID)
+		}
+		ids[row.Name] = row.ID
+	}
+	return ids
+}
+
+func TestMigrateLegacyLabels(t *testing.T) {
+	useTestSchema(t)
+	// the tables as they were while categories and difficulties were plain numbers
+	mustExec(t, `CREATE TABLE challenges (
+		id bigserial PRIMARY KEY, created_at timestamptz, updated_at timestamptz, deleted_at timestamptz,
+		name text, category smallint, difficulty smallint)`)
+	mustExec(t, `CREATE TABLE challenge_prerequisites (
+		id bigserial PRIMARY KEY, created_at timestamptz, updated_at timestamptz, deleted_at timestamptz,
+		challenge_id bigint, requires_id bigint, category smallint, min_points bigint)`)
+	mustExec(t, `INSERT INTO challenges (name, category, difficulty) VALUES ('a', 2, 1), ('b', 2, NULL), ('c', 5, 3)`)
+	mustExec(t, `INSERT INTO challenge_prerequisites (challenge_id, category, min_points) VALUES (1, 7, 100)`)
+	if err := DB.AutoMigrate(&Category{}, &Difficulty{}, &Challenge{}, &ChallengePrerequisite{}); err != nil {
+		t.Fatalf("AutoMigrate failed: %v", err)
+	}
+	// an admin already made this one, it is reused rather than duplicated
+	existing := Category{Label: Label{Name: "Category 2", SortOrder: 9}}
+	if err := DB.Create(&existing).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := migrateLegacyLabels(); err != nil {
+		t.Fatalf("migrateLegacyLabels failed: %v", err)
+	}
+
+	categories := labelIDs[Category](t)
+	if len(categories) != 3 || categories["Category 2"] != existing.ID || categories["Category 5"] == 0 || categories["Category 7"] == 0 {
+		t.Fatalf("unexpected categories: %v", categories)
+	}
+	difficulties := labelIDs[Difficulty](t)
+	if len(difficulties) != 2 || difficulties["Difficulty 1"] == 0 || difficulties["Difficulty 3"] == 0 {
+		t.Fatalf("unexpected difficulties: %v", difficulties)
+	}
+	var order int
+	DB.Model(&Category{}).Where("name = ?", "Category 5").Select("sort_order").Scan(&order)
+	if order != 5 {
+		t.Fatalf("expected the new category to keep its old number as order, got %d", order)
+	}
+
+	var challenges []Challenge
+	if err := DB.Order("id").Find(&challenges).Error; err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		category   uint
+		difficulty uint
+	}{
+		{categories["Category 2"], difficulties["Difficulty 1"]},
+		{categories["Category 2"], 0},
+		{categories["Category 5"], difficulties["Difficulty 3"]},
+	}
+	for i, challenge := range challenges {
+		var category, difficulty uint
+		if challenge.CategoryID != nil {
+			category = *challenge.CategoryID
+		}
+		if challenge.DifficultyID != nil {
+			difficulty = *challenge.DifficultyID
+		}
+		if category != want[i].category || difficulty != want[i].difficulty {
+			t.Fatalf("challenge %s: expected category %d and difficulty %d, got %d and %d",
+				challenge.Name, want[i].category, want[i].difficulty, category, difficulty)
+		}
+	}
+	var prerequisite ChallengePrerequisite
+	if err := DB.First(&prerequisite).Error; err != nil {
+		t.Fatal(err)
+	}
+	if prerequisite.CategoryID == nil || *prerequisite.CategoryID != categories["Category 7"] {
+		t.Fatalf("expected the prerequisite to point at Category 7, got %v", prerequisite.CategoryID)
+	}
+	for _, table := range []string{"challenges", "challenge_prerequisites"} {
+		if DB.Migrator().HasColumn(table, "category") {
+			t.Fatalf("expected %s.category to be dropped", table)
+		}
+	}
+
+	// a second start finds nothing left to migrate
+	if err := migrateLegacyLabels(); err != nil {
+		t.Fatalf("second migrateLegacyLabels failed: %v", err)
+	}
+	if again := labelIDs[Category](t); len(again) != len(categories) {
+		t.Fatalf("expected no new categories on the second run, got %v", again)
+	}
+}
+
+func TestDeletedLabelNameCanBeReused(t *testing.T) {
+	useTestSchema(t)
+	if err := DB.AutoMigrate(&Category{}); err != nil {
+		t.Fatal(err)
+	}
+	old := Category{Label: Label{Name: "web"}}
+	if err := DB.Create(&old).Error; err != nil {
+		t.Fatal(err)
+	}
+	// soft deleted the way older versions did it
+	if err := DB.Delete(&old).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := purgeDeletedLabels(); err != nil {
+		t.Fatalf("purgeDeletedLabels failed: %v", err)
+	}
+	if err := DB.Create(&Category{Label: Label{Name: "web"}}).Error; err != nil {
+		t.Fatalf("expected the name of a deleted category to be free again: %v", err)
+	}
+}
@@ -1,22 +1,35 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Challenge struct {
 	gorm.Model
-	Name       string `json:"name"`
-	Author     string `json:"author"`
-	Desc       string `json:"desc"`
-	Category   int8   `json:"category"`
-	PointsMin  int    `json:"points_min"`
-	PointsMax  int    `json:"points_max"`
-	Difficulty int8   `json:"difficulty"`
-	IsStatic   bool   `json:"is_static"`
-	IsVisible  bool   `json:"is_visible"`
-
-	StaticConfig  *StaticConfig  `gorm:"foreignKey:ChallengeID;constraint:OnDelete:CASCADE"`
-	DynamicConfig *DynamicConfig `gorm:"foreignKey:ChallengeID;constraint:OnDelete:CASCADE"`
-	Hints         []Hint         `json:"hints" gorm:"foreignKey:ChallengeID"`
+	Name         string     `json:"name"`
+	Author       string     `json:"author"`
+	Desc         string     `json:"desc"`
+	CategoryID   *uint      `json:"category_id" gorm:"index"`
+	PointsMin    int        `json:"points_min"`
+	PointsMax    int        `json:"points_max"`
+	DifficultyID *uint      `json:"difficulty_id" gorm:"index"`
+	IsStatic     bool       `json:"is_static"`
+	IsVisible    bool       `json:"is_visible"`
+	Scoring      string     `json:"scoring" gorm:"default:power"`
+	Decay        int        `json:"decay"`
+	Tags         []string   `json:"tags" gorm:"serializer:json"`
+	ReleaseAt    *time.Time `json:"release_at,omitempty" gorm:"index"` // made visible by the release scheduler
+	HideAt       *time.Time `json:"hide_at,omitempty" gorm:"index"`    // hidden again by the release scheduler
+
+	Category      *Category               `json:"category,omitempty" gorm:"foreignKey:CategoryID;constraint:OnDelete:SET NULL"`
+	Difficulty    *Difficulty             `json:"difficulty,omitempty" gorm:"foreignKey:DifficultyID;constraint:OnDelete:SET NULL"`
+	StaticConfig  *StaticConfig           `gorm:"foreignKey:ChallengeID;constraint:OnDelete:CASCADE"`
+	DynamicConfig *DynamicConfig          `gorm:"foreignKey:ChallengeID;constraint:OnDelete:CASCADE"`
+	Flags         []FlagMatcher           `json:"flags" gorm:"foreignKey:ChallengeID;constraint:OnDelete:CASCADE"`
+	Prerequisites []ChallengePrerequisite `json:"prerequisites" gorm:"foreignKey:ChallengeID;constraint:OnDelete:CASCADE"`
+	Hints         []Hint                  `json:"hints" gorm:"foreignKey:ChallengeID"`
 }
 
 type StaticConfig struct {
@@ -24,6 +37,7 @@ type StaticConfig struct {
 	Flag        string   `json:"flag,omitempty"`
 	Ports       []int    `json:"ports,omitempty" gorm:"type:integer[]"`
 	Links       []string `json:"links,omitempty" gorm:"type:text[]"`
+	PerTeam     bool     `json:"per_team"` // flag derived per team like a dynamic one, handed out in templated attachments
 }
 
 type DynamicConfig struct {
@@ -33,6 +47,30 @@ type DynamicConfig struct {
 	TTL          int64    `json:"ttl"`
 	Reusable     bool     `json:"reusable"`
 	IsFiles      bool     `json:"is_files"`
+	WarmPool     *int     `json:"warm_pool,omitempty"` // containers kept started ahead of time, unset uses warm-pool-size
+	// resource and security overrides, zero values fall back to the [docker.sandbox] defaults
+	MemoryMB       int64    `json:"memory_mb,omitempty"`
+	CPUs           float64  `json:"cpus,omitempty"`
+	PidsLimit      int64    `json:"pids_limit,omitempty"`
+	CapAdd         []string `json:"cap_add,omitempty" gorm:"serializer:json"`
+	CapDrop        []string `json:"cap_drop,omitempty" gorm:"serializer:json"`
+	ReadOnly       *bool    `json:"read_only,omitempty"`
+	Tmpfs          []string `json:"tmpfs,omitempty" gorm:"serializer:json"`
+	SeccompProfile string   `json:"seccomp_profile,omitempty"`
+	// set for multi-container challenges, DockerImage and ExposedPorts are then unused
+	Services []SandboxService `json:"services,omitempty" gorm:"serializer:json"`
+	// ports served through the HTTP side of the sandbox proxy, the rest go through its TLS gateway
+	ProxyHTTPPorts []string `json:"proxy_http_ports,omitempty" gorm:"serializer:json"`
+}
+
+// SandboxService is one container of a multi-container challenge. The services of a
+// sandbox share a private network and reach each other by name.
+type SandboxService struct {
+	Name  string   `json:"name"`
+	Image string   `json:"image"`
+	Ports []string `json:"ports,omitempty"` // published to players
+	Env   []string `json:"env,omitempty"`
+	Flag  bool     `json:"flag"` // ./generate and ./reset run in this service
 }
 
 type Hint struct {
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// jsonColumns hold string lists that older databases stored as text[]. The driver
+// cannot scan a postgres array into a []string, so they are kept as json text now.
+var jsonColumns = []struct{ table, column string }{
+	{"challenges", "tags"},
+	{"dynamic_configs", "cap_add"},
+	{"dynamic_configs", "cap_drop"},
+	{"dynamic_configs", "tmpfs"},
+	{"containers", "service_ids"},
+	{"dynamic_configs", "proxy_http_ports"},
+}
+
+// migrateJSONColumns converts the jsonColumns still typed as arrays, keeping their
+// values. It has to run before AutoMigrate, which would cast the arrays to their
+// '{a,b}' text form instead.
+func migrateJSONColumns() error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		for _, c := range jsonColumns {
+			var dataType string
+			if err := tx.Raw("SELECT data_type FROM information_schema.columns WHERE table_schema = CURRENT_SCHEMA() AND table_name = ? AND column_name = ?", c.table, c.column).Scan(&dataType).Error; err != nil {
+				return err
+			}
+			if dataType != "ARRAY" {
+				continue
+			}
+			column := clause.Column{Name: c.column}
+			if err := tx.Exec("ALTER TABLE ? ALTER COLUMN ? TYPE text USING array_to_json(?)::text", clause.Table{Name: c.table}, column, column).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
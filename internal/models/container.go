@@ -1,13 +1,22 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Container struct {
 	gorm.Model
-	TeamID      uint     `json:"team_id" gorm:"index"`
-	ChallengeID uint     `json:"challenge_id" gorm:"index"`
-	ContainerID string   `json:"container_id" gorm:"unique"` // Docker container ID
-	Flag        string   `json:"flag"`
-	Ports       []int    `json:"ports" gorm:"type:integer[]"`
-	Links       []string `json:"links" gorm:"type:text[]"`
+	UserID      uint      `json:"user_id" gorm:"index"`
+	TeamID      uint      `json:"team_id" gorm:"index"`
+	ChallengeID uint      `json:"challenge_id" gorm:"index"`
+	ContainerID string    `json:"container_id" gorm:"unique"`         // Docker container ID
+	ServiceIDs  []string  `json:"service_ids" gorm:"serializer:json"` // every container of a multi-container sandbox
+	NetworkID   string    `json:"network_id"`
+	ProxyToken  string    `json:"proxy_token"`
+	Flag        string    `json:"flag"`
+	Ports       []int     `json:"ports" gorm:"type:integer[]"`
+	Links       []string  `json:"links" gorm:"type:text[]"`
+	ExpiresAt   time.Time `json:"expires_at"`
 }
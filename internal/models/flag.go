@@ -0,0 +1,13 @@
+package models
+
+import "gorm.io/gorm"
+
+// FlagMatcher is one accepted flag of a static challenge
+type FlagMatcher struct {
+	gorm.Model
+	ChallengeID uint   `json:"challenge_id" gorm:"index"`
+	Flag        string `json:"flag"`
+	Mode        string `json:"mode" gorm:"default:exact"` // exact, case-insensitive or regex
+	TrimSpace   bool   `json:"trim_space"`
+	WrapFormat  bool   `json:"wrap_format"` // Flag is the inner part, accepted as <flag-format>{Flag}
+}
@@ -0,0 +1,12 @@
+package models
+
+import "gorm.io/gorm"
+
+type HintPurchase struct {
+	gorm.Model
+	HintID      uint `json:"hint_id" gorm:"column:hint_id;uniqueIndex:idx_hint_team"`
+	TeamID      uint `json:"team_id" gorm:"column:team_id;uniqueIndex:idx_hint_team;index"`
+	UserID      uint `json:"user_id" gorm:"column:user_id;index"`
+	ChallengeID uint `json:"challenge_id" gorm:"column:challenge_id;index"`
+	Points      int  `json:"points" gorm:"column:points"`
+}
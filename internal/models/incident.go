@@ -0,0 +1,15 @@
+package models
+
+import "gorm.io/gorm"
+
+// FlagShareIncident records a team submitting a dynamic flag that belongs to another team
+type FlagShareIncident struct {
+	gorm.Model
+	ChallengeID     uint   `json:"challenge_id" gorm:"column:challenge_id;index"`
+	SubmitterUserID uint   `json:"submitter_user_id" gorm:"column:submitter_user_id;index"`
+	SubmitterTeamID uint   `json:"submitter_team_id" gorm:"column:submitter_team_id;index"`
+	OwnerTeamID     uint   `json:"owner_team_id" gorm:"column:owner_team_id;index"`
+	FlagHash        string `json:"flag_hash" gorm:"column:flag_hash;size:80"`
+	Action          string `json:"action" gorm:"column:action"`
+	IP              string `json:"ip" gorm:"column:ip"`
+}
@@ -54,8 +54,17 @@ func InitDB(cfg *config.Config) {
 	if err != nil {
 		logrus.Fatalf("Failed to connect to database after %d attempts: %v", maxRetries, err)
 	}
-	if err := DB.AutoMigrate(&Challenge{}, &Container{}, &Solve{}); err != nil {
+	if err := migrateJSONColumns(); err != nil {
+		logrus.Fatalf("Failed to convert array columns: %v", err)
+	}
+	if err := DB.AutoMigrate(&Category{}, &Difficulty{}, &Challenge{}, &Container{}, &Solve{}, &HintPurchase{}, &Announcement{}, &Submission{}, &FlagShareIncident{}, &FlagMatcher{}, &ChallengePrerequisite{}, &Attachment{}); err != nil {
 		logrus.Fatalf("Failed to migrate database: %v", err)
 	}
+	if err := migrateLegacyLabels(); err != nil {
+		logrus.Fatalf("Failed to migrate challenge categories and difficulties: %v", err)
+	}
+	if err := purgeDeletedLabels(); err != nil {
+		logrus.Fatalf("Failed to purge deleted categories and difficulties: %v", err)
+	}
 	logrus.Println("Database initialized successfully")
 }
@@ -0,0 +1,14 @@
+package models
+
+import "gorm.io/gorm"
+
+// ChallengePrerequisite keeps a challenge locked for a team until it is met. Each row
+// is either a challenge to solve first (RequiresID) or a number of points to reach in
+// a category (CategoryID and MinPoints). A challenge unlocks once all its rows are met.
+type ChallengePrerequisite struct {
+	gorm.Model
+	ChallengeID uint  `json:"challenge_id" gorm:"index"`
+	RequiresID  *uint `json:"requires_id,omitempty" gorm:"index"`
+	CategoryID  *uint `json:"category_id,omitempty"`
+	MinPoints   int   `json:"min_points,omitempty"`
+}
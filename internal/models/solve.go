@@ -9,4 +9,7 @@ type Solve struct {
 	UserID        uint `json:"user_id" gorm:"column:user_id;index"`
 	ChallengeType int8 `json:"challenge_type" gorm:"column:challenge_type"`
 	BloodCount    uint `json:"blood_type" gorm:"column:blood_count"`
+	// Points is what the challenge was worth when it was solved, 0 on solves recorded
+	// before it was kept
+	Points int `json:"points" gorm:"column:points"`
 }
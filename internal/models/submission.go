@@ -0,0 +1,14 @@
+package models
+
+import "gorm.io/gorm"
+
+// Submission is an audit record of every evaluated flag, right or wrong
+type Submission struct {
+	gorm.Model
+	UserID      uint   `json:"user_id" gorm:"column:user_id;index"`
+	TeamID      uint   `json:"team_id" gorm:"column:team_id;index"`
+	ChallengeID uint   `json:"challenge_id" gorm:"column:challenge_id;index"`
+	FlagHash    string `json:"flag_hash" gorm:"column:flag_hash;size:80;index"`
+	Correct     bool   `json:"correct" gorm:"column:correct"`
+	IP          string `json:"ip" gorm:"column:ip"`
+}
@@ -0,0 +1,133 @@
+package notification
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const bufferFile = "pending.jsonl"
+
+// diskBuffer keeps undelivered messages as JSON lines so they survive broker outages and restarts
+type diskBuffer struct {
+	path  string
+	max   int
+	count int
+	mu    sync.Mutex
+}
+
+func openDiskBuffer(dir string, max int) (*diskBuffer, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	b := &diskBuffer{path: filepath.Join(dir, bufferFile), max: max}
+	pending, err := b.read()
+	if err != nil {
+		return nil, err
+	}
+	b.count = len(pending)
+	return b, nil
+}
+
+func (b *diskBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.count
+}
+
+func (b *diskBuffer) Load() ([][]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.read()
+}
+
+// Append adds messages to the end of the buffer, dropping the oldest past max
+func (b *diskBuffer) Append(messages [][]byte) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.max > 0 && b.count+len(messages) > b.max {
+		pending, err := b.read()
+		if err != nil {
+			return err
+		}
+		pending = append(pending, messages...)
+		return b.write(pending[len(pending)-b.max:])
+	}
+	f, err := os.OpenFile(b.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o640)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for _, msg := range messages {
+		w.Write(msg)
+		w.WriteByte('\n')
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	b.count += len(messages)
+	return f.Close()
+}
+
+// Ack removes the n oldest messages once they have been delivered
+func (b *diskBuffer) Ack(n int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	pending, err := b.read()
+	if err != nil {
+		return err
+	}
+	return b.write(pending[min(n, len(pending)):])
+}
+
+func (b *diskBuffer) read() ([][]byte, error) {
+	data, err := os.ReadFile(b.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var messages [][]byte
+	for _, line := range bytes.Split(data, []byte{'\n'}) {
+		if len(bytes.TrimSpace(line)) > 0 {
+			messages = append(messages, line)
+		}
+	}
+	return messages, nil
+}
+
+func (b *diskBuffer) write(messages [][]byte) error {
+	if len(messages) == 0 {
+		b.count = 0
+		if err := os.Remove(b.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	tmp := b.path + ".tmp"
+	var buf bytes.Buffer
+	for _, msg := range messages {
+		buf.Write(msg)
+		buf.WriteByte('\n')
+	}
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o640); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, b.path); err != nil {
+		return err
+	}
+	b.count = len(messages)
+	return nil
+}
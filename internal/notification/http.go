@@ -2,38 +2,38 @@ package notification
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"time"
 
-	"github.com/intraware/rodan/internal/config"
+	"github.com/intraware/rodan/internal/utils/values"
 )
 
-type notifPayload struct {
-	Message string `json:"message"`
-}
+type httpNotifier struct{}
 
-func httpNotif(message string, cfg config.NotificationConfig) error {
+func (h *httpNotifier) Notify(ctx context.Context, payload Payload) error {
+	cfg := values.GetConfig().App.Notification
 	if cfg.HTTP == nil {
 		return fmt.Errorf("HTTP notification config is nil")
 	}
 	url := cfg.HTTP.URL
 	endpoint := cfg.HTTP.Endpoint
 	apiKey := cfg.HTTP.HashedAPIKey
-	body, err := json.Marshal(notifPayload{Message: message})
+	body, err := json.Marshal(payload)
 	if err != nil {
 		return fmt.Errorf("failed to marshal payload: %w", err)
 	}
 	retries := cfg.DefaultRetry
+	client := &http.Client{Timeout: cfg.Timeout}
 	for i := range retries {
-		req, err := http.NewRequest("POST", url+endpoint, bytes.NewBuffer(body))
+		req, err := http.NewRequestWithContext(ctx, "POST", url+endpoint, bytes.NewBuffer(body))
 		if err != nil {
 			return fmt.Errorf("failed to create request: %w", err)
 		}
 		req.Header.Set("Content-Type", "application/json")
 		req.Header.Set("x-api-key", apiKey)
-		client := &http.Client{Timeout: cfg.Timeout}
 		resp, err := client.Do(req)
 		if err != nil {
 			if i < retries {
@@ -42,7 +42,7 @@ func httpNotif(message string, cfg config.NotificationConfig) error {
 			}
 			return fmt.Errorf("failed to send notification: %w", err)
 		}
-		defer resp.Body.Close()
+		resp.Body.Close()
 		if resp.StatusCode >= 300 {
 			if i < retries {
 				time.Sleep(cfg.RetryDelay)
@@ -54,3 +54,7 @@ func httpNotif(message string, cfg config.NotificationConfig) error {
 	}
 	return fmt.Errorf("notification failed after %d retries", retries)
 }
+
+func (h *httpNotifier) Close() error {
+	return nil
+}
@@ -0,0 +1,53 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/intraware/rodan/internal/config"
+	"github.com/segmentio/kafka-go"
+)
+
+type kafkaBroker struct {
+	writer *kafka.Writer
+}
+
+func newKafkaBroker(cfg *config.KafkaNotificationConfig, timeout time.Duration) *kafkaBroker {
+	return &kafkaBroker{writer: &kafka.Writer{
+		Addr:         kafka.TCP(cfg.Brokers...),
+		Topic:        cfg.Topic,
+		Balancer:     &kafka.LeastBytes{},
+		RequiredAcks: kafka.RequireAll,
+		MaxAttempts:  3,
+		// batching already happens in the queue, flush straight away
+		BatchTimeout: 10 * time.Millisecond,
+		WriteTimeout: timeout,
+	}}
+}
+
+func (k *kafkaBroker) Publish(ctx context.Context, messages [][]byte) error {
+	msgs := make([]kafka.Message, len(messages))
+	for i, msg := range messages {
+		msgs[i] = kafka.Message{Value: msg}
+	}
+	return k.writer.WriteMessages(ctx, msgs...)
+}
+
+func (k *kafkaBroker) Close() error {
+	return k.writer.Close()
+}
+
+func newKafkaNotifier(cfg config.NotificationConfig) (Notifier, error) {
+	if cfg.Kafka == nil {
+		return nil, errors.New("Kafka notification config is nil")
+	}
+	return NewQueueNotifier(newKafkaBroker(cfg.Kafka, cfg.Timeout), QueueOptions{
+		BatchSize:    cfg.Kafka.BatchSize,
+		BatchTimeout: cfg.Kafka.BatchTimeout,
+		RetryDelay:   cfg.RetryDelay,
+		SendTimeout:  cfg.Timeout,
+		BufferDir:    cfg.Kafka.BufferDir,
+		MaxBuffered:  cfg.Kafka.MaxBuffered,
+	})
+}
@@ -1,21 +1,104 @@
 package notification
 
 import (
+	"context"
 	"errors"
+	"reflect"
+	"sync"
+	"time"
 
+	"github.com/intraware/rodan/internal/config"
 	"github.com/intraware/rodan/internal/utils/values"
 )
 
-func SendNotification(message string) error {
+// Payload is the JSON body every delivery backend sends
+type Payload struct {
+	Type    string    `json:"type"`
+	Message string    `json:"message"`
+	Data    any       `json:"data,omitempty"`
+	SentAt  time.Time `json:"sent_at"`
+}
+
+// Notifier delivers payloads to an external service
+type Notifier interface {
+	Notify(ctx context.Context, payload Payload) error
+	Close() error
+}
+
+var (
+	notifierLock   sync.Mutex
+	activeNotifier Notifier
+	activeConfig   config.NotificationConfig
+)
+
+// getNotifier builds the notifier for the configured delivery method and swaps it
+// out when a config reload changes anything it was built from
+func getNotifier(cfg config.NotificationConfig) (Notifier, error) {
+	notifierLock.Lock()
+	defer notifierLock.Unlock()
+	if activeNotifier != nil && builtFrom(activeConfig, cfg) {
+		return activeNotifier, nil
+	}
+	var notifier Notifier
+	switch cfg.DeliveryMethod {
+	case "http":
+		notifier = &httpNotifier{}
+	case "kafka":
+		var err error
+		if notifier, err = newKafkaNotifier(cfg); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("Invalid delivery method")
+	}
+	if activeNotifier != nil {
+		activeNotifier.Close()
+	}
+	activeNotifier = notifier
+	activeConfig = cfg
+	return notifier, nil
+}
+
+// builtFrom reports whether a notifier built from old still fits cfg. The http
+// notifier reads its settings on every send, the kafka one keeps them.
+func builtFrom(old, cfg config.NotificationConfig) bool {
+	if old.DeliveryMethod != cfg.DeliveryMethod {
+		return false
+	}
+	if cfg.DeliveryMethod != "kafka" {
+		return true
+	}
+	return old.Timeout == cfg.Timeout && old.RetryDelay == cfg.RetryDelay && reflect.DeepEqual(old.Kafka, cfg.Kafka)
+}
+
+func Send(payload Payload) error {
 	cfg := values.GetConfig().App.Notification
 	if !cfg.Enabled {
 		return errors.New("Notification is not enabled")
 	}
-	if cfg.DeliveryMethod == "http" {
-		return httpNotif(message, cfg)
-	} else if cfg.DeliveryMethod == "kafka" {
-		return errors.New("Kafka is not implemented")
-	} else {
-		return errors.New("Invalid delivery method")
+	notifier, err := getNotifier(cfg)
+	if err != nil {
+		return err
+	}
+	if payload.SentAt.IsZero() {
+		payload.SentAt = time.Now()
+	}
+	return notifier.Notify(context.Background(), payload)
+}
+
+func SendNotification(message string) error {
+	return Send(Payload{Type: "message", Message: message})
+}
+
+// Close flushes and releases the active notifier
+func Close() error {
+	notifierLock.Lock()
+	defer notifierLock.Unlock()
+	if activeNotifier == nil {
+		return nil
 	}
+	err := activeNotifier.Close()
+	activeNotifier = nil
+	activeConfig = config.NotificationConfig{}
+	return err
 }
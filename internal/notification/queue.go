@@ -0,0 +1,221 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultBatchSize    = 100
+	defaultBatchTimeout = time.Second
+	defaultRetryDelay   = 5 * time.Second
+	defaultSendTimeout  = 10 * time.Second
+	// how long Notify waits for room in the queue before buffering to disk instead
+	defaultEnqueueTimeout = 100 * time.Millisecond
+)
+
+var errQueueClosed = errors.New("notification queue is closed")
+
+// Broker is a message queue a QueueNotifier can deliver batches to
+type Broker interface {
+	Publish(ctx context.Context, messages [][]byte) error
+	Close() error
+}
+
+type QueueOptions struct {
+	BatchSize      int
+	BatchTimeout   time.Duration
+	RetryDelay     time.Duration
+	SendTimeout    time.Duration
+	EnqueueTimeout time.Duration
+	BufferDir      string
+	MaxBuffered    int
+}
+
+// QueueNotifier batches payloads for a Broker. Batches that cannot be delivered
+// are written to disk and resent, oldest first, once the broker is reachable again.
+type QueueNotifier struct {
+	broker   Broker
+	opts     QueueOptions
+	buffer   *diskBuffer
+	incoming chan []byte
+	done     chan struct{}
+	stopped  chan struct{}
+	mu       sync.RWMutex
+	closed   bool
+}
+
+func NewQueueNotifier(broker Broker, opts QueueOptions) (*QueueNotifier, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultBatchSize
+	}
+	if opts.BatchTimeout <= 0 {
+		opts.BatchTimeout = defaultBatchTimeout
+	}
+	if opts.RetryDelay <= 0 {
+		opts.RetryDelay = defaultRetryDelay
+	}
+	if opts.SendTimeout <= 0 {
+		opts.SendTimeout = defaultSendTimeout
+	}
+	if opts.EnqueueTimeout <= 0 {
+		opts.EnqueueTimeout = defaultEnqueueTimeout
+	}
+	buffer, err := openDiskBuffer(opts.BufferDir, opts.MaxBuffered)
+	if err != nil {
+		return nil, err
+	}
+	q := &QueueNotifier{
+		broker:   broker,
+		opts:     opts,
+		buffer:   buffer,
+		incoming: make(chan []byte, opts.BatchSize),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	go q.run()
+	return q, nil
+}
+
+func (q *QueueNotifier) Notify(ctx context.Context, payload Payload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		return errQueueClosed
+	}
+	wait := time.NewTimer(q.opts.EnqueueTimeout)
+	defer wait.Stop()
+	select {
+	case q.incoming <- body:
+		return nil
+	case <-ctx.Done():
+	case <-wait.C:
+	}
+	// the queue is full while the broker is down, keep it for the retry loop rather
+	// than losing it or holding up the caller
+	return q.buffer.Append([][]byte{body})
+}
+
+// Close flushes pending payloads, buffering them if the broker is down
+func (q *QueueNotifier) Close() error {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return nil
+	}
+	q.closed = true
+	close(q.done)
+	q.mu.Unlock()
+	<-q.stopped
+	return q.broker.Close()
+}
+
+func (q *QueueNotifier) run() {
+	defer close(q.stopped)
+	var batch [][]byte
+	linger := time.NewTimer(q.opts.BatchTimeout)
+	linger.Stop()
+	defer linger.Stop()
+	retry := time.NewTicker(q.opts.RetryDelay)
+	defer retry.Stop()
+	// pick up whatever a previous run left behind
+	q.retryBuffered()
+	for {
+		select {
+		case msg := <-q.incoming:
+			batch = append(batch, msg)
+			if len(batch) == 1 {
+				linger.Reset(q.opts.BatchTimeout)
+			}
+			if len(batch) >= q.opts.BatchSize {
+				linger.Stop()
+				q.flush(batch)
+				batch = nil
+			}
+		case <-linger.C:
+			q.flush(batch)
+			batch = nil
+		case <-retry.C:
+			q.retryBuffered()
+		case <-q.done:
+		drain:
+			for {
+				select {
+				case msg := <-q.incoming:
+					batch = append(batch, msg)
+					if len(batch) >= q.opts.BatchSize {
+						q.flush(batch)
+						batch = nil
+					}
+				default:
+					break drain
+				}
+			}
+			if len(batch) == 0 {
+				q.retryBuffered()
+			} else {
+				q.flush(batch)
+			}
+			return
+		}
+	}
+}
+
+func (q *QueueNotifier) flush(batch [][]byte) {
+	if len(batch) == 0 {
+		return
+	}
+	// anything already buffered goes out first to keep ordering
+	if q.retryBuffered() {
+		err := q.publish(batch)
+		if err == nil {
+			return
+		}
+		logrus.Warnf("[notification] broker unavailable, buffering %d events: %v", len(batch), err)
+	}
+	if err := q.buffer.Append(batch); err != nil {
+		logrus.Errorf("[notification] failed to buffer %d events: %v", len(batch), err)
+	}
+}
+
+// retryBuffered resends buffered messages and reports whether the buffer is now empty
+func (q *QueueNotifier) retryBuffered() bool {
+	if q.buffer.Len() == 0 {
+		return true
+	}
+	pending, err := q.buffer.Load()
+	if err != nil {
+		logrus.Errorf("[notification] failed to read buffered events: %v", err)
+		return false
+	}
+	sent := 0
+	for sent < len(pending) {
+		end := min(sent+q.opts.BatchSize, len(pending))
+		if err := q.publish(pending[sent:end]); err != nil {
+			break
+		}
+		sent = end
+	}
+	if sent == 0 {
+		return false
+	}
+	if err := q.buffer.Ack(sent); err != nil {
+		logrus.Errorf("[notification] failed to update buffered events: %v", err)
+	}
+	return sent == len(pending)
+}
+
+func (q *QueueNotifier) publish(batch [][]byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), q.opts.SendTimeout)
+	defer cancel()
+	return q.broker.Publish(ctx, batch)
+}
@@ -0,0 +1,172 @@
+package notification_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/intraware/rodan/internal/notification"
+)
+
+// standInBroker records delivered batches and can simulate an outage
+type standInBroker struct {
+	mu      sync.Mutex
+	down    bool
+	batches [][]string
+}
+
+func (b *standInBroker) Publish(ctx context.Context, messages [][]byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.down {
+		return errors.New("broker unavailable")
+	}
+	batch := make([]string, 0, len(messages))
+	for _, msg := range messages {
+		var payload notification.Payload
+		if err := json.Unmarshal(msg, &payload); err != nil {
+			return err
+		}
+		batch = append(batch, payload.Message)
+	}
+	b.batches = append(b.batches, batch)
+	return nil
+}
+
+func (b *standInBroker) Close() error { return nil }
+
+func (b *standInBroker) setDown(down bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.down = down
+}
+
+func (b *standInBroker) delivered() (messages []string, batches int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, batch := range b.batches {
+		messages = append(messages, batch...)
+	}
+	return messages, len(b.batches)
+}
+
+func newQueue(t *testing.T, broker notification.Broker, dir string, opts notification.QueueOptions) *notification.QueueNotifier {
+	t.Helper()
+	opts.BufferDir = dir
+	q, err := notification.NewQueueNotifier(broker, opts)
+	if err != nil {
+		t.Fatalf("NewQueueNotifier failed: %v", err)
+	}
+	return q
+}
+
+func notifyN(t *testing.T, q *notification.QueueNotifier, from, to int) {
+	t.Helper()
+	for i := from; i < to; i++ {
+		if err := q.Notify(context.Background(), notification.Payload{Type: "solve", Message: fmt.Sprint(i)}); err != nil {
+			t.Fatalf("Notify failed: %v", err)
+		}
+	}
+}
+
+func expectMessages(t *testing.T, got []string, want ...int) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("expected %d messages, got %d: %v", len(want), len(got), got)
+	}
+	for i, w := range want {
+		if got[i] != fmt.Sprint(w) {
+			t.Fatalf("message %d: expected %d, got %s (all: %v)", i, w, got[i], got)
+		}
+	}
+}
+
+func TestQueueNotifierBatches(t *testing.T) {
+	broker := &standInBroker{}
+	q := newQueue(t, broker, t.TempDir(), notification.QueueOptions{BatchSize: 3, BatchTimeout: time.Hour})
+	notifyN(t, q, 0, 7)
+	if err := q.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	got, batches := broker.delivered()
+	expectMessages(t, got, 0, 1, 2, 3, 4, 5, 6)
+	if batches != 3 {
+		t.Fatalf("expected 3 batches, got %d", batches)
+	}
+}
+
+func TestQueueNotifierFlushesAfterTimeout(t *testing.T) {
+	broker := &standInBroker{}
+	q := newQueue(t, broker, t.TempDir(), notification.QueueOptions{BatchSize: 100, BatchTimeout: 20 * time.Millisecond})
+	defer q.Close()
+	notifyN(t, q, 0, 2)
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if got, _ := broker.delivered(); len(got) == 2 {
+			expectMessages(t, got, 0, 1)
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("batch was not flushed after the batch timeout")
+}
+
+func TestQueueNotifierRetriesAfterOutage(t *testing.T) {
+	broker := &standInBroker{down: true}
+	q := newQueue(t, broker, t.TempDir(), notification.QueueOptions{
+		BatchSize:    2,
+		BatchTimeout: 10 * time.Millisecond,
+		RetryDelay:   20 * time.Millisecond,
+	})
+	defer q.Close()
+	notifyN(t, q, 0, 3)
+	time.Sleep(100 * time.Millisecond)
+	if got, _ := broker.delivered(); len(got) != 0 {
+		t.Fatalf("expected nothing delivered during outage, got %v", got)
+	}
+	broker.setDown(false)
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if got, _ := broker.delivered(); len(got) == 3 {
+			expectMessages(t, got, 0, 1, 2)
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	got, _ := broker.delivered()
+	t.Fatalf("buffered events were not resent, got %v", got)
+}
+
+func TestQueueNotifierBufferSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	down := &standInBroker{down: true}
+	q := newQueue(t, down, dir, notification.QueueOptions{BatchSize: 10, BatchTimeout: time.Hour, RetryDelay: time.Hour})
+	notifyN(t, q, 0, 4)
+	q.Close()
+
+	up := &standInBroker{}
+	q = newQueue(t, up, dir, notification.QueueOptions{BatchSize: 10, BatchTimeout: time.Hour, RetryDelay: time.Hour})
+	notifyN(t, q, 4, 6)
+	q.Close()
+	got, _ := up.delivered()
+	expectMessages(t, got, 0, 1, 2, 3, 4, 5)
+}
+
+func TestQueueNotifierDropsOldestPastMaxBuffered(t *testing.T) {
+	dir := t.TempDir()
+	down := &standInBroker{down: true}
+	opts := notification.QueueOptions{BatchSize: 1, BatchTimeout: time.Hour, RetryDelay: time.Hour, MaxBuffered: 2}
+	q := newQueue(t, down, dir, opts)
+	notifyN(t, q, 0, 5)
+	q.Close()
+
+	up := &standInBroker{}
+	q = newQueue(t, up, dir, opts)
+	q.Close()
+	got, _ := up.delivered()
+	expectMessages(t, got, 3, 4)
+}
@@ -0,0 +1,73 @@
+package notification
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/intraware/rodan/internal/config"
+	"github.com/intraware/rodan/internal/utils/values"
+)
+
+// stuckBroker never answers until released, like a broker that accepts the connection
+// and then hangs
+type stuckBroker struct {
+	release chan struct{}
+}
+
+func (b *stuckBroker) Publish(ctx context.Context, messages [][]byte) error {
+	<-b.release
+	return context.DeadlineExceeded
+}
+
+func (b *stuckBroker) Close() error { return nil }
+
+func TestSendDoesNotBlockWhenBrokerIsStuck(t *testing.T) {
+	var cfg config.Config
+	cfg.App.Notification.Enabled = true
+	cfg.App.Notification.DeliveryMethod = "kafka"
+	values.SetConfig(&cfg)
+
+	broker := &stuckBroker{release: make(chan struct{})}
+	q, err := NewQueueNotifier(broker, QueueOptions{
+		BatchSize:      1,
+		BatchTimeout:   time.Hour,
+		RetryDelay:     time.Hour,
+		EnqueueTimeout: 10 * time.Millisecond,
+		BufferDir:      t.TempDir(),
+	})
+	if err != nil {
+		t.Fatalf("NewQueueNotifier failed: %v", err)
+	}
+	notifierLock.Lock()
+	activeNotifier, activeConfig = q, cfg.App.Notification
+	notifierLock.Unlock()
+	defer func() {
+		close(broker.release)
+		Close()
+	}()
+
+	// the first message keeps the run loop stuck in Publish, the next fills the queue
+	const sends = 20
+	done := make(chan error, 1)
+	go func() {
+		for range sends {
+			if err := Send(Payload{Type: "solve", Message: "message"}); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Send blocked on a full queue with the broker down")
+	}
+	if buffered := q.buffer.Len(); buffered < sends-2 {
+		t.Fatalf("expected the overflow to be buffered, got %d of %d", buffered, sends)
+	}
+}
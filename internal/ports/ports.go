@@ -0,0 +1,239 @@
+package ports
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/intraware/rodan/internal/cache"
+	"github.com/intraware/rodan/internal/config"
+	"github.com/intraware/rodan/internal/utils/values"
+	"github.com/sirupsen/logrus"
+)
+
+var ErrExhausted = errors.New("no free host ports left in the port range")
+
+const defaultLeaseTTL = 10 * time.Minute
+
+// Allocator hands out host ports from a fixed range. Leases are tracked in memory and,
+// when several instances share one docker host through redis, claimed there as well
+// so two instances never pick the same port.
+type Allocator struct {
+	mu       sync.Mutex
+	start    int
+	end      int
+	next     int
+	owners   map[int]string
+	leases   Leases
+	leaseTTL time.Duration
+}
+
+// Leases is where instances sharing a docker host record which of them holds a port.
+// An owner is a container name, docker keeps those unique per host.
+type Leases interface {
+	Claim(key, owner string, ttl time.Duration) (bool, error)
+	Refresh(key, owner string, ttl time.Duration) (bool, error)
+	Unclaim(key, owner string) error
+}
+
+// redisLeases records leases through the shared redis
+type redisLeases struct{}
+
+func (redisLeases) Claim(key, owner string, ttl time.Duration) (bool, error) {
+	return cache.Claim(key, owner, ttl)
+}
+
+func (redisLeases) Refresh(key, owner string, ttl time.Duration) (bool, error) {
+	return cache.Refresh(key, owner, ttl)
+}
+
+func (redisLeases) Unclaim(key, owner string) error {
+	return cache.Unclaim(key, owner)
+}
+
+// New returns an allocator for the ports start to end. Without leases the allocator
+// assumes it is the only one on the docker host.
+func New(start, end int, leases Leases, leaseTTL time.Duration) *Allocator {
+	if leaseTTL <= 0 {
+		leaseTTL = defaultLeaseTTL
+	}
+	a := &Allocator{
+		start:    start,
+		end:      end,
+		next:     start,
+		owners:   make(map[int]string),
+		leases:   leases,
+		leaseTTL: leaseTTL,
+	}
+	if leases != nil {
+		go a.keepAlive()
+	}
+	return a
+}
+
+// Shared reports whether other instances may lease from the same range
+func (a *Allocator) Shared() bool {
+	return a.leases != nil
+}
+
+func leaseKey(port int) string {
+	return fmt.Sprintf("port-lease_%d", port)
+}
+
+// Lease takes n free ports for owner. Ports are tried in order from where the last
+// lease stopped, so a full pass over the range is the most it ever does.
+func (a *Allocator) Lease(owner string, n int) ([]int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	size := a.end - a.start + 1
+	leased := make([]int, 0, n)
+	for tried := 0; tried < size && len(leased) < n; tried++ {
+		port := a.next
+		a.next++
+		if a.next > a.end {
+			a.next = a.start
+		}
+		if _, taken := a.owners[port]; taken {
+			continue
+		}
+		if a.leases != nil {
+			claimed, err := a.leases.Claim(leaseKey(port), owner, a.leaseTTL)
+			if err != nil {
+				a.release(leased)
+				return nil, err
+			}
+			if !claimed {
+				continue
+			}
+		}
+		a.owners[port] = owner
+		leased = append(leased, port)
+	}
+	if len(leased) < n {
+		a.release(leased)
+		return nil, ErrExhausted
+	}
+	return leased, nil
+}
+
+// Reserve marks ports docker already has bound when the allocator starts as in use.
+// They are taken whatever redis says, a conflicting lease is only reported. owner has
+// to be the name the container was leased for, so leases from before a restart are
+// renewed instead of reported.
+func (a *Allocator) Reserve(owner string, ports ...int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, port := range ports {
+		if port < a.start || port > a.end {
+			continue
+		}
+		a.owners[port] = owner
+		if a.leases != nil {
+			key := leaseKey(port)
+			held, err := a.leases.Claim(key, owner, a.leaseTTL)
+			if err == nil && !held {
+				// still ours from before a restart
+				held, err = a.leases.Refresh(key, owner, a.leaseTTL)
+			}
+			reportLease(port, held, err)
+		}
+	}
+}
+
+func (a *Allocator) Release(ports ...int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.release(ports)
+}
+
+func (a *Allocator) release(ports []int) {
+	for _, port := range ports {
+		owner, ok := a.owners[port]
+		if !ok {
+			continue
+		}
+		delete(a.owners, port)
+		if a.leases != nil {
+			if err := a.leases.Unclaim(leaseKey(port), owner); err != nil {
+				logrus.Errorf("Failed to release lease of port %d: %v", port, err)
+			}
+		}
+	}
+}
+
+// InUse is the number of ports currently leased by this instance
+func (a *Allocator) InUse() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.owners)
+}
+
+// keepAlive renews the redis leases of this instance, leases of a crashed instance
+// run out after leaseTTL instead of staying taken forever. The leases are copied so
+// Lease and Release are not held up by the round trips to redis.
+func (a *Allocator) keepAlive() {
+	for {
+		time.Sleep(a.leaseTTL / 3)
+		a.mu.Lock()
+		owned := maps.Clone(a.owners)
+		a.mu.Unlock()
+		for port, owner := range owned {
+			key := leaseKey(port)
+			held, err := a.leases.Refresh(key, owner, a.leaseTTL)
+			if err == nil && !held {
+				if !a.owns(port, owner) {
+					// released while renewing, nothing to keep
+					continue
+				}
+				// ran out while redis was unreachable, take it back unless someone else has
+				held, err = a.leases.Claim(key, owner, a.leaseTTL)
+			}
+			reportLease(port, held, err)
+		}
+	}
+}
+
+func (a *Allocator) owns(port int, owner string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.owners[port] == owner
+}
+
+// reportLease logs a lease that could not be recorded. One held by another instance
+// is only reported, the port is bound by a container of this one and cannot be
+// handed over.
+func reportLease(port int, held bool, err error) {
+	if err != nil {
+		logrus.Errorf("Failed to record lease of port %d: %v", port, err)
+	} else if !held {
+		logrus.Warnf("Port %d is leased by another instance as well", port)
+	}
+}
+
+var (
+	defaultLock      sync.Mutex
+	defaultAllocator *Allocator
+)
+
+// Default is the allocator for the configured port range. The range is read once,
+// changing it needs a restart.
+func Default() *Allocator {
+	defaultLock.Lock()
+	defer defaultLock.Unlock()
+	if defaultAllocator == nil {
+		cfg := values.GetConfig()
+		var leases Leases
+		if sharedLeases(cfg.App.AppCache) {
+			leases = redisLeases{}
+		}
+		defaultAllocator = New(cfg.Docker.PortRange.Start, cfg.Docker.PortRange.End, leases, cfg.Docker.PortLeaseTTL)
+	}
+	return defaultAllocator
+}
+
+// sharedLeases mirrors cache.NewCache, leases go to redis whenever the caches do
+func sharedLeases(cfg config.CacheConfig) bool {
+	return !cfg.InApp && cfg.ServiceType == "redis"
+}
@@ -0,0 +1,169 @@
+package ports_test
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/intraware/rodan/internal/ports"
+)
+
+func TestLease(t *testing.T) {
+	a := ports.New(40000, 40004, nil, 0)
+	first, err := a.Lease("a", 2)
+	if err != nil {
+		t.Fatalf("Lease failed: %v", err)
+	}
+	second, err := a.Lease("b", 2)
+	if err != nil {
+		t.Fatalf("Lease failed: %v", err)
+	}
+	if !slices.Equal(first, []int{40000, 40001}) || !slices.Equal(second, []int{40002, 40003}) {
+		t.Fatalf("expected consecutive ports, got %v and %v", first, second)
+	}
+	if a.InUse() != 4 {
+		t.Fatalf("expected 4 ports in use, got %d", a.InUse())
+	}
+}
+
+func TestLeaseExhausted(t *testing.T) {
+	a := ports.New(40000, 40002, nil, 0)
+	if _, err := a.Lease("a", 2); err != nil {
+		t.Fatalf("Lease failed: %v", err)
+	}
+	if _, err := a.Lease("b", 2); !errors.Is(err, ports.ErrExhausted) {
+		t.Fatalf("expected ErrExhausted, got %v", err)
+	}
+	// a lease that cannot be filled keeps nothing
+	if a.InUse() != 2 {
+		t.Fatalf("expected the failed lease to hand its port back, got %d in use", a.InUse())
+	}
+	if got, err := a.Lease("c", 1); err != nil || !slices.Equal(got, []int{40002}) {
+		t.Fatalf("expected the last port to be free, got %v, %v", got, err)
+	}
+	if _, err := a.Lease("d", 1); !errors.Is(err, ports.ErrExhausted) {
+		t.Fatalf("expected ErrExhausted on a full range, got %v", err)
+	}
+}
+
+func TestRelease(t *testing.T) {
+	a := ports.New(40000, 40002, nil, 0)
+	leased, err := a.Lease("a", 3)
+	if err != nil {
+		t.Fatalf("Lease failed: %v", err)
+	}
+	a.Release(leased[1])
+	// releasing twice or outside the range is harmless
+	a.Release(leased[1], 50000)
+	if a.InUse() != 2 {
+		t.Fatalf("expected 2 ports in use, got %d", a.InUse())
+	}
+	got, err := a.Lease("b", 1)
+	if err != nil || !slices.Equal(got, []int{leased[1]}) {
+		t.Fatalf("expected the released port to be leased again, got %v, %v", got, err)
+	}
+}
+
+func TestLeaseWrapsAround(t *testing.T) {
+	a := ports.New(40000, 40003, nil, 0)
+	leased, err := a.Lease("a", 3)
+	if err != nil {
+		t.Fatalf("Lease failed: %v", err)
+	}
+	a.Release(leased[0])
+	got, err := a.Lease("b", 2)
+	if err != nil || !slices.Equal(got, []int{40003, 40000}) {
+		t.Fatalf("expected the lease to continue at the end and wrap to the start, got %v, %v", got, err)
+	}
+}
+
+func TestReserve(t *testing.T) {
+	a := ports.New(40000, 40002, nil, 0)
+	a.Reserve("docker", 40001, 39999, 40003)
+	if a.InUse() != 1 {
+		t.Fatalf("expected only the port in range to be reserved, got %d", a.InUse())
+	}
+	got, err := a.Lease("a", 2)
+	if err != nil || !slices.Equal(got, []int{40000, 40002}) {
+		t.Fatalf("expected the reserved port to be skipped, got %v, %v", got, err)
+	}
+}
+
+// memoryLeases stands in for redis, it keeps who holds which key
+type memoryLeases struct {
+	mu     sync.Mutex
+	owners map[string]string
+}
+
+func (m *memoryLeases) Claim(key, owner string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, held := m.owners[key]; held {
+		return false, nil
+	}
+	m.owners[key] = owner
+	return true, nil
+}
+
+func (m *memoryLeases) Refresh(key, owner string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.owners[key] == owner, nil
+}
+
+func (m *memoryLeases) Unclaim(key, owner string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.owners[key] == owner {
+		delete(m.owners, key)
+	}
+	return nil
+}
+
+func (m *memoryLeases) owner(port int) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.owners[fmt.Sprintf("port-lease_%d", port)]
+}
+
+func TestReserveAndReleaseShared(t *testing.T) {
+	leases := &memoryLeases{owners: map[string]string{
+		// leased by this instance before a restart
+		"port-lease_40000": "sandbox-1",
+		// leased by another instance
+		"port-lease_40002": "sandbox-2",
+	}}
+	a := ports.New(40000, 40003, leases, time.Hour)
+	a.Reserve("sandbox-1", 40000, 40001)
+	if a.InUse() != 2 {
+		t.Fatalf("expected both ports to be reserved, got %d in use", a.InUse())
+	}
+	if leases.owner(40000) != "sandbox-1" || leases.owner(40001) != "sandbox-1" {
+		t.Fatalf("expected both leases to be held by sandbox-1, got %q and %q", leases.owner(40000), leases.owner(40001))
+	}
+	// the port of the other instance is skipped
+	got, err := a.Lease("sandbox-3", 1)
+	if err != nil || !slices.Equal(got, []int{40003}) {
+		t.Fatalf("expected the one unclaimed port, got %v, %v", got, err)
+	}
+
+	a.Release(40000, 40001)
+	if leases.owner(40000) != "" || leases.owner(40001) != "" {
+		t.Fatal("expected releasing to give up the leases")
+	}
+	got, err = a.Lease("sandbox-4", 2)
+	if err != nil || !slices.Equal(got, []int{40000, 40001}) {
+		t.Fatalf("expected the released ports to be leased again, got %v, %v", got, err)
+	}
+	// a lease that moved to someone else is left alone on release
+	leases.mu.Lock()
+	leases.owners["port-lease_40000"] = "sandbox-5"
+	leases.mu.Unlock()
+	a.Release(40000)
+	if leases.owner(40000) != "sandbox-5" {
+		t.Fatalf("expected the lease of sandbox-5 to survive, got %q", leases.owner(40000))
+	}
+}
@@ -0,0 +1,47 @@
+package proxy
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type targetKey struct{}
+
+var reverseProxy = &httputil.ReverseProxy{
+	Rewrite: func(r *httputil.ProxyRequest) {
+		r.Out.URL.Scheme = "http"
+		r.Out.URL.Host = r.In.Context().Value(targetKey{}).(string)
+		r.SetXForwarded()
+		// the challenge sees the hostname the player used
+		r.Out.Host = r.In.Host
+	},
+	ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+		logrus.Debugf("Sandbox proxy request to %s failed: %v", r.Host, err)
+		http.Error(w, "Sandbox unavailable", http.StatusBadGateway)
+	},
+}
+
+func handleHTTP(w http.ResponseWriter, r *http.Request) {
+	route, ok := lookup(r.Host, true)
+	if !ok {
+		http.Error(w, "Sandbox not found", http.StatusNotFound)
+		return
+	}
+	ctx := context.WithValue(r.Context(), targetKey{}, route.Target)
+	reverseProxy.ServeHTTP(w, r.WithContext(ctx))
+}
+
+func serveHTTP(ln net.Listener) {
+	server := &http.Server{
+		Handler:           http.HandlerFunc(handleHTTP),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	if err := server.Serve(ln); err != nil {
+		logrus.Errorf("Sandbox HTTP proxy stopped: %v", err)
+	}
+}
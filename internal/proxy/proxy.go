@@ -0,0 +1,113 @@
+package proxy
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/intraware/rodan/internal/config"
+	"github.com/intraware/rodan/internal/utils/values"
+)
+
+// Route is where one hostname label of the sandbox domain leads
+type Route struct {
+	Target string // container ip:port
+	HTTP   bool   // served by the HTTP listener, otherwise by the TLS gateway
+}
+
+// routes only live in this process. Sandboxes are owned by the instance that started
+// them and register their routes again when it restarts, so *.domain has to reach the
+// instance running the sandboxes; a second instance behind the same domain would
+// answer 404 for them.
+var (
+	routesLock sync.RWMutex
+	routes     = make(map[string]Route)
+)
+
+func Register(label string, route Route) {
+	routesLock.Lock()
+	defer routesLock.Unlock()
+	routes[label] = route
+}
+
+func Unregister(labels ...string) {
+	routesLock.Lock()
+	defer routesLock.Unlock()
+	for _, label := range labels {
+		delete(routes, label)
+	}
+}
+
+// lookup resolves a Host header or SNI name to its route. Only single labels
+// directly under the configured domain are routed.
+func lookup(host string, isHTTP bool) (Route, bool) {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	suffix := "." + strings.ToLower(values.GetConfig().Docker.Proxy.Domain)
+	label, ok := strings.CutSuffix(host, suffix)
+	if !ok || label == "" || strings.Contains(label, ".") {
+		return Route{}, false
+	}
+	routesLock.RLock()
+	route, ok := routes[label]
+	routesLock.RUnlock()
+	if !ok || route.HTTP != isHTTP {
+		return Route{}, false
+	}
+	return route, true
+}
+
+// NewToken returns the random part of a sandbox's hostnames, it is what keeps one
+// team from guessing another team's sandbox
+func NewToken() string {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// Link is what players are given for a route, an URL for HTTP ports and a host:port
+// to speak TLS to for the rest
+func Link(label string, isHTTP bool) string {
+	cfg := values.GetConfig().Docker.Proxy
+	host := label + "." + cfg.Domain
+	if !isHTTP {
+		port := cfg.TLSPublicPort
+		if port == 0 {
+			port = 443
+		}
+		return fmt.Sprintf("%s:%d", host, port)
+	}
+	scheme := cfg.HTTPScheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	if cfg.HTTPPublicPort != 0 {
+		host = fmt.Sprintf("%s:%d", host, cfg.HTTPPublicPort)
+	}
+	return scheme + "://" + host
+}
+
+// Start launches the configured listeners. They run for the life of the process,
+// changing the listen addresses needs a restart.
+func Start(cfg config.ProxyConfig) error {
+	if cfg.HTTPListen != "" {
+		ln, err := net.Listen("tcp", cfg.HTTPListen)
+		if err != nil {
+			return err
+		}
+		go serveHTTP(ln)
+	}
+	if cfg.TLSListen != "" {
+		ln, err := listenTLS(cfg.TLSListen, cfg.TLSCert, cfg.TLSKey)
+		if err != nil {
+			return err
+		}
+		go serveTLS(ln)
+	}
+	return nil
+}
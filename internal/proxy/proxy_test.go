@@ -0,0 +1,56 @@
+package proxy
+
+import (
+	"testing"
+
+	"github.com/intraware/rodan/internal/config"
+	"github.com/intraware/rodan/internal/utils/values"
+)
+
+func TestLookup(t *testing.T) {
+	var cfg config.Config
+	cfg.Docker.Proxy.Domain = "Sandbox.CTF.example.com"
+	values.SetConfig(&cfg)
+	web := Route{Target: "10.0.0.2:80", HTTP: true}
+	shell := Route{Target: "10.0.0.3:1337"}
+	Register("abc123", web)
+	Register("abc123-shell-1337", shell)
+	defer Unregister("abc123", "abc123-shell-1337")
+
+	cases := []struct {
+		name   string
+		host   string
+		isHTTP bool
+		want   Route
+		found  bool
+	}{
+		{"http route", "abc123.sandbox.ctf.example.com", true, web, true},
+		{"with port", "abc123.sandbox.ctf.example.com:8080", true, web, true},
+		{"mixed case", "ABC123.Sandbox.ctf.example.COM", true, web, true},
+		{"trailing dot", "abc123.sandbox.ctf.example.com.", true, web, true},
+		{"tls route", "abc123-shell-1337.sandbox.ctf.example.com", false, shell, true},
+		{"http route over tls", "abc123.sandbox.ctf.example.com", false, Route{}, false},
+		{"tls route over http", "abc123-shell-1337.sandbox.ctf.example.com", true, Route{}, false},
+		{"unknown label", "nope.sandbox.ctf.example.com", true, Route{}, false},
+		{"nested label", "x.abc123.sandbox.ctf.example.com", true, Route{}, false},
+		{"domain itself", "sandbox.ctf.example.com", true, Route{}, false},
+		{"empty label", ".sandbox.ctf.example.com", true, Route{}, false},
+		{"other domain", "abc123.example.org", true, Route{}, false},
+		{"domain as a prefix", "abc123.sandbox.ctf.example.com.evil.org", true, Route{}, false},
+		{"no dot before the domain", "abc123sandbox.ctf.example.com", true, Route{}, false},
+		{"empty host", "", true, Route{}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, found := lookup(tc.host, tc.isHTTP)
+			if found != tc.found || got != tc.want {
+				t.Fatalf("expected %+v %v, got %+v %v", tc.want, tc.found, got, found)
+			}
+		})
+	}
+
+	Unregister("abc123")
+	if _, found := lookup("abc123.sandbox.ctf.example.com", true); found {
+		t.Fatal("expected an unregistered route to be gone")
+	}
+}
@@ -0,0 +1,76 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"io"
+	"net"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	handshakeTimeout = 10 * time.Second
+	dialTimeout      = 5 * time.Second
+)
+
+func listenTLS(addr, certFile, keyFile string) (net.Listener, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	return tls.Listen("tcp", addr, &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	})
+}
+
+// serveTLS is the gateway for raw TCP ports. The player's TLS is terminated here and
+// the plain stream is piped to the container picked by the SNI name.
+func serveTLS(ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				continue
+			}
+			logrus.Errorf("Sandbox TLS gateway stopped: %v", err)
+			return
+		}
+		go handleTLS(conn.(*tls.Conn))
+	}
+}
+
+func handleTLS(conn *tls.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	if err := conn.Handshake(); err != nil {
+		return
+	}
+	conn.SetDeadline(time.Time{})
+	route, ok := lookup(conn.ConnectionState().ServerName, false)
+	if !ok {
+		return
+	}
+	upstream, err := net.DialTimeout("tcp", route.Target, dialTimeout)
+	if err != nil {
+		logrus.Debugf("Sandbox TLS gateway dial to %s failed: %v", route.Target, err)
+		return
+	}
+	defer upstream.Close()
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(upstream, conn)
+		if tcp, ok := upstream.(*net.TCPConn); ok {
+			tcp.CloseWrite()
+		}
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(conn, upstream)
+		conn.CloseWrite()
+		done <- struct{}{}
+	}()
+	<-done
+	<-done
+}
@@ -0,0 +1,184 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/intraware/rodan/internal/cache"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+// Bucket is the stored token bucket state, fields are exported so it survives the redis codec
+type Bucket struct {
+	Tokens float64
+	Last   int64
+}
+
+// Rule allows Burst requests at once, refilling one token every Every
+type Rule struct {
+	Burst int
+	Every time.Duration
+}
+
+func (r Rule) Enabled() bool {
+	return r.Burst > 0 && r.Every > 0
+}
+
+type Check struct {
+	Key  string
+	Rule Rule
+}
+
+// Limiter applies token buckets kept in store. In redis the buckets are checked and
+// taken by one script, so instances sharing it see the same limits. Otherwise the
+// mutex keeps them consistent within this process.
+type Limiter struct {
+	store cache.Cache[string, Bucket]
+	mu    sync.Mutex
+}
+
+func New(store cache.Cache[string, Bucket]) *Limiter {
+	return &Limiter{store: store}
+}
+
+// takeScript is Allow for buckets in redis. Times are in microseconds, ARGV holds the
+// current time and then burst and refill interval of every key. It returns the wait
+// until every bucket has a token again, 0 once one was taken from each.
+var takeScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local tokens, lasts = {}, {}
+local wait = 0
+for i, key in ipairs(KEYS) do
+	local burst = tonumber(ARGV[2 * i])
+	local every = tonumber(ARGV[2 * i + 1])
+	local state = redis.call("HMGET", key, "tokens", "last")
+	local t = tonumber(state[1])
+	local last = tonumber(state[2]) or now
+	if t == nil then
+		t = burst
+	elseif now > last then
+		t = math.min(burst, t + (now - last) / every)
+	end
+	tokens[i] = t
+	lasts[i] = math.max(now, last)
+	if t < 1 then
+		wait = math.max(wait, math.ceil((1 - t) * every))
+	end
+end
+if wait > 0 then
+	return wait
+end
+for i, key in ipairs(KEYS) do
+	local burst = tonumber(ARGV[2 * i])
+	local every = tonumber(ARGV[2 * i + 1])
+	redis.call("HSET", key, "tokens", tostring(tokens[i] - 1), "last", string.format("%d", lasts[i]))
+	-- a bucket left alone this long is full again, the same as a missing one
+	redis.call("PEXPIRE", key, math.ceil(burst * every / 1000) + 1)
+end
+return 0`)
+
+// Allow takes one token from every enabled bucket, or from none of them. When any
+// bucket is empty it returns the longest wait until all of them have a token again.
+func (l *Limiter) Allow(now time.Time, checks ...Check) (bool, time.Duration) {
+	var enabled []Check
+	for _, check := range checks {
+		if check.Rule.Enabled() {
+			enabled = append(enabled, check)
+		}
+	}
+	if len(enabled) == 0 {
+		return true, 0
+	}
+	if allowed, retryAfter, ok := l.allowShared(now, enabled); ok {
+		return allowed, retryAfter
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	buckets := make([]Bucket, len(enabled))
+	var retryAfter time.Duration
+	for i, check := range enabled {
+		bucket := l.refill(check, now)
+		if bucket.Tokens < 1 {
+			wait := time.Duration(math.Ceil((1 - bucket.Tokens) * float64(check.Rule.Every)))
+			retryAfter = max(retryAfter, wait)
+		}
+		buckets[i] = bucket
+	}
+	if retryAfter > 0 {
+		return false, retryAfter
+	}
+	for i, check := range enabled {
+		buckets[i].Tokens--
+		l.store.Set(check.Key, buckets[i])
+	}
+	return true, 0
+}
+
+// allowShared runs Allow in redis, ok is false when the buckets are kept in process
+func (l *Limiter) allowShared(now time.Time, checks []Check) (allowed bool, retryAfter time.Duration, ok bool) {
+	keys := make([]string, len(checks))
+	args := []any{now.UnixMicro()}
+	for i, check := range checks {
+		keys[i] = check.Key
+		args = append(args, check.Rule.Burst, max(check.Rule.Every.Microseconds(), 1))
+	}
+	result, ok, err := cache.Eval(l.store, takeScript, keys, args...)
+	if !ok {
+		return false, 0, false
+	}
+	if err != nil {
+		// an unreachable redis does not lock everyone out
+		logrus.Errorf("Failed to apply rate limit: %v", err)
+		return true, 0, true
+	}
+	if wait, _ := result.(int64); wait > 0 {
+		return false, time.Duration(wait) * time.Microsecond, true
+	}
+	return true, 0, true
+}
+
+func (l *Limiter) refill(check Check, now time.Time) Bucket {
+	burst := float64(check.Rule.Burst)
+	bucket, ok := l.store.Get(check.Key)
+	if !ok {
+		return Bucket{Tokens: burst, Last: now.UnixNano()}
+	}
+	elapsed := now.Sub(time.Unix(0, bucket.Last))
+	if elapsed > 0 {
+		bucket.Tokens = math.Min(burst, bucket.Tokens+float64(elapsed)/float64(check.Rule.Every))
+		bucket.Last = now.UnixNano()
+	}
+	return bucket
+}
+
+// countScript adds one to a counter, the window starts with its first count
+var countScript = redis.NewScript(`
+local count = redis.call("INCR", KEYS[1])
+if count == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return count`)
+
+var countLock sync.Mutex
+
+// Count adds one to the counter key in store and returns the new count. window is the
+// time to live of store, a counter runs out that long after its first count.
+func Count(store cache.Cache[string, int], key string, window time.Duration) int {
+	result, ok, err := cache.Eval(store, countScript, []string{key}, window.Milliseconds())
+	if ok {
+		if err != nil {
+			logrus.Errorf("Failed to count %s: %v", key, err)
+			return 0
+		}
+		count, _ := result.(int64)
+		return int(count)
+	}
+	countLock.Lock()
+	defer countLock.Unlock()
+	count, _ := store.Get(key)
+	count++
+	store.Set(key, count)
+	return count
+}
@@ -0,0 +1,152 @@
+package ratelimit_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/intraware/rodan/internal/ratelimit"
+)
+
+// mapStore is an in-process cache without expiry
+type mapStore[V any] struct {
+	mu     sync.Mutex
+	values map[string]V
+}
+
+func newStore[V any]() *mapStore[V] {
+	return &mapStore[V]{values: make(map[string]V)}
+}
+
+func (m *mapStore[V]) Get(key string) (V, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, ok := m.values[key]
+	return v, ok
+}
+
+func (m *mapStore[V]) Set(key string, value V) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[key] = value
+}
+
+func (m *mapStore[V]) Delete(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.values, key)
+}
+
+func (m *mapStore[V]) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values = make(map[string]V)
+}
+
+func TestAllow(t *testing.T) {
+	rule := ratelimit.Rule{Burst: 2, Every: 10 * time.Second}
+	start := time.Unix(1_700_000_000, 0)
+	type call struct {
+		after      time.Duration // since start
+		allowed    bool
+		retryAfter time.Duration
+	}
+	cases := []struct {
+		name  string
+		calls []call
+	}{
+		{"burst then empty", []call{
+			{0, true, 0},
+			{0, true, 0},
+			{0, false, 10 * time.Second},
+		}},
+		{"retry after shrinks while refilling", []call{
+			{0, true, 0},
+			{0, true, 0},
+			{4 * time.Second, false, 6 * time.Second},
+		}},
+		{"refills one token per interval", []call{
+			{0, true, 0},
+			{0, true, 0},
+			{10 * time.Second, true, 0},
+			{10 * time.Second, false, 10 * time.Second},
+		}},
+		{"never refills past the burst", []call{
+			{0, true, 0},
+			{time.Hour, true, 0},
+			{time.Hour, true, 0},
+			{time.Hour, false, 10 * time.Second},
+		}},
+		{"a refused call takes nothing", []call{
+			{0, true, 0},
+			{0, true, 0},
+			{5 * time.Second, false, 5 * time.Second},
+			{10 * time.Second, true, 0},
+		}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			l := ratelimit.New(newStore[ratelimit.Bucket]())
+			for i, c := range tc.calls {
+				allowed, retryAfter := l.Allow(start.Add(c.after), ratelimit.Check{Key: "user:1", Rule: rule})
+				if allowed != c.allowed || retryAfter != c.retryAfter {
+					t.Fatalf("call %d: expected %v and %s, got %v and %s", i, c.allowed, c.retryAfter, allowed, retryAfter)
+				}
+			}
+		})
+	}
+}
+
+func TestAllowTakesFromAllOrNone(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	store := newStore[ratelimit.Bucket]()
+	l := ratelimit.New(store)
+	user := ratelimit.Check{Key: "user:1", Rule: ratelimit.Rule{Burst: 5, Every: time.Second}}
+	challenge := ratelimit.Check{Key: "challenge:1:1", Rule: ratelimit.Rule{Burst: 1, Every: 30 * time.Second}}
+	disabled := ratelimit.Check{Key: "team:1", Rule: ratelimit.Rule{}}
+
+	if allowed, _ := l.Allow(now, user, challenge, disabled); !allowed {
+		t.Fatal("expected the first call to be allowed")
+	}
+	allowed, retryAfter := l.Allow(now, user, challenge, disabled)
+	if allowed || retryAfter != 30*time.Second {
+		t.Fatalf("expected the challenge bucket to refuse for 30s, got %v and %s", allowed, retryAfter)
+	}
+	if bucket, _ := store.Get("user:1"); bucket.Tokens != 4 {
+		t.Fatalf("expected the refused call to leave the user bucket at 4, got %g", bucket.Tokens)
+	}
+	if _, ok := store.Get("team:1"); ok {
+		t.Fatal("expected a disabled rule to keep no bucket")
+	}
+	// another challenge only shares the user bucket
+	other := ratelimit.Check{Key: "challenge:1:2", Rule: challenge.Rule}
+	if allowed, _ := l.Allow(now, user, other); !allowed {
+		t.Fatal("expected a different challenge to be allowed")
+	}
+	if allowed, _ := l.Allow(now); !allowed {
+		t.Fatal("expected a call without checks to be allowed")
+	}
+}
+
+func TestAllowRetryAfterIsTheLongestWait(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	l := ratelimit.New(newStore[ratelimit.Bucket]())
+	short := ratelimit.Check{Key: "user:1", Rule: ratelimit.Rule{Burst: 1, Every: 2 * time.Second}}
+	long := ratelimit.Check{Key: "team:1", Rule: ratelimit.Rule{Burst: 1, Every: 7 * time.Second}}
+	l.Allow(now, short, long)
+	if allowed, retryAfter := l.Allow(now.Add(time.Second), short, long); allowed || retryAfter != 6*time.Second {
+		t.Fatalf("expected a wait of 6s for the team bucket, got %v and %s", allowed, retryAfter)
+	}
+}
+
+func TestCount(t *testing.T) {
+	store := newStore[int]()
+	for want := 1; want <= 3; want++ {
+		if got := ratelimit.Count(store, "1", time.Minute); got != want {
+			t.Fatalf("expected %d, got %d", want, got)
+		}
+	}
+	if got := ratelimit.Count(store, "2", time.Minute); got != 1 {
+		t.Fatalf("expected a separate counter per key, got %d", got)
+	}
+}
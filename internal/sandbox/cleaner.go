@@ -7,9 +7,10 @@ import (
 	"time"
 
 	"github.com/intraware/rodan/internal/utils/docker"
-	"github.com/intraware/rodan/internal/utils/values"
 )
 
+const orphanGracePeriod = 2 * time.Minute
+
 type cleaner struct {
 	BoxList       *list.List
 	CleanInterval time.Time
@@ -24,15 +25,18 @@ func newCleaner() *cleaner {
 		wakeUp:        make(chan struct{}, 1),
 	}
 	go cl.clean()
-	if values.GetConfig().Docker.CleanOrphaned {
-		go cl.clean_orphan()
-	}
 	return cl
 }
 
 func (c *cleaner) Add(box *SandBox) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	for e := c.BoxList.Front(); e != nil; e = e.Next() {
+		if e.Value.(*SandBox) == box {
+			c.BoxList.Remove(e)
+			break
+		}
+	}
 	c.BoxList.PushBack(box)
 	deadline, ok := box.Context.Deadline()
 	if !ok {
@@ -56,13 +60,19 @@ func (c *cleaner) clean_orphan() {
 			continue
 		}
 		for _, ctr := range containerList {
+			// containers that were just created may not be tracked yet
+			if time.Since(time.Unix(ctr.Created, 0)) < orphanGracePeriod {
+				continue
+			}
 			existsInPool := containerPool.CheckIfExists(ctr.ID)
 			existsInCleaner := c.checkIfExists(ctr.ID)
 			if existsInPool || existsInCleaner {
 				continue
 			}
-			docker.StopContainer(ctx, ctr.ID)
+			docker.RemoveContainer(ctx, ctr.ID)
 		}
+		// networks of removed multi-container sandboxes are empty now
+		docker.PruneNetworks(ctx, orphanGracePeriod)
 		time.Sleep(1 * time.Minute)
 	}
 }
@@ -150,7 +160,7 @@ func (c *cleaner) checkIfExists(containerID string) bool {
 
 	for e := c.BoxList.Front(); e != nil; e = e.Next() {
 		box := e.Value.(*SandBox)
-		if box.Container != nil && box.Container.ContainerID == containerID {
+		if box.Container != nil && box.Container.Owns(containerID) {
 			return true
 		}
 	}
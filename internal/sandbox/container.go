@@ -3,66 +3,220 @@ package sandbox
 import (
 	"context"
 	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/intraware/rodan/internal/models"
 	"github.com/intraware/rodan/internal/utils/docker"
 )
 
+// container is the set of docker containers behind one sandbox. A single image
+// challenge has one, a multi-container challenge has one per service on a private
+// network. ContainerID is the first container the flag goes into.
 type container struct {
 	Context     context.Context
 	ContainerID string
+	ServiceIDs  []string // every container, in start order
+	FlagIDs     []string // containers that run ./generate and ./reset
+	NetworkID   string
 	ImageName   string
 	ChallengeID uint
 	TTL         time.Duration
 	StartedAt   time.Time
+	spec        string // config the containers were made from, see specOf
 }
 
-func newContainer(ctx context.Context, challengeID uint, containerName, imageName string, ttl time.Duration, exposedPorts []string) (*container, error) {
-	if !docker.ImageExists(ctx, imageName) {
+// newContainer creates the containers of a challenge, labels go on every one of them
+func newContainer(ctx context.Context, challengeID uint, containerName string, dc *models.DynamicConfig, ttl time.Duration, limits docker.ContainerLimits, labels map[string]string) (*container, error) {
+	if len(dc.Services) > 0 {
+		c, err := newServiceGroup(ctx, challengeID, containerName, dc.Services, ttl, limits, labels)
+		if err != nil {
+			return nil, err
+		}
+		c.spec = specOf(dc)
+		return c, nil
+	}
+	if !docker.ImageExists(ctx, dc.DockerImage) {
 		return nil, errImageNotExists
 	}
-	containerID, err := docker.CreateContainer(ctx, containerName, imageName, exposedPorts)
+	containerID, err := docker.CreateServiceContainer(ctx, containerName, dc.DockerImage, dc.ExposedPorts, limits, docker.ServiceOptions{Labels: labels})
 	if err != nil {
 		return nil, err
 	}
 	return &container{
-		Context:     ctx,
+		Context:     context.WithoutCancel(ctx),
 		ContainerID: containerID,
-		ImageName:   imageName,
+		ServiceIDs:  []string{containerID},
+		FlagIDs:     []string{containerID},
+		ImageName:   dc.DockerImage,
 		ChallengeID: challengeID,
 		TTL:         ttl,
+		spec:        specOf(dc),
 	}, nil
 }
 
+// newServiceGroup creates every service of a challenge on a fresh network. Nothing
+// is left behind when one of them fails.
+func newServiceGroup(ctx context.Context, challengeID uint, containerName string, services []models.SandboxService, ttl time.Duration, limits docker.ContainerLimits, labels map[string]string) (*container, error) {
+	for _, svc := range services {
+		if !docker.ImageExists(ctx, svc.Image) {
+			return nil, errImageNotExists
+		}
+	}
+	networkID, err := docker.CreateNetwork(ctx, "rodan-"+containerName)
+	if err != nil {
+		return nil, err
+	}
+	c := &container{
+		Context:     context.WithoutCancel(ctx),
+		NetworkID:   networkID,
+		ImageName:   services[0].Image,
+		ChallengeID: challengeID,
+		TTL:         ttl,
+	}
+	for _, svc := range services {
+		containerID, err := docker.CreateServiceContainer(ctx, containerName+"-"+svc.Name, svc.Image, svc.Ports, limits, docker.ServiceOptions{
+			Env:     svc.Env,
+			Network: networkID,
+			Alias:   svc.Name,
+			Labels:  labels,
+		})
+		if err != nil {
+			c.Discard()
+			return nil, err
+		}
+		c.ServiceIDs = append(c.ServiceIDs, containerID)
+	}
+	c.FlagIDs = flagTargets(services, c.ServiceIDs)
+	c.ContainerID = c.FlagIDs[0]
+	return c, nil
+}
+
+var serviceName = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,38}[a-z0-9])?$`)
+
+// ValidateServices checks the services of a multi-container challenge. Names double
+// as hostnames on the sandbox network and go into proxy hostname labels, which is
+// why they are kept short enough for a 63 character label.
+func ValidateServices(services []models.SandboxService) error {
+	seen := make(map[string]bool, len(services))
+	published := false
+	for _, svc := range services {
+		if !serviceName.MatchString(svc.Name) {
+			return fmt.Errorf("invalid service name %q", svc.Name)
+		}
+		if seen[svc.Name] {
+			return fmt.Errorf("duplicate service name %q", svc.Name)
+		}
+		seen[svc.Name] = true
+		if svc.Image == "" {
+			return fmt.Errorf("service %s needs an image", svc.Name)
+		}
+		for _, port := range svc.Ports {
+			if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+				return fmt.Errorf("service %s has an invalid port %q", svc.Name, port)
+			}
+			published = true
+		}
+		for _, env := range svc.Env {
+			if !strings.Contains(env, "=") {
+				return fmt.Errorf("service %s has an invalid env entry %q", svc.Name, env)
+			}
+		}
+	}
+	if len(services) > 0 && !published {
+		return fmt.Errorf("at least one service has to publish a port")
+	}
+	return nil
+}
+
+// flagTargets picks the containers of the services marked flag, the first service
+// when none is
+func flagTargets(services []models.SandboxService, ids []string) []string {
+	var targets []string
+	for i, svc := range services {
+		if svc.Flag && i < len(ids) {
+			targets = append(targets, ids[i])
+		}
+	}
+	if len(targets) == 0 && len(ids) > 0 {
+		targets = ids[:1]
+	}
+	return targets
+}
+
+// Start brings the services up in order, stopping the ones already started if one fails
 func (c *container) Start() (err error) {
-	err = docker.StartContainer(c.Context, c.ContainerID)
-	if err == nil {
-		c.StartedAt = time.Now()
+	for i, id := range c.ServiceIDs {
+		if err = docker.StartContainer(c.Context, id); err != nil {
+			for j := i - 1; j >= 0; j-- {
+				docker.StopContainer(c.Context, c.ServiceIDs[j])
+			}
+			return
+		}
 	}
+	c.StartedAt = time.Now()
 	return
 }
 
+// Stop stops the services in reverse order, every one is tried even if one fails
 func (c *container) Stop() (err error) {
-	err = docker.StopContainer(c.Context, c.ContainerID)
+	for i := len(c.ServiceIDs) - 1; i >= 0; i-- {
+		if serr := docker.StopContainer(c.Context, c.ServiceIDs[i]); serr != nil && err == nil {
+			err = serr
+		}
+	}
 	return
 }
 
+// Discard removes every container and then the network they were on
 func (c *container) Discard() (err error) {
-	err = docker.RemoveContainer(c.Context, c.ContainerID)
+	for i := len(c.ServiceIDs) - 1; i >= 0; i-- {
+		if rerr := docker.RemoveContainer(c.Context, c.ServiceIDs[i]); rerr != nil && err == nil {
+			err = rerr
+		}
+	}
+	if c.NetworkID != "" {
+		if rerr := docker.RemoveNetwork(c.Context, c.NetworkID); rerr != nil && err == nil {
+			err = rerr
+		}
+	}
 	return
 }
 
 func (c *container) Reset() (err error) {
-	err = docker.RunCommand(c.Context, c.ContainerID, "./reset")
+	for _, id := range c.FlagIDs {
+		if err = docker.RunCommand(c.Context, id, "./reset"); err != nil {
+			return
+		}
+	}
 	return
 }
 
 func (c *container) GenerateFlag(flag string) (err error) {
 	generate := fmt.Sprintf("./generate %s", flag)
-	err = docker.RunCommand(c.Context, c.ContainerID, generate)
+	for _, id := range c.FlagIDs {
+		if err = docker.RunCommand(c.Context, id, generate); err != nil {
+			return
+		}
+	}
 	return
 }
 
+// Owns reports whether the docker container belongs to this sandbox
+func (c *container) Owns(containerID string) bool {
+	if c.ContainerID == containerID {
+		return true
+	}
+	for _, id := range c.ServiceIDs {
+		if id == containerID {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *container) GetAll() ([]string, error) {
 	containers, err := docker.ListContainers(c.Context)
 	if err != nil {
@@ -0,0 +1,77 @@
+package sandbox
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/intraware/rodan/internal/models"
+)
+
+func TestValidateServices(t *testing.T) {
+	web := models.SandboxService{Name: "web", Image: "web:latest", Ports: []string{"80"}, Flag: true}
+	db := models.SandboxService{Name: "db", Image: "postgres:16", Env: []string{"POSTGRES_PASSWORD=secret"}}
+	named := func(name string) models.SandboxService {
+		return models.SandboxService{Name: name, Image: "web:latest", Ports: []string{"80"}}
+	}
+	cases := []struct {
+		name     string
+		services []models.SandboxService
+		valid    bool
+	}{
+		{"none", nil, true},
+		{"single", []models.SandboxService{web}, true},
+		{"web and db", []models.SandboxService{web, db}, true},
+		{"duplicate names", []models.SandboxService{web, db, {Name: "db", Image: "redis:7"}}, false},
+		{"nothing published", []models.SandboxService{db}, false},
+		{"missing image", []models.SandboxService{web, {Name: "bot"}}, false},
+		{"invalid port", []models.SandboxService{{Name: "web", Image: "web:latest", Ports: []string{"http"}}}, false},
+		{"port out of range", []models.SandboxService{{Name: "web", Image: "web:latest", Ports: []string{"70000"}}}, false},
+		{"invalid env", []models.SandboxService{web, {Name: "db", Image: "postgres:16", Env: []string{"PASSWORD"}}}, false},
+		{"alias with digits and hyphen", []models.SandboxService{named("api-v2")}, true},
+		{"alias of one character", []models.SandboxService{named("a")}, true},
+		{"alias at the length limit", []models.SandboxService{named(strings.Repeat("a", 40))}, true},
+		{"alias too long", []models.SandboxService{named(strings.Repeat("a", 41))}, false},
+		{"empty alias", []models.SandboxService{named("")}, false},
+		{"uppercase alias", []models.SandboxService{named("Web")}, false},
+		{"alias with leading hyphen", []models.SandboxService{named("-web")}, false},
+		{"alias with trailing hyphen", []models.SandboxService{named("web-")}, false},
+		{"alias with a dot", []models.SandboxService{named("web.internal")}, false},
+		{"alias with an underscore", []models.SandboxService{named("web_app")}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateServices(tc.services)
+			if tc.valid && err != nil {
+				t.Fatalf("expected it to be accepted, got %v", err)
+			}
+			if !tc.valid && err == nil {
+				t.Fatal("expected it to be rejected")
+			}
+		})
+	}
+}
+
+func TestFlagTargets(t *testing.T) {
+	ids := []string{"c-web", "c-db", "c-bot"}
+	cases := []struct {
+		name  string
+		flags []bool
+		want  []string
+	}{
+		{"none marked falls back to the first", []bool{false, false, false}, []string{"c-web"}},
+		{"one marked", []bool{false, true, false}, []string{"c-db"}},
+		{"several marked", []bool{true, false, true}, []string{"c-web", "c-bot"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			services := make([]models.SandboxService, len(tc.flags))
+			for i, flag := range tc.flags {
+				services[i].Flag = flag
+			}
+			if got := flagTargets(services, ids); !slices.Equal(got, tc.want) {
+				t.Fatalf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package sandbox
+
+import (
+	"time"
+
+	"github.com/intraware/rodan/internal/events"
+	"github.com/intraware/rodan/internal/utils/values"
+)
+
+// scheduleExpiryWarning lets the team know shortly before their sandbox runs out
+func (s *SandBox) scheduleExpiryWarning() {
+	s.cancelExpiryWarning()
+	lead := values.GetConfig().App.Stream.SandboxWarning
+	deadline, ok := s.Context.Deadline()
+	if !ok || lead <= 0 {
+		return
+	}
+	wait := max(time.Until(deadline)-lead, 0)
+	teamID, challengeID := s.TeamID, s.ChallengeMeta.ID
+	s.warnTimer = time.AfterFunc(wait, func() {
+		events.Publish(events.ForTeam(teamID, events.SandboxExpiring, events.SandboxData{
+			ChallengeID: challengeID,
+			ExpiresAt:   deadline,
+		}))
+	})
+}
+
+func (s *SandBox) cancelExpiryWarning() {
+	if s.warnTimer != nil {
+		s.warnTimer.Stop()
+		s.warnTimer = nil
+	}
+}
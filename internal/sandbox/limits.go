@@ -0,0 +1,153 @@
+package sandbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/intraware/rodan/internal/config"
+	"github.com/intraware/rodan/internal/models"
+	"github.com/intraware/rodan/internal/utils/docker"
+)
+
+var capabilityName = regexp.MustCompile(`^[A-Z_]+$`)
+
+// ValidateLimits checks the resource and security overrides of a dynamic challenge
+// against the configured ceilings
+func ValidateLimits(dc models.DynamicConfig, cfg config.SandboxConfig) error {
+	if dc.MemoryMB < 0 || dc.CPUs < 0 || dc.PidsLimit < 0 {
+		return fmt.Errorf("memory_mb, cpus and pids_limit must be >= 0")
+	}
+	if dc.WarmPool != nil && *dc.WarmPool < 0 {
+		return fmt.Errorf("warm_pool must be >= 0")
+	}
+	if cfg.MaxMemoryMB > 0 && dc.MemoryMB > cfg.MaxMemoryMB {
+		return fmt.Errorf("memory_mb cannot exceed %d", cfg.MaxMemoryMB)
+	}
+	if cfg.MaxCPUs > 0 && dc.CPUs > cfg.MaxCPUs {
+		return fmt.Errorf("cpus cannot exceed %g", cfg.MaxCPUs)
+	}
+	if cfg.MaxPidsLimit > 0 && dc.PidsLimit > cfg.MaxPidsLimit {
+		return fmt.Errorf("pids_limit cannot exceed %d", cfg.MaxPidsLimit)
+	}
+	for _, c := range append(append([]string{}, dc.CapAdd...), dc.CapDrop...) {
+		if !capabilityName.MatchString(c) {
+			return fmt.Errorf("invalid capability %q", c)
+		}
+	}
+	for _, c := range dc.CapAdd {
+		if !slices.Contains(cfg.AllowedCapAdd, c) {
+			return fmt.Errorf("capability %s may not be added", c)
+		}
+	}
+	for _, mount := range dc.Tmpfs {
+		target, _, _ := strings.Cut(mount, ":")
+		if !path.IsAbs(target) || path.Clean(target) == "/" {
+			return fmt.Errorf("invalid tmpfs mount %q", mount)
+		}
+	}
+	if dc.SeccompProfile == "unconfined" {
+		return fmt.Errorf("seccomp_profile cannot be unconfined")
+	}
+	if dc.SeccompProfile != "" {
+		if _, err := loadSeccompProfile(dc.SeccompProfile); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadSeccompProfile reads a profile from disk, the daemon only takes it inline
+func loadSeccompProfile(profile string) (string, error) {
+	if profile == "unconfined" {
+		return profile, nil
+	}
+	data, err := os.ReadFile(profile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read seccomp profile: %w", err)
+	}
+	if !json.Valid(data) {
+		return "", fmt.Errorf("seccomp profile %s is not valid json", profile)
+	}
+	return string(data), nil
+}
+
+// clamp applies a challenge override to a default, kept under the ceiling when one is set
+func clamp[T int64 | float64](def, override, ceiling T) T {
+	value := def
+	if override > 0 {
+		value = override
+	}
+	if ceiling > 0 && value > ceiling {
+		value = ceiling
+	}
+	return value
+}
+
+// containerLimits merges the challenge overrides into the configured defaults
+func containerLimits(cfg config.SandboxConfig, dc *models.DynamicConfig) (docker.ContainerLimits, error) {
+	if dc == nil {
+		dc = &models.DynamicConfig{}
+	}
+	limits := docker.ContainerLimits{
+		MemoryMB:        clamp(cfg.MemoryMB, dc.MemoryMB, cfg.MaxMemoryMB),
+		CPUs:            clamp(cfg.CPUs, dc.CPUs, cfg.MaxCPUs),
+		PidsLimit:       clamp(cfg.PidsLimit, dc.PidsLimit, cfg.MaxPidsLimit),
+		CapAdd:          mergeCaps(cfg.CapAdd, allowedCaps(dc.CapAdd, cfg.AllowedCapAdd)),
+		CapDrop:         mergeCaps(cfg.CapDrop, dc.CapDrop),
+		ReadOnly:        cfg.ReadOnly,
+		NoNewPrivileges: !cfg.AllowNewPrivileges,
+	}
+	if dc.ReadOnly != nil {
+		limits.ReadOnly = *dc.ReadOnly
+	}
+	// a challenge mount replaces a default one on the same path
+	for _, mount := range append(append([]string{}, cfg.Tmpfs...), dc.Tmpfs...) {
+		target, options, _ := strings.Cut(mount, ":")
+		if limits.Tmpfs == nil {
+			limits.Tmpfs = make(map[string]string)
+		}
+		limits.Tmpfs[target] = options
+	}
+	profile := cfg.SeccompProfile
+	// only the operator may turn seccomp off, a challenge stored with it falls back
+	if dc.SeccompProfile != "" && dc.SeccompProfile != "unconfined" {
+		profile = dc.SeccompProfile
+	}
+	if profile != "" {
+		seccomp, err := loadSeccompProfile(profile)
+		if err != nil {
+			return limits, err
+		}
+		limits.Seccomp = seccomp
+	}
+	return limits, nil
+}
+
+// allowedCaps drops the capabilities a challenge may not add, for rows stored before
+// they were checked
+func allowedCaps(caps, allowed []string) []string {
+	var kept []string
+	for _, c := range caps {
+		if slices.Contains(allowed, c) {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
+
+func mergeCaps(defaults, extra []string) []string {
+	var caps []string
+	seen := make(map[string]bool)
+	for _, c := range append(append([]string{}, defaults...), extra...) {
+		if !seen[c] {
+			seen[c] = true
+			caps = append(caps, c)
+		}
+	}
+	return caps
+}
@@ -0,0 +1,171 @@
+package sandbox
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/intraware/rodan/internal/config"
+	"github.com/intraware/rodan/internal/models"
+)
+
+func testSandboxConfig() config.SandboxConfig {
+	return config.SandboxConfig{
+		MemoryMB:      256,
+		CPUs:          0.5,
+		PidsLimit:     256,
+		MaxMemoryMB:   1024,
+		MaxCPUs:       2,
+		MaxPidsLimit:  512,
+		CapDrop:       []string{"ALL"},
+		CapAdd:        []string{"CHOWN", "SETUID"},
+		AllowedCapAdd: []string{"CHOWN", "SETUID", "NET_RAW"},
+		ReadOnly:      true,
+		Tmpfs:         []string{"/tmp:rw,size=64m"},
+	}
+}
+
+func TestClamp(t *testing.T) {
+	cases := []struct {
+		name                         string
+		def, override, ceiling, want int64
+	}{
+		{"default", 256, 0, 1024, 256},
+		{"override", 256, 512, 1024, 512},
+		{"override over ceiling", 256, 4096, 1024, 1024},
+		{"default over ceiling", 2048, 0, 1024, 1024},
+		{"no ceiling", 256, 4096, 0, 4096},
+		{"unlimited default", 0, 0, 1024, 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := clamp(tc.def, tc.override, tc.ceiling); got != tc.want {
+				t.Fatalf("expected %d, got %d", tc.want, got)
+			}
+		})
+	}
+	if got := clamp(0.5, 4.0, 2.0); got != 2 {
+		t.Fatalf("expected cpus to be clamped to 2, got %g", got)
+	}
+}
+
+func TestMergeCaps(t *testing.T) {
+	cases := []struct {
+		name            string
+		defaults, extra []string
+		want            []string
+	}{
+		{"both empty", nil, nil, nil},
+		{"defaults only", []string{"CHOWN"}, nil, []string{"CHOWN"}},
+		{"extra appended", []string{"CHOWN"}, []string{"NET_RAW"}, []string{"CHOWN", "NET_RAW"}},
+		{"duplicates dropped", []string{"CHOWN", "SETUID"}, []string{"SETUID", "CHOWN", "NET_RAW"}, []string{"CHOWN", "SETUID", "NET_RAW"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := mergeCaps(tc.defaults, tc.extra); !slices.Equal(got, tc.want) {
+				t.Fatalf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestContainerLimits(t *testing.T) {
+	cfg := testSandboxConfig()
+	writable := false
+	limits, err := containerLimits(cfg, &models.DynamicConfig{
+		MemoryMB:       4096,
+		PidsLimit:      128,
+		CapAdd:         []string{"NET_RAW", "SYS_ADMIN"},
+		ReadOnly:       &writable,
+		Tmpfs:          []string{"/tmp:rw,size=16m", "/run"},
+		SeccompProfile: "unconfined",
+	})
+	if err != nil {
+		t.Fatalf("containerLimits failed: %v", err)
+	}
+	if limits.MemoryMB != 1024 || limits.CPUs != 0.5 || limits.PidsLimit != 128 {
+		t.Fatalf("unexpected resources: %+v", limits)
+	}
+	if want := []string{"CHOWN", "SETUID", "NET_RAW"}; !slices.Equal(limits.CapAdd, want) {
+		t.Fatalf("expected cap_add %v with SYS_ADMIN filtered out, got %v", want, limits.CapAdd)
+	}
+	if !slices.Equal(limits.CapDrop, []string{"ALL"}) {
+		t.Fatalf("expected the default cap_drop, got %v", limits.CapDrop)
+	}
+	if limits.ReadOnly || !limits.NoNewPrivileges {
+		t.Fatalf("expected a writable root and no new privileges, got %+v", limits)
+	}
+	if limits.Tmpfs["/tmp"] != "rw,size=16m" || len(limits.Tmpfs) != 2 {
+		t.Fatalf("expected the challenge mount to replace the default one, got %v", limits.Tmpfs)
+	}
+	if limits.Seccomp != "" {
+		t.Fatalf("expected a stored unconfined profile to be ignored, got %q", limits.Seccomp)
+	}
+
+	defaults, err := containerLimits(cfg, nil)
+	if err != nil {
+		t.Fatalf("containerLimits without overrides failed: %v", err)
+	}
+	if defaults.MemoryMB != 256 || !defaults.ReadOnly || defaults.Tmpfs["/tmp"] != "rw,size=64m" {
+		t.Fatalf("expected the configured defaults, got %+v", defaults)
+	}
+}
+
+func TestContainerLimitsSeccompProfile(t *testing.T) {
+	profile := filepath.Join(t.TempDir(), "seccomp.json")
+	if err := os.WriteFile(profile, []byte(`{"defaultAction":"SCMP_ACT_ERRNO"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := testSandboxConfig()
+	cfg.SeccompProfile = profile
+	limits, err := containerLimits(cfg, nil)
+	if err != nil {
+		t.Fatalf("containerLimits failed: %v", err)
+	}
+	if limits.Seccomp != `{"defaultAction":"SCMP_ACT_ERRNO"}` {
+		t.Fatalf("expected the profile to be passed inline, got %q", limits.Seccomp)
+	}
+	cfg.SeccompProfile = filepath.Join(t.TempDir(), "missing.json")
+	if _, err := containerLimits(cfg, nil); err == nil {
+		t.Fatal("expected a missing profile to fail")
+	}
+}
+
+func TestValidateLimits(t *testing.T) {
+	cfg := testSandboxConfig()
+	negative := -1
+	cases := []struct {
+		name  string
+		dc    models.DynamicConfig
+		valid bool
+	}{
+		{"empty", models.DynamicConfig{}, true},
+		{"within ceilings", models.DynamicConfig{MemoryMB: 1024, CPUs: 2, PidsLimit: 512}, true},
+		{"negative memory", models.DynamicConfig{MemoryMB: -1}, false},
+		{"negative warm pool", models.DynamicConfig{WarmPool: &negative}, false},
+		{"memory over ceiling", models.DynamicConfig{MemoryMB: 1025}, false},
+		{"cpus over ceiling", models.DynamicConfig{CPUs: 2.5}, false},
+		{"pids over ceiling", models.DynamicConfig{PidsLimit: 513}, false},
+		{"allowed cap", models.DynamicConfig{CapAdd: []string{"NET_RAW"}}, true},
+		{"disallowed cap", models.DynamicConfig{CapAdd: []string{"SYS_ADMIN"}}, false},
+		{"all caps", models.DynamicConfig{CapAdd: []string{"ALL"}}, false},
+		{"malformed cap", models.DynamicConfig{CapDrop: []string{"net_raw"}}, false},
+		{"tmpfs", models.DynamicConfig{Tmpfs: []string{"/run:size=8m"}}, true},
+		{"relative tmpfs", models.DynamicConfig{Tmpfs: []string{"run"}}, false},
+		{"root tmpfs", models.DynamicConfig{Tmpfs: []string{"/"}}, false},
+		{"unconfined seccomp", models.DynamicConfig{SeccompProfile: "unconfined"}, false},
+		{"missing seccomp", models.DynamicConfig{SeccompProfile: "/no/such/profile.json"}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateLimits(tc.dc, cfg)
+			if tc.valid && err != nil {
+				t.Fatalf("expected it to be accepted, got %v", err)
+			}
+			if !tc.valid && err == nil {
+				t.Fatal("expected it to be rejected")
+			}
+		})
+	}
+}
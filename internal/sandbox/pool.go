@@ -1,28 +1,106 @@
 package sandbox
 
 import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
 	"sync"
+	"time"
 
+	"github.com/intraware/rodan/internal/models"
+	"github.com/intraware/rodan/internal/utils/docker"
 	"github.com/intraware/rodan/internal/utils/values"
+	"github.com/sirupsen/logrus"
 )
 
+const defaultWarmInterval = 30 * time.Second
+
+// warmLabel marks the containers the pool creates ahead of time, they are not
+// recorded anywhere and have to be found again after a restart
+var warmLabel = map[string]string{"rodan.pool": "warm"}
+
+// pool keeps ready containers per challenge. Warm containers are created and started
+// ahead of time without a flag, containers released by reusable sandboxes join them.
 type pool struct {
-	mu   sync.RWMutex
-	pool map[uint][]*container
+	mu      sync.Mutex
+	pool    map[uint][]*container
+	targets map[uint]warmTarget
+	pending map[uint]int
+	stats   map[uint]*PoolStat
+	wakeUp  chan struct{}
+	// where targets and new containers come from, replaced in tests
+	loadTargets func() (map[uint]warmTarget, error)
+	create      func(challengeID uint, target warmTarget) (*container, error)
+}
+
+// warmTarget is what the pool should hold for one visible dynamic challenge
+type warmTarget struct {
+	size   int
+	spec   string
+	config models.DynamicConfig
+	ttl    time.Duration
+}
+
+// PoolStat is the warm pool state of one challenge, the counters add up since startup
+type PoolStat struct {
+	ChallengeID uint  `json:"challenge_id"`
+	Target      int   `json:"target"`
+	Ready       int   `json:"ready"`
+	Pending     int   `json:"pending"`
+	Hits        int64 `json:"hits"`
+	Misses      int64 `json:"misses"`
+	Created     int64 `json:"created"`
+	Failed      int64 `json:"failed"`
+	Evicted     int64 `json:"evicted"`
 }
 
 func newPool() *pool {
 	return &pool{
-		pool: make(map[uint][]*container),
+		pool:    make(map[uint][]*container),
+		targets: make(map[uint]warmTarget),
+		pending: make(map[uint]int),
+		stats:   make(map[uint]*PoolStat),
+		wakeUp:  make(chan struct{}, 1),
+
+		loadTargets: loadWarmTargets,
+		create:      warmContainer,
+	}
+}
+
+// specOf fingerprints the config a container was made from, pooled containers of an
+// older config are never handed out. The pool size is left out so resizing the pool
+// keeps the containers it already has.
+func specOf(dc *models.DynamicConfig) string {
+	if dc == nil {
+		return ""
 	}
+	spec := *dc
+	spec.WarmPool = nil
+	b, _ := json.Marshal(spec)
+	return string(b)
+}
+
+func (p *pool) stat(challengeID uint) *PoolStat {
+	st, ok := p.stats[challengeID]
+	if !ok {
+		st = &PoolStat{ChallengeID: challengeID}
+		p.stats[challengeID] = st
+	}
+	return st
 }
 
 func (p *pool) Aquire(challengeID uint) (*container, error) {
 	var ctr *container
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	defer p.wake()
 	containers, ok := p.pool[challengeID]
 	if !ok || len(containers) == 0 {
+		p.stat(challengeID).Misses++
 		return nil, errNoContainers
 	}
 	ctr = containers[0]
@@ -30,33 +108,254 @@ func (p *pool) Aquire(challengeID uint) (*container, error) {
 	if len(p.pool[challengeID]) == 0 {
 		delete(p.pool, challengeID)
 	}
+	p.stat(challengeID).Hits++
 	return ctr, nil
 }
 
+// capacity is how many containers a challenge may keep pooled, at least pool-size so
+// reusable sandboxes can still hand theirs back
+func (p *pool) capacity(challengeID uint) int {
+	return max(p.targets[challengeID].size, values.GetConfig().Docker.PoolSize)
+}
+
 func (p *pool) Release(c *container) error {
 	challengeID := c.ChallengeID
 	p.mu.Lock()
 	defer p.mu.Unlock()
-	if len(p.pool[challengeID]) >= values.GetConfig().Docker.PoolSize {
+	if target, ok := p.targets[challengeID]; ok && target.spec != c.spec {
 		return errPoolFull
 	}
-	if len(p.pool[challengeID]) == 0 {
-		p.pool[challengeID] = make([]*container, values.GetConfig().Docker.PoolSize)
+	if len(p.pool[challengeID]) >= p.capacity(challengeID) {
+		return errPoolFull
 	}
 	p.pool[challengeID] = append(p.pool[challengeID], c)
 	return nil
 }
 
 func (p *pool) CheckIfExists(containerID string) bool {
-	p.mu.RLock()
-	defer p.mu.RUnlock()
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	for _, containers := range p.pool {
 		for i := range containers {
 			ctr := containers[i]
-			if ctr.ContainerID == containerID {
+			if ctr != nil && ctr.Owns(containerID) {
 				return true
 			}
 		}
 	}
 	return false
 }
+
+// Stats reports every challenge the pool has a target or history for
+func (p *pool) Stats() []PoolStat {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ids := make(map[uint]bool)
+	for id := range p.stats {
+		ids[id] = true
+	}
+	for id := range p.targets {
+		ids[id] = true
+	}
+	stats := make([]PoolStat, 0, len(ids))
+	for id := range ids {
+		st := *p.stat(id)
+		st.Target = p.targets[id].size
+		st.Ready = len(p.pool[id])
+		st.Pending = p.pending[id]
+		stats = append(stats, st)
+	}
+	slices.SortFunc(stats, func(a, b PoolStat) int { return cmp.Compare(a.ChallengeID, b.ChallengeID) })
+	return stats
+}
+
+func (p *pool) wake() {
+	select {
+	case p.wakeUp <- struct{}{}:
+	default:
+	}
+}
+
+// run keeps the pool in line with the challenges, on every interval and whenever
+// something was taken out or the challenges changed
+func (p *pool) run() {
+	for {
+		p.reconcile()
+		interval := values.GetConfig().Docker.WarmPoolInterval
+		if interval <= 0 {
+			interval = defaultWarmInterval
+		}
+		select {
+		case <-time.After(interval):
+		case <-p.wakeUp:
+		}
+	}
+}
+
+func loadWarmTargets() (map[uint]warmTarget, error) {
+	var challenges []models.Challenge
+	if err := models.DB.Preload("DynamicConfig").Where("is_static = ? AND is_visible = ?", false, true).Find(&challenges).Error; err != nil {
+		return nil, err
+	}
+	defaultSize := values.GetConfig().Docker.WarmPoolSize
+	targets := make(map[uint]warmTarget, len(challenges))
+	for _, challenge := range challenges {
+		dc := challenge.DynamicConfig
+		if dc == nil {
+			continue
+		}
+		size := defaultSize
+		if dc.WarmPool != nil {
+			size = *dc.WarmPool
+		}
+		targets[challenge.ID] = warmTarget{
+			size:   max(size, 0),
+			spec:   specOf(dc),
+			config: *dc,
+			ttl:    time.Duration(dc.TTL),
+		}
+	}
+	return targets, nil
+}
+
+// reconcile evicts containers of challenges that were hidden, deleted or changed and
+// starts filling the ones that are short
+func (p *pool) reconcile() {
+	targets, err := p.loadTargets()
+	if err != nil {
+		logrus.Errorf("Failed to load warm pool targets: %v", err)
+		return
+	}
+	var evicted []*container
+	p.mu.Lock()
+	p.targets = targets
+	for id, containers := range p.pool {
+		target, ok := targets[id]
+		keep := containers[:0]
+		for _, ctr := range containers {
+			if ok && ctr.spec == target.spec && len(keep) < p.capacity(id) {
+				keep = append(keep, ctr)
+				continue
+			}
+			evicted = append(evicted, ctr)
+			p.stat(id).Evicted++
+		}
+		if len(keep) == 0 {
+			delete(p.pool, id)
+		} else {
+			p.pool[id] = keep
+		}
+	}
+	for id, target := range targets {
+		missing := target.size - len(p.pool[id]) - p.pending[id]
+		if missing <= 0 {
+			continue
+		}
+		p.pending[id] += missing
+		go p.fill(id, target, missing)
+	}
+	p.mu.Unlock()
+	for _, ctr := range evicted {
+		ctr.Discard()
+	}
+}
+
+// fill creates containers for one challenge one after another, a broken image fails
+// once per round instead of all at once
+func (p *pool) fill(challengeID uint, target warmTarget, count int) {
+	for i := range count {
+		ctr, err := p.create(challengeID, target)
+		p.mu.Lock()
+		p.pending[challengeID]--
+		if err != nil {
+			p.stat(challengeID).Failed++
+			// the rest of this round is given up, the next one tries again
+			p.pending[challengeID] -= count - i - 1
+			p.mu.Unlock()
+			logrus.Errorf("Failed to warm a container for challenge %d: %v", challengeID, err)
+			return
+		}
+		current, ok := p.targets[challengeID]
+		if !ok || current.spec != target.spec || len(p.pool[challengeID]) >= current.size {
+			p.stat(challengeID).Evicted++
+			p.mu.Unlock()
+			ctr.Discard()
+			continue
+		}
+		p.pool[challengeID] = append(p.pool[challengeID], ctr)
+		p.stat(challengeID).Created++
+		p.mu.Unlock()
+	}
+}
+
+func warmContainer(challengeID uint, target warmTarget) (*container, error) {
+	limits, err := containerLimits(values.GetConfig().Docker.Sandbox, &target.config)
+	if err != nil {
+		return nil, err
+	}
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	name := fmt.Sprintf("warm-%d-%s", challengeID, hex.EncodeToString(suffix))
+	ctr, err := newContainer(context.Background(), challengeID, name, &target.config, target.ttl, limits, warmLabel)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctr.Start(); err != nil {
+		ctr.Discard()
+		return nil, err
+	}
+	return ctr, nil
+}
+
+// RemoveWarmLeftovers removes the warm containers a previous run of this instance left
+// behind that no sandbox took over. It has to run before docker.ReconcilePorts, their host ports
+// would stay reserved for good otherwise.
+func RemoveWarmLeftovers(ctx context.Context) (int, error) {
+	var labels []string
+	for key, value := range warmLabel {
+		labels = append(labels, key+"="+value)
+	}
+	containers, err := docker.ListLabeled(ctx, labels...)
+	if err != nil || len(containers) == 0 {
+		return 0, err
+	}
+	var records []models.Container
+	if err := models.DB.Select("container_id, service_ids").Find(&records).Error; err != nil {
+		return 0, err
+	}
+	// a warm container handed to a sandbox is recovered along with it
+	taken := make(map[string]bool)
+	for _, record := range records {
+		taken[record.ContainerID] = true
+		for _, id := range record.ServiceIDs {
+			taken[id] = true
+		}
+	}
+	removed := 0
+	for _, ctr := range containers {
+		if taken[ctr.ID] || !docker.OwnedByInstance(ctr.Labels) {
+			continue
+		}
+		if err := docker.RemoveContainer(ctx, ctr.ID); err != nil {
+			logrus.Errorf("Failed to remove leftover warm container %s: %v", ctr.ID, err)
+			continue
+		}
+		removed++
+	}
+	// the networks of removed service groups are empty now
+	if err := docker.PruneNetworks(ctx, 0); err != nil {
+		logrus.Errorf("Failed to prune networks: %v", err)
+	}
+	return removed, nil
+}
+
+// RefreshWarmPool makes the pool pick up challenge changes now instead of on its
+// next round
+func RefreshWarmPool() {
+	containerPool.wake()
+}
+
+// WarmPoolStats reports the warm pool of every dynamic challenge
+func WarmPoolStats() []PoolStat {
+	return containerPool.Stats()
+}
@@ -0,0 +1,165 @@
+package sandbox
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/intraware/rodan/internal/config"
+	"github.com/intraware/rodan/internal/models"
+	"github.com/intraware/rodan/internal/utils/values"
+)
+
+// testPool is a pool whose targets are set by the test and whose containers are
+// fakes without any docker container behind them
+func testPool(t *testing.T, targets map[uint]warmTarget) *pool {
+	t.Helper()
+	var cfg config.Config
+	cfg.Docker.PoolSize = 1
+	values.SetConfig(&cfg)
+	p := newPool()
+	p.loadTargets = func() (map[uint]warmTarget, error) { return targets, nil }
+	p.create = func(challengeID uint, target warmTarget) (*container, error) {
+		return &container{ChallengeID: challengeID, spec: target.spec}, nil
+	}
+	return p
+}
+
+func target(size int, image string) warmTarget {
+	dc := models.DynamicConfig{DockerImage: image, WarmPool: &size}
+	return warmTarget{size: size, spec: specOf(&dc), config: dc}
+}
+
+// settle waits for the fills started by reconcile to finish
+func settle(t *testing.T, p *pool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		p.mu.Lock()
+		pending := 0
+		for _, n := range p.pending {
+			pending += n
+		}
+		p.mu.Unlock()
+		if pending == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the pool did not finish filling")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func stat(p *pool, challengeID uint) PoolStat {
+	for _, st := range p.Stats() {
+		if st.ChallengeID == challengeID {
+			return st
+		}
+	}
+	return PoolStat{}
+}
+
+func TestPoolFillAndAquire(t *testing.T) {
+	p := testPool(t, map[uint]warmTarget{1: target(2, "web")})
+	p.reconcile()
+	settle(t, p)
+	if st := stat(p, 1); st.Ready != 2 || st.Created != 2 || st.Target != 2 {
+		t.Fatalf("expected two warm containers, got %+v", st)
+	}
+	for range 2 {
+		if _, err := p.Aquire(1); err != nil {
+			t.Fatalf("expected a warm container, got %v", err)
+		}
+	}
+	if _, err := p.Aquire(1); !errors.Is(err, errNoContainers) {
+		t.Fatalf("expected an empty pool, got %v", err)
+	}
+	if st := stat(p, 1); st.Hits != 2 || st.Misses != 1 || st.Ready != 0 {
+		t.Fatalf("unexpected stats after taking them out: %+v", st)
+	}
+	// the next round fills it up again
+	p.reconcile()
+	settle(t, p)
+	if st := stat(p, 1); st.Ready != 2 || st.Created != 4 {
+		t.Fatalf("expected the pool to be refilled, got %+v", st)
+	}
+}
+
+func TestPoolReconcileEvicts(t *testing.T) {
+	targets := map[uint]warmTarget{1: target(2, "web"), 2: target(1, "pwn")}
+	p := testPool(t, targets)
+	p.reconcile()
+	settle(t, p)
+
+	// challenge 1 changed its image, challenge 2 was hidden
+	targets[1] = target(2, "web:v2")
+	delete(targets, 2)
+	p.reconcile()
+	settle(t, p)
+	if st := stat(p, 1); st.Evicted != 2 || st.Ready != 2 {
+		t.Fatalf("expected the old containers to be replaced, got %+v", st)
+	}
+	for _, ctr := range p.pool[1] {
+		if ctr.spec != targets[1].spec {
+			t.Fatal("expected only containers of the new config to be pooled")
+		}
+	}
+	if st := stat(p, 2); st.Evicted != 1 || st.Ready != 0 {
+		t.Fatalf("expected the hidden challenge to be emptied, got %+v", st)
+	}
+
+	// shrinking the pool keeps what still fits
+	targets[1] = target(1, "web:v2")
+	p.reconcile()
+	settle(t, p)
+	if st := stat(p, 1); st.Evicted != 3 || st.Ready != 1 {
+		t.Fatalf("expected a resize to keep one container, got %+v", st)
+	}
+}
+
+func TestPoolFillFailure(t *testing.T) {
+	p := testPool(t, map[uint]warmTarget{1: target(3, "web")})
+	p.create = func(uint, warmTarget) (*container, error) { return nil, errImageNotExists }
+	p.reconcile()
+	settle(t, p)
+	if st := stat(p, 1); st.Failed != 1 || st.Ready != 0 || st.Pending != 0 {
+		t.Fatalf("expected one failure per round and nothing pending, got %+v", st)
+	}
+}
+
+func TestPoolRelease(t *testing.T) {
+	p := testPool(t, map[uint]warmTarget{1: target(1, "web")})
+	p.reconcile()
+	settle(t, p)
+	old := &container{ChallengeID: 1, spec: target(1, "web:v1").spec}
+	if err := p.Release(old); !errors.Is(err, errPoolFull) {
+		t.Fatalf("expected a container of another config to be refused, got %v", err)
+	}
+	current := &container{ChallengeID: 1, spec: p.targets[1].spec}
+	if err := p.Release(current); !errors.Is(err, errPoolFull) {
+		t.Fatalf("expected a full pool to refuse it, got %v", err)
+	}
+	if _, err := p.Aquire(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Release(current); err != nil {
+		t.Fatalf("expected it to be taken back, got %v", err)
+	}
+}
+
+func TestSpecOfIgnoresPoolSize(t *testing.T) {
+	small, large := 1, 5
+	a := models.DynamicConfig{DockerImage: "web", WarmPool: &small}
+	b := models.DynamicConfig{DockerImage: "web", WarmPool: &large}
+	if specOf(&a) != specOf(&b) {
+		t.Fatal("expected the pool size not to change the spec")
+	}
+	if a.WarmPool != &small {
+		t.Fatal("expected specOf to leave the config alone")
+	}
+	b.DockerImage = "web:v2"
+	if specOf(&a) == specOf(&b) {
+		t.Fatal("expected a new image to change the spec")
+	}
+}
@@ -0,0 +1,81 @@
+package sandbox
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/intraware/rodan/internal/models"
+	"github.com/intraware/rodan/internal/proxy"
+	"github.com/intraware/rodan/internal/utils/docker"
+	"github.com/intraware/rodan/internal/utils/values"
+)
+
+// openRoutes points the sandbox hostnames at its containers. Labels are the token
+// alone for a single port, token-<port> for a single container and
+// token-<service>-<port> for multi-container challenges.
+func (s *SandBox) openRoutes() error {
+	if !values.GetConfig().Docker.Proxy.Enabled || s.Container == nil {
+		return nil
+	}
+	s.closeRoutes()
+	if s.ProxyToken == "" {
+		s.ProxyToken = proxy.NewToken()
+	}
+	dc := s.ChallengeMeta.DynamicConfig
+	services := dc.Services
+	if len(services) == 0 {
+		services = []models.SandboxService{{Ports: dc.ExposedPorts}}
+	}
+	total := 0
+	for _, svc := range services {
+		total += len(svc.Ports)
+	}
+	for i, svc := range services {
+		if len(svc.Ports) == 0 || i >= len(s.Container.ServiceIDs) {
+			continue
+		}
+		ip, err := docker.ContainerIP(s.Container.Context, s.Container.ServiceIDs[i], s.Container.NetworkID)
+		if err != nil {
+			s.closeRoutes()
+			return err
+		}
+		for _, port := range svc.Ports {
+			label := s.ProxyToken
+			switch {
+			case total == 1:
+			case svc.Name == "":
+				label = fmt.Sprintf("%s-%s", s.ProxyToken, port)
+			default:
+				label = fmt.Sprintf("%s-%s-%s", s.ProxyToken, svc.Name, port)
+			}
+			isHTTP := slices.Contains(dc.ProxyHTTPPorts, port)
+			proxy.Register(label, proxy.Route{Target: ip + ":" + port, HTTP: isHTTP})
+			s.routes = append(s.routes, label)
+			s.links = append(s.links, proxy.Link(label, isHTTP))
+		}
+	}
+	return nil
+}
+
+// ValidateProxyPorts checks that the ports marked HTTP are ports the challenge exposes
+func ValidateProxyPorts(dc models.DynamicConfig) error {
+	exposed := dc.ExposedPorts
+	if len(dc.Services) > 0 {
+		exposed = nil
+		for _, svc := range dc.Services {
+			exposed = append(exposed, svc.Ports...)
+		}
+	}
+	for _, port := range dc.ProxyHTTPPorts {
+		if !slices.Contains(exposed, port) {
+			return fmt.Errorf("proxy http port %s is not exposed", port)
+		}
+	}
+	return nil
+}
+
+func (s *SandBox) closeRoutes() {
+	proxy.Unregister(s.routes...)
+	s.routes = nil
+	s.links = nil
+}
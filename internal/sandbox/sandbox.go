@@ -7,10 +7,33 @@ import (
 	"time"
 
 	"github.com/intraware/rodan/internal/models"
+	"github.com/intraware/rodan/internal/ports"
 	"github.com/intraware/rodan/internal/utils/docker"
+	"github.com/intraware/rodan/internal/utils/values"
+	"github.com/sirupsen/logrus"
 )
 
 var containerPool = newPool()
+var boxCleaner *cleaner
+
+// Init starts the sandbox cleaner and returns the sandboxes recovered from a previous run.
+// The orphan cleaner is only started after recovery so reattached containers are never killed.
+func Init(ctx context.Context) ([]*SandBox, error) {
+	boxCleaner = newCleaner()
+	boxes, err := recoverSandBoxes(ctx)
+	for _, box := range boxes {
+		if rerr := box.openRoutes(); rerr != nil {
+			logrus.Errorf("Failed to route recovered sandbox for team %d challenge %d: %v", box.TeamID, box.ChallengeMeta.ID, rerr)
+		}
+		boxCleaner.Add(box)
+		box.scheduleExpiryWarning()
+	}
+	if values.GetConfig().Docker.CleanOrphaned {
+		go boxCleaner.clean_orphan()
+	}
+	go containerPool.run()
+	return boxes, err
+}
 
 type SandBox struct {
 	UserID        uint
@@ -22,6 +45,10 @@ type SandBox struct {
 	Flag          string
 	Context       context.Context
 	CancelFunc    context.CancelFunc
+	ProxyToken    string // hostname prefix behind the sandbox proxy
+	warnTimer     *time.Timer
+	routes        []string
+	links         []string
 }
 
 func NewSandBox(userID, teamID uint, challenge *models.Challenge, flag string) *SandBox {
@@ -54,17 +81,25 @@ func (s *SandBox) Start() error {
 	}
 	if ctr == nil && errors.Is(err, errNoContainers) {
 		containerName := fmt.Sprintf("%d-%d-%d", s.UserID, s.TeamID, s.ChallengeMeta.ID)
-		ctr, err = newContainer(
-			ctx,
-			s.ChallengeMeta.ID,
-			containerName,
-			s.ChallengeMeta.DynamicConfig.DockerImage,
-			ttl,
-			s.ChallengeMeta.DynamicConfig.ExposedPorts,
-		)
+		var limits docker.ContainerLimits
+		limits, err = containerLimits(values.GetConfig().Docker.Sandbox, s.ChallengeMeta.DynamicConfig)
+		if err == nil {
+			ctr, err = newContainer(
+				ctx,
+				s.ChallengeMeta.ID,
+				containerName,
+				s.ChallengeMeta.DynamicConfig,
+				ttl,
+				limits,
+				nil,
+			)
+		}
 		if err != nil {
 			cancel()
 			s.CancelFunc = nil
+			if errors.Is(err, ports.ErrExhausted) {
+				return ErrNoFreePorts
+			}
 			return ErrFailedToCreateContainer
 		}
 	}
@@ -83,11 +118,25 @@ func (s *SandBox) Start() error {
 		return ErrFailedToStartContainer
 	}
 	s.Container = ctr
+	if err = s.openRoutes(); err != nil {
+		s.Container = nil
+		ctr.Discard()
+		cancel()
+		s.CancelFunc = nil
+		return ErrFailedToStartContainer
+	}
 	s.Active = true
+	s.persist()
+	s.scheduleExpiryWarning()
+	if boxCleaner != nil {
+		boxCleaner.Add(s)
+	}
 	return nil
 }
 
 func (s *SandBox) Stop() error {
+	s.cancelExpiryWarning()
+	s.closeRoutes()
 	if s.CancelFunc != nil {
 		s.CancelFunc()
 		s.CancelFunc = nil
@@ -114,52 +163,84 @@ func (s *SandBox) Stop() error {
 	}
 	s.Container = nil
 	s.Active = false
+	s.forget()
 	return nil
 }
 
+// Regenerate replaces the containers of a running sandbox with fresh ones made from
+// challenge. Once the old ones are gone a failure leaves the sandbox stopped, so it
+// can be started again.
 func (s *SandBox) Regenerate(challenge *models.Challenge) (err error) {
 	err = nil
 	if s.Container == nil {
 		err = ErrContainerNotFound
 		return
 	}
+	s.closeRoutes()
 	s.Container.Stop()
 	err = s.Container.Discard()
 	if err != nil {
 		err = ErrFailedToDiscardContainer
 		return
 	}
+	s.Container = nil
+	defer func() {
+		if err != nil {
+			s.cancelExpiryWarning()
+			if s.CancelFunc != nil {
+				s.CancelFunc()
+				s.CancelFunc = nil
+			}
+			s.Active = false
+			s.forget()
+		}
+	}()
+	if challenge != nil && challenge.DynamicConfig != nil {
+		s.ChallengeMeta = *challenge
+	}
+	dc := s.ChallengeMeta.DynamicConfig
 	var ctr *container
 	containerName := fmt.Sprintf("%d-%d-%d", s.UserID, s.TeamID, s.ChallengeMeta.ID)
-	ttl := time.Duration(challenge.DynamicConfig.TTL)
+	ttl := time.Duration(dc.TTL)
+	if s.CancelFunc != nil {
+		s.CancelFunc()
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), ttl)
 	s.Context = ctx
 	s.CancelFunc = cancel
-	ctr, err = newContainer(
-		s.Context,
-		s.ChallengeMeta.ID,
-		containerName,
-		s.ChallengeMeta.DynamicConfig.DockerImage,
-		time.Duration(s.ChallengeMeta.DynamicConfig.TTL),
-		s.ChallengeMeta.DynamicConfig.ExposedPorts,
-	)
+	limits, err := containerLimits(values.GetConfig().Docker.Sandbox, dc)
+	if err == nil {
+		ctr, err = newContainer(s.Context, s.ChallengeMeta.ID, containerName, dc, ttl, limits, nil)
+	}
+	if errors.Is(err, ports.ErrExhausted) {
+		err = ErrNoFreePorts
+		return
+	}
 	if err != nil {
 		err = ErrFailedToCreateContainer
 		return
 	}
 	err = ctr.GenerateFlag(s.Flag)
 	if err != nil {
-		ctr.Stop()
+		ctr.Discard()
 		err = ErrFailedToGenerateFlag
 		return
 	}
 	err = ctr.Start()
 	if err != nil {
-		ctr.Stop()
+		ctr.Discard()
 		err = ErrFailedToStartContainer
 		return
 	}
 	s.Container = ctr
+	if err = s.openRoutes(); err != nil {
+		s.Container = nil
+		ctr.Discard()
+		err = ErrFailedToStartContainer
+		return
+	}
+	s.persist()
+	s.scheduleExpiryWarning()
 	return
 }
 
@@ -172,17 +253,22 @@ func (s *SandBox) ExtendTTL() {
 	s.Context = ctx
 	s.CancelFunc = cancel
 	s.Container.StartedAt = time.Now()
+	s.persist()
+	s.scheduleExpiryWarning()
 }
 
 func (s *SandBox) GetMeta() (SandBoxResponse, error) {
 	var response SandBoxResponse
-	ports, err := docker.GetBoundPorts(s.Context, s.Container.ContainerID)
-	if err != nil {
-		return response, err
-	}
-	response.Ports = make([]string, 0, len(ports))
-	for _, port := range ports {
-		response.Ports = append(response.Ports, port)
+	response.Ports = []string{}
+	response.Links = s.links
+	for _, id := range s.Container.ServiceIDs {
+		ports, err := docker.GetBoundPorts(s.Context, id)
+		if err != nil {
+			return response, err
+		}
+		for _, port := range ports {
+			response.Ports = append(response.Ports, port)
+		}
 	}
 	expiryTime := s.Container.StartedAt.Add(s.Container.TTL)
 	timeLeft := time.Until(expiryTime).Seconds()
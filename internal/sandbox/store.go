@@ -0,0 +1,141 @@
+package sandbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/intraware/rodan/internal/models"
+	"github.com/intraware/rodan/internal/utils/docker"
+	"github.com/sirupsen/logrus"
+)
+
+func (s *SandBox) persist() {
+	if models.DB == nil || s.Container == nil {
+		return
+	}
+	deadline, _ := s.Context.Deadline()
+	record := models.Container{
+		UserID:      s.UserID,
+		TeamID:      s.TeamID,
+		ChallengeID: s.ChallengeMeta.ID,
+		ContainerID: s.Container.ContainerID,
+		ServiceIDs:  s.Container.ServiceIDs,
+		NetworkID:   s.Container.NetworkID,
+		ProxyToken:  s.ProxyToken,
+		Flag:        s.Flag,
+		ExpiresAt:   deadline,
+	}
+	tx := models.DB.Begin()
+	if err := tx.Unscoped().
+		Where("(team_id = ? AND challenge_id = ?) OR container_id = ?", s.TeamID, s.ChallengeMeta.ID, record.ContainerID).
+		Delete(&models.Container{}).Error; err != nil {
+		tx.Rollback()
+		logrus.Errorf("Failed to clear sandbox record for team %d challenge %d: %v", s.TeamID, s.ChallengeMeta.ID, err)
+		return
+	}
+	if err := tx.Create(&record).Error; err != nil {
+		tx.Rollback()
+		logrus.Errorf("Failed to persist sandbox for team %d challenge %d: %v", s.TeamID, s.ChallengeMeta.ID, err)
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		logrus.Errorf("Failed to persist sandbox for team %d challenge %d: %v", s.TeamID, s.ChallengeMeta.ID, err)
+	}
+}
+
+func (s *SandBox) forget() {
+	if models.DB == nil {
+		return
+	}
+	if err := models.DB.Unscoped().
+		Where("team_id = ? AND challenge_id = ?", s.TeamID, s.ChallengeMeta.ID).
+		Delete(&models.Container{}).Error; err != nil {
+		logrus.Errorf("Failed to remove sandbox record for team %d challenge %d: %v", s.TeamID, s.ChallengeMeta.ID, err)
+	}
+}
+
+// recoverSandBoxes reattaches containers that were running before a restart.
+// Records whose container is gone or whose TTL has run out are cleaned up.
+func recoverSandBoxes(ctx context.Context) ([]*SandBox, error) {
+	containers, err := docker.ListContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	running := make(map[string]bool, len(containers))
+	for _, ctr := range containers {
+		running[ctr.ID] = string(ctr.State) == "running"
+	}
+	var records []models.Container
+	if err := models.DB.Find(&records).Error; err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	var boxes []*SandBox
+	for _, record := range records {
+		ids := record.ServiceIDs
+		if len(ids) == 0 {
+			ids = []string{record.ContainerID}
+		}
+		// a group is only reattached when every one of its containers survived
+		healthy := record.ExpiresAt.After(now)
+		for _, id := range ids {
+			healthy = healthy && running[id]
+		}
+		var challenge models.Challenge
+		if healthy {
+			err := models.DB.Preload("DynamicConfig").First(&challenge, record.ChallengeID).Error
+			healthy = err == nil && challenge.DynamicConfig != nil
+		}
+		if !healthy {
+			for _, id := range ids {
+				if _, exists := running[id]; exists {
+					docker.RemoveContainer(ctx, id)
+				}
+			}
+			if record.NetworkID != "" {
+				docker.RemoveNetwork(ctx, record.NetworkID)
+			}
+			models.DB.Unscoped().Delete(&record)
+			continue
+		}
+		boxes = append(boxes, restoreSandBox(record, ids, &challenge))
+	}
+	return boxes, nil
+}
+
+func restoreSandBox(record models.Container, ids []string, challenge *models.Challenge) *SandBox {
+	ttl := time.Duration(challenge.DynamicConfig.TTL)
+	ctx, cancel := context.WithDeadline(context.Background(), record.ExpiresAt)
+	return &SandBox{
+		UserID:        record.UserID,
+		TeamID:        record.TeamID,
+		ChallengeMeta: *challenge,
+		Container: &container{
+			Context:     context.Background(),
+			ContainerID: record.ContainerID,
+			ServiceIDs:  ids,
+			FlagIDs:     flagIDs(challenge.DynamicConfig, record.ContainerID, ids),
+			NetworkID:   record.NetworkID,
+			ImageName:   challenge.DynamicConfig.DockerImage,
+			ChallengeID: challenge.ID,
+			TTL:         ttl,
+			StartedAt:   record.ExpiresAt.Add(-ttl),
+			spec:        specOf(challenge.DynamicConfig),
+		},
+		CreatedAt:  record.CreatedAt,
+		Active:     true,
+		Flag:       record.Flag,
+		ProxyToken: record.ProxyToken,
+		Context:    ctx,
+		CancelFunc: cancel,
+	}
+}
+
+// flagIDs finds the flag containers of a recovered sandbox, records only keep the
+// container ids in service order
+func flagIDs(dc *models.DynamicConfig, containerID string, ids []string) []string {
+	if len(dc.Services) != len(ids) {
+		return []string{containerID}
+	}
+	return flagTargets(dc.Services, ids)
+}
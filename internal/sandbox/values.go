@@ -7,6 +7,7 @@ var errNoContainers = errors.New("no containers exist")
 var errPoolFull = errors.New("container pool is full")
 
 var ErrFailedToCreateContainer = errors.New("Failed to create a new container")
+var ErrNoFreePorts = errors.New("No free host ports left for a new container")
 var ErrContainerNotFound = errors.New("Container not found")
 var ErrFailedToDiscardContainer = errors.New("Failed to discard container")
 var ErrFailedToStartContainer = errors.New("Failed to start the created container")
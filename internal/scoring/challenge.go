@@ -0,0 +1,69 @@
+package scoring
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/intraware/rodan/internal/models"
+	"github.com/intraware/rodan/internal/utils/values"
+)
+
+const (
+	initialBackoff = time.Minute
+	maxBackoff     = 1 * time.Hour
+)
+
+var (
+	playerCount     atomic.Int64
+	playerUpdatedAt atomic.Int64
+	playerBackoff   atomic.Int64
+)
+
+// ChallengePoints prices a challenge with the strategy it was configured with
+func ChallengePoints(challenge models.Challenge, solves, players int) int {
+	strategy, err := Get(challenge.Scoring)
+	if err != nil {
+		strategy, _ = Get(DefaultStrategy)
+	}
+	return strategy.Points(Params{
+		Solves:    solves,
+		Players:   players,
+		MinPoints: challenge.PointsMin,
+		MaxPoints: challenge.PointsMax,
+		Decay:     challenge.Decay,
+	})
+}
+
+// PlayerCount returns the number of players that count towards decay.
+// The value is cached and the refresh interval backs off while it stays the same.
+func PlayerCount() int {
+	now := time.Now()
+	last := time.Unix(0, playerUpdatedAt.Load())
+	backoff := time.Duration(playerBackoff.Load())
+	if backoff == 0 {
+		backoff = initialBackoff
+	}
+	if time.Since(last) < backoff {
+		return int(playerCount.Load())
+	}
+	lbCfg := values.GetConfig().App.Leaderboard
+	query := models.DB.Model(&models.User{})
+	if len(lbCfg.UserBlackList) > 0 {
+		query = query.Where("id NOT IN (?)", lbCfg.UserBlackList)
+	}
+	if len(lbCfg.TeamBlackList) > 0 {
+		query = query.Where("team_id NOT IN (?)", lbCfg.TeamBlackList)
+	}
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return int(playerCount.Load())
+	}
+	if count == playerCount.Load() {
+		playerBackoff.Store(int64(min(backoff*2, maxBackoff)))
+	} else {
+		playerCount.Store(count)
+		playerBackoff.Store(int64(initialBackoff))
+	}
+	playerUpdatedAt.Store(now.UnixNano())
+	return int(count)
+}
@@ -0,0 +1,78 @@
+package scoring
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Params are the inputs a strategy needs to price a challenge
+type Params struct {
+	Solves    int // current number of solves
+	Players   int // expected max solves, the number of players on the board
+	MinPoints int
+	MaxPoints int
+	Decay     int // strategy specific, see each implementation
+}
+
+type ScoringStrategy interface {
+	Name() string
+	Points(p Params) int
+}
+
+const (
+	Static      = "static"
+	Power       = "power"
+	Logarithmic = "logarithmic"
+	Linear      = "linear"
+)
+
+const DefaultStrategy = Power
+
+var (
+	strategiesMu sync.RWMutex
+	strategies   = map[string]ScoringStrategy{}
+)
+
+func init() {
+	Register(staticScore{})
+	Register(powerDecay{})
+	Register(logarithmicDecay{})
+	Register(linearDecay{})
+}
+
+func Register(strategy ScoringStrategy) {
+	strategiesMu.Lock()
+	defer strategiesMu.Unlock()
+	strategies[strategy.Name()] = strategy
+}
+
+// Get returns the strategy registered under name, an empty name resolves to the default strategy
+func Get(name string) (ScoringStrategy, error) {
+	if name == "" {
+		name = DefaultStrategy
+	}
+	strategiesMu.RLock()
+	defer strategiesMu.RUnlock()
+	strategy, ok := strategies[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown scoring strategy %q", name)
+	}
+	return strategy, nil
+}
+
+func Names() []string {
+	strategiesMu.RLock()
+	defer strategiesMu.RUnlock()
+	names := make([]string, 0, len(strategies))
+	for name := range strategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func Validate(name string) error {
+	_, err := Get(name)
+	return err
+}
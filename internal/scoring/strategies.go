@@ -0,0 +1,82 @@
+package scoring
+
+import (
+	"math"
+
+	"github.com/intraware/rodan/internal/utils/values"
+)
+
+func clamp(score float64, p Params) int {
+	score = max(score, float64(p.MinPoints))
+	score = min(score, float64(p.MaxPoints))
+	return int(math.Round(score))
+}
+
+// staticScore never decays
+type staticScore struct{}
+
+func (staticScore) Name() string { return Static }
+
+func (staticScore) Points(p Params) int {
+	return p.MaxPoints
+}
+
+// powerDecay gives the first `full-points-threshold` solvers full score, after that
+// the score decays smoothly with `decay-sharpness` as the power until every player solved it
+type powerDecay struct{}
+
+func (powerDecay) Name() string { return Power }
+
+func (powerDecay) Points(p Params) int {
+	cfg := values.GetConfig().App.Leaderboard
+	offset := cfg.FullPointsThreshold
+	power := cfg.DecaySharpness
+	solves := p.Solves
+	total := p.Players
+	if solves <= offset || total <= offset {
+		return p.MaxPoints
+	}
+	if solves > total {
+		solves = total
+	}
+	x := float64(solves-offset) / float64(total-offset)
+	return clamp(float64(p.MaxPoints)-float64(p.MaxPoints-p.MinPoints)*math.Pow(x, power), p)
+}
+
+// logarithmicDecay is the CTFd style decay, the minimum is reached after `Decay` solves.
+// When Decay is not set the number of players is used.
+type logarithmicDecay struct{}
+
+func (logarithmicDecay) Name() string { return Logarithmic }
+
+func (logarithmicDecay) Points(p Params) int {
+	decay := p.Decay
+	if decay <= 0 {
+		decay = p.Players
+	}
+	if decay <= 0 {
+		return p.MaxPoints
+	}
+	// the first solve does not lower the value
+	solves := max(p.Solves-1, 0)
+	score := (float64(p.MinPoints-p.MaxPoints)/float64(decay*decay))*float64(solves*solves) + float64(p.MaxPoints)
+	return clamp(math.Ceil(score), p)
+}
+
+// linearDecay drops `Decay` points for every solve after the first.
+// When Decay is not set the range is spread evenly over the number of players.
+type linearDecay struct{}
+
+func (linearDecay) Name() string { return Linear }
+
+func (linearDecay) Points(p Params) int {
+	solves := max(p.Solves-1, 0)
+	step := float64(p.Decay)
+	if p.Decay <= 0 {
+		if p.Players <= 1 {
+			return p.MaxPoints
+		}
+		step = float64(p.MaxPoints-p.MinPoints) / float64(p.Players-1)
+	}
+	return clamp(float64(p.MaxPoints)-step*float64(solves), p)
+}
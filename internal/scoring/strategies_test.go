@@ -0,0 +1,98 @@
+package scoring_test
+
+import (
+	"testing"
+
+	"github.com/intraware/rodan/internal/config"
+	"github.com/intraware/rodan/internal/models"
+	"github.com/intraware/rodan/internal/scoring"
+	"github.com/intraware/rodan/internal/utils/values"
+)
+
+func setLeaderboard(threshold int, sharpness float64) {
+	var cfg config.Config
+	cfg.App.Leaderboard.FullPointsThreshold = threshold
+	cfg.App.Leaderboard.DecaySharpness = sharpness
+	values.SetConfig(&cfg)
+}
+
+func points(t *testing.T, strategy string, p scoring.Params) int {
+	t.Helper()
+	s, err := scoring.Get(strategy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s.Points(p)
+}
+
+func TestStrategyPoints(t *testing.T) {
+	setLeaderboard(1, 2)
+	cases := []struct {
+		name     string
+		strategy string
+		params   scoring.Params
+		want     int
+	}{
+		{"static ignores solves", scoring.Static, scoring.Params{Solves: 500, Players: 10, MinPoints: 100, MaxPoints: 500}, 500},
+
+		{"power no solves", scoring.Power, scoring.Params{Solves: 0, Players: 100, MinPoints: 100, MaxPoints: 500}, 500},
+		{"power within threshold", scoring.Power, scoring.Params{Solves: 1, Players: 100, MinPoints: 100, MaxPoints: 500}, 500},
+		{"power no players", scoring.Power, scoring.Params{Solves: 5, Players: 0, MinPoints: 100, MaxPoints: 500}, 500},
+		{"power halfway", scoring.Power, scoring.Params{Solves: 6, Players: 11, MinPoints: 100, MaxPoints: 500}, 400},
+		{"power everyone solved", scoring.Power, scoring.Params{Solves: 11, Players: 11, MinPoints: 100, MaxPoints: 500}, 100},
+		{"power more solves than players", scoring.Power, scoring.Params{Solves: 1 << 20, Players: 11, MinPoints: 100, MaxPoints: 500}, 100},
+
+		{"logarithmic no solves", scoring.Logarithmic, scoring.Params{Solves: 0, Decay: 10, MinPoints: 100, MaxPoints: 500}, 500},
+		{"logarithmic first solve", scoring.Logarithmic, scoring.Params{Solves: 1, Decay: 10, MinPoints: 100, MaxPoints: 500}, 500},
+		{"logarithmic halfway", scoring.Logarithmic, scoring.Params{Solves: 6, Decay: 10, MinPoints: 100, MaxPoints: 500}, 400},
+		{"logarithmic at decay", scoring.Logarithmic, scoring.Params{Solves: 11, Decay: 10, MinPoints: 100, MaxPoints: 500}, 100},
+		{"logarithmic clamps to min", scoring.Logarithmic, scoring.Params{Solves: 1 << 20, Decay: 10, MinPoints: 100, MaxPoints: 500}, 100},
+		{"logarithmic falls back to players", scoring.Logarithmic, scoring.Params{Solves: 11, Players: 10, MinPoints: 100, MaxPoints: 500}, 100},
+		{"logarithmic no decay no players", scoring.Logarithmic, scoring.Params{Solves: 50, MinPoints: 100, MaxPoints: 500}, 500},
+
+		{"linear no solves", scoring.Linear, scoring.Params{Solves: 0, Decay: 25, MinPoints: 100, MaxPoints: 500}, 500},
+		{"linear steps", scoring.Linear, scoring.Params{Solves: 5, Decay: 25, MinPoints: 100, MaxPoints: 500}, 400},
+		{"linear clamps to min", scoring.Linear, scoring.Params{Solves: 1 << 20, Decay: 25, MinPoints: 100, MaxPoints: 500}, 100},
+		{"linear spread over players", scoring.Linear, scoring.Params{Solves: 3, Players: 5, MinPoints: 100, MaxPoints: 500}, 300},
+		{"linear single player", scoring.Linear, scoring.Params{Solves: 3, Players: 1, MinPoints: 100, MaxPoints: 500}, 500},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := points(t, tc.strategy, tc.params); got != tc.want {
+				t.Fatalf("expected %d, got %d", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestStrategiesStayInRange(t *testing.T) {
+	setLeaderboard(0, 1.5)
+	for _, name := range scoring.Names() {
+		prev := -1
+		for solves := 0; solves <= 300; solves++ {
+			got := points(t, name, scoring.Params{Solves: solves, Players: 200, Decay: 50, MinPoints: 50, MaxPoints: 500})
+			if got < 50 || got > 500 {
+				t.Fatalf("%s: %d solves gave %d, outside 50-500", name, solves, got)
+			}
+			if prev != -1 && got > prev {
+				t.Fatalf("%s: points went up from %d to %d at %d solves", name, prev, got, solves)
+			}
+			prev = got
+		}
+	}
+}
+
+func TestChallengePointsFallsBackToDefault(t *testing.T) {
+	setLeaderboard(0, 1)
+	got := scoring.ChallengePoints(challengeWith("no-such-strategy"), 0, 10)
+	if got != 500 {
+		t.Fatalf("expected the default strategy to give 500 with no solves, got %d", got)
+	}
+	if _, err := scoring.Get("no-such-strategy"); err == nil {
+		t.Fatal("expected an unknown strategy to be rejected")
+	}
+}
+
+func challengeWith(strategy string) models.Challenge {
+	return models.Challenge{Scoring: strategy, PointsMin: 100, PointsMax: 500}
+}
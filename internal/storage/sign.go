@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// Grant is what a download link allows: one attachment, for one member of a team,
+// until it expires
+type Grant struct {
+	AttachmentID uint
+	TeamID       uint
+	UserID       uint
+	Expires      int64
+}
+
+func (g Grant) payload() string {
+	return fmt.Sprintf("%d:%d:%d:%d", g.AttachmentID, g.TeamID, g.UserID, g.Expires)
+}
+
+// Sign returns the hex HMAC of the grant
+func Sign(secret string, g Grant) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(g.payload()))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature and that the grant has not expired
+func Verify(secret string, g Grant, signature string, now time.Time) bool {
+	if secret == "" || now.Unix() > g.Expires {
+		return false
+	}
+	expected, err := hex.DecodeString(Sign(secret, g))
+	if err != nil {
+		return false
+	}
+	got, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(expected, got)
+}
@@ -0,0 +1,59 @@
+package storage_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/intraware/rodan/internal/storage"
+)
+
+func TestVerify(t *testing.T) {
+	const secret = "download-secret"
+	now := time.Unix(1_700_000_000, 0)
+	grant := storage.Grant{AttachmentID: 4, TeamID: 2, UserID: 9, Expires: now.Add(time.Minute).Unix()}
+	signature := storage.Sign(secret, grant)
+	tampered := func(change func(*storage.Grant)) storage.Grant {
+		g := grant
+		change(&g)
+		return g
+	}
+	cases := []struct {
+		name      string
+		secret    string
+		grant     storage.Grant
+		signature string
+		now       time.Time
+		want      bool
+	}{
+		{"valid", secret, grant, signature, now, true},
+		{"at expiry", secret, grant, signature, time.Unix(grant.Expires, 0), true},
+		{"expired", secret, grant, signature, time.Unix(grant.Expires+1, 0), false},
+		{"wrong secret", "other-secret", grant, signature, now, false},
+		{"empty secret", "", grant, storage.Sign("", grant), now, false},
+		{"other attachment", secret, tampered(func(g *storage.Grant) { g.AttachmentID++ }), signature, now, false},
+		{"other team", secret, tampered(func(g *storage.Grant) { g.TeamID++ }), signature, now, false},
+		{"other user", secret, tampered(func(g *storage.Grant) { g.UserID++ }), signature, now, false},
+		{"extended expiry", secret, tampered(func(g *storage.Grant) { g.Expires += 3600 }), signature, now, false},
+		{"flipped signature", secret, grant, flipLast(signature), now, false},
+		{"truncated signature", secret, grant, signature[:len(signature)-2], now, false},
+		{"not hex", secret, grant, "zz" + signature[2:], now, false},
+		{"no signature", secret, grant, "", now, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := storage.Verify(tc.secret, tc.grant, tc.signature, tc.now); got != tc.want {
+				t.Fatalf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func flipLast(signature string) string {
+	last := signature[len(signature)-1]
+	if last == '0' {
+		last = '1'
+	} else {
+		last = '0'
+	}
+	return signature[:len(signature)-1] + string(last)
+}
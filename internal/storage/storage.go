@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/intraware/rodan/internal/config"
+	"github.com/intraware/rodan/internal/utils/values"
+)
+
+var ErrNotFound = errors.New("file not found")
+
+// Backend keeps attachment contents under opaque keys
+type Backend interface {
+	Put(ctx context.Context, key string, r io.Reader) (int64, error)
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+var (
+	backendLock   sync.Mutex
+	activeBackend Backend
+)
+
+// getBackend builds the configured backend the first time it is needed. The backend
+// is not reloadable, moving files between backends is left to the operator.
+func getBackend(cfg config.StorageConfig) (Backend, error) {
+	backendLock.Lock()
+	defer backendLock.Unlock()
+	if activeBackend != nil {
+		return activeBackend, nil
+	}
+	switch cfg.Backend {
+	case "", "local":
+		backend, err := newLocalBackend(cfg.LocalDir)
+		if err != nil {
+			return nil, err
+		}
+		activeBackend = backend
+	default:
+		return nil, errors.New("Invalid storage backend")
+	}
+	return activeBackend, nil
+}
+
+func Put(ctx context.Context, key string, r io.Reader) (int64, error) {
+	backend, err := getBackend(values.GetConfig().App.Storage)
+	if err != nil {
+		return 0, err
+	}
+	return backend.Put(ctx, key, r)
+}
+
+func Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	backend, err := getBackend(values.GetConfig().App.Storage)
+	if err != nil {
+		return nil, err
+	}
+	return backend.Open(ctx, key)
+}
+
+func Delete(ctx context.Context, key string) error {
+	backend, err := getBackend(values.GetConfig().App.Storage)
+	if err != nil {
+		return err
+	}
+	return backend.Delete(ctx, key)
+}
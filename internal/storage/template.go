@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+)
+
+// DefaultPlaceholder is swapped for the team's flag in templated attachments
+const DefaultPlaceholder = "{{FLAG}}"
+
+// MaxTemplateSize caps templated attachments, they are rendered in memory per download
+const MaxTemplateSize = 32 << 20
+
+var (
+	ErrTemplateTooLarge = errors.New("templated attachment is too large")
+	ErrNoPlaceholder    = errors.New("placeholder not found in attachment")
+)
+
+var zipMagic = []byte("PK\x03\x04")
+
+// ReadTemplate reads a templated attachment, refusing anything past MaxTemplateSize
+func ReadTemplate(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxTemplateSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxTemplateSize {
+		return nil, ErrTemplateTooLarge
+	}
+	return data, nil
+}
+
+// HasPlaceholder reports whether rendering the template would change anything. Zip
+// archives are searched entry by entry since their contents are usually compressed.
+func HasPlaceholder(data []byte, placeholder string) (bool, error) {
+	if !bytes.HasPrefix(data, zipMagic) {
+		return bytes.Contains(data, []byte(placeholder)), nil
+	}
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return false, err
+	}
+	for _, f := range archive.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		content, err := readEntry(f)
+		if err != nil {
+			return false, err
+		}
+		if bytes.Contains(content, []byte(placeholder)) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Render swaps every placeholder in the template for the flag. Zip archives are
+// rebuilt with the substitution applied inside each entry, other files are treated
+// as plain bytes.
+func Render(data []byte, placeholder, flag string) ([]byte, error) {
+	if !bytes.HasPrefix(data, zipMagic) {
+		return bytes.ReplaceAll(data, []byte(placeholder), []byte(flag)), nil
+	}
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	w := zip.NewWriter(&out)
+	w.SetComment(archive.Comment)
+	for _, f := range archive.File {
+		header := f.FileHeader
+		entry, err := w.CreateHeader(&header)
+		if err != nil {
+			return nil, err
+		}
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		content, err := readEntry(f)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := entry.Write(bytes.ReplaceAll(content, []byte(placeholder), []byte(flag))); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+func readEntry(f *zip.File) ([]byte, error) {
+	if f.UncompressedSize64 > MaxTemplateSize {
+		return nil, ErrTemplateTooLarge
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return ReadTemplate(rc)
+}
@@ -0,0 +1,113 @@
+package storage_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/intraware/rodan/internal/storage"
+)
+
+const flag = "rodan{0123456789abcdef}"
+
+func buildZip(t *testing.T, entries map[string]string, dirs ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	w.SetComment("challenge files")
+	for _, dir := range dirs {
+		if _, err := w.Create(dir + "/"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, content := range entries {
+		f, err := w.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func readZip(t *testing.T, data []byte) (map[string]string, string) {
+	t.Helper()
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("rendered archive does not open: %v", err)
+	}
+	entries := make(map[string]string)
+	for _, f := range archive.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries[f.Name] = string(content)
+	}
+	return entries, archive.Comment
+}
+
+func TestRenderZip(t *testing.T) {
+	template := buildZip(t, map[string]string{
+		"src/main.c":  `char *flag = "{{FLAG}}";`,
+		"README":      "no flag in here",
+		"notes/twice": "{{FLAG}} and {{FLAG}}",
+	}, "src", "notes")
+	if ok, err := storage.HasPlaceholder(template, storage.DefaultPlaceholder); err != nil || !ok {
+		t.Fatalf("expected the placeholder to be found inside the archive, got %v, %v", ok, err)
+	}
+	rendered, err := storage.Render(template, storage.DefaultPlaceholder, flag)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	entries, comment := readZip(t, rendered)
+	want := map[string]string{
+		"src/":        "",
+		"notes/":      "",
+		"src/main.c":  `char *flag = "` + flag + `";`,
+		"README":      "no flag in here",
+		"notes/twice": flag + " and " + flag,
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("expected %d entries, got %v", len(want), entries)
+	}
+	for name, content := range want {
+		if got, ok := entries[name]; !ok || got != content {
+			t.Fatalf("%s: expected %q, got %q", name, content, got)
+		}
+	}
+	if comment != "challenge files" {
+		t.Fatalf("expected the archive comment to be kept, got %q", comment)
+	}
+}
+
+func TestRenderPlain(t *testing.T) {
+	rendered, err := storage.Render([]byte("flag: {{FLAG}}\n"), storage.DefaultPlaceholder, flag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(rendered) != "flag: "+flag+"\n" {
+		t.Fatalf("unexpected render: %q", rendered)
+	}
+}
+
+func TestHasPlaceholderWithoutOne(t *testing.T) {
+	template := buildZip(t, map[string]string{"README": "nothing"})
+	if ok, err := storage.HasPlaceholder(template, storage.DefaultPlaceholder); err != nil || ok {
+		t.Fatalf("expected no placeholder, got %v, %v", ok, err)
+	}
+	if ok, _ := storage.HasPlaceholder([]byte("plain"), storage.DefaultPlaceholder); ok {
+		t.Fatal("expected no placeholder in plain data")
+	}
+}
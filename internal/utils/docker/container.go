@@ -2,15 +2,16 @@ package docker
 
 import (
 	"context"
-	"errors"
 	"fmt"
-	"math/rand"
-	"net"
+	"strconv"
 	"strings"
 
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
 	"github.com/docker/go-connections/nat"
+	"github.com/intraware/rodan/internal/ports"
 	"github.com/intraware/rodan/internal/utils/values"
 )
 
@@ -27,58 +28,77 @@ func StopContainer(ctx context.Context, containerID string) (err error) {
 	return
 }
 
-func randomPortInRange(minPort, maxPort int) (int, error) {
-	if minPort >= maxPort {
-		return 0, errors.New("invalid port range")
-	}
-	portRange := maxPort - minPort + 1
-	attempts := portRange * values.GetConfig().Docker.PortsMaxRetry
-	for range attempts {
-		port := rand.Intn(portRange) + minPort
-		addr := fmt.Sprintf(":%d", port)
-		ln, err := net.Listen("tcp", addr)
-		if err == nil {
-			ln.Close()
-			return port, nil
-		}
-	}
-	return 0, errors.New("no available port found in range")
+// ServiceOptions places a container on a sandbox network, reachable under Alias.
+// Labels are added to the created_by label every rodan container gets.
+type ServiceOptions struct {
+	Env     []string
+	Network string
+	Alias   string
+	Labels  map[string]string
+}
+
+func CreateContainer(ctx context.Context, containerName, imageName string, internalPorts []string, limits ContainerLimits) (containerID string, err error) {
+	return CreateServiceContainer(ctx, containerName, imageName, internalPorts, limits, ServiceOptions{})
 }
 
-func CreateContainer(ctx context.Context, containerName, imageName string, internalPorts []string) (containerID string, err error) {
+// CreateServiceContainer creates a container that publishes internalPorts, optionally
+// joined to a network. Services on the same network talk to each other on any port.
+func CreateServiceContainer(ctx context.Context, containerName, imageName string, internalPorts []string, limits ContainerLimits, opts ServiceOptions) (containerID string, err error) {
 	exposedPorts := nat.PortSet{}
 	portBindings := nat.PortMap{}
-	minPort := values.GetConfig().Docker.PortRange.Start
-	maxPort := values.GetConfig().Docker.PortRange.End
-	usedHostPorts := make(map[int]bool)
-	for _, internal := range internalPorts {
-		containerPort := nat.Port(internal + "/tcp")
-		var hostPort int
-		for {
-			hostPort, err = randomPortInRange(minPort, maxPort)
+	// behind the sandbox proxy nothing is published, players go through the proxy
+	var hostPorts []int
+	if !values.GetConfig().Docker.Proxy.Enabled && len(internalPorts) > 0 {
+		hostPorts, err = ports.Default().Lease(containerName, len(internalPorts))
+		if err != nil {
+			return
+		}
+		// the ports stay leased for as long as the container exists, RemoveContainer frees them
+		defer func() {
 			if err != nil {
-				return
-			}
-			if !usedHostPorts[hostPort] {
-				usedHostPorts[hostPort] = true
-				break
+				ports.Default().Release(hostPorts...)
 			}
+		}()
+	}
+	for i, internal := range internalPorts {
+		containerPort := nat.Port(internal + "/tcp")
+		exposedPorts[containerPort] = struct{}{}
+		if hostPorts == nil {
+			continue
 		}
 		portBindings[containerPort] = []nat.PortBinding{{
 			HostIP:   values.GetConfig().Docker.BindingHost,
-			HostPort: fmt.Sprintf("%d", hostPort),
+			HostPort: strconv.Itoa(hostPorts[i]),
 		}}
-		exposedPorts[containerPort] = struct{}{}
+	}
+	hostConfig := &container.HostConfig{
+		PortBindings: portBindings,
+	}
+	limits.apply(hostConfig)
+	var networking *network.NetworkingConfig
+	if opts.Network != "" {
+		hostConfig.NetworkMode = container.NetworkMode(opts.Network)
+		endpoint := &network.EndpointSettings{}
+		if opts.Alias != "" {
+			endpoint.Aliases = []string{opts.Alias}
+		}
+		networking = &network.NetworkingConfig{
+			EndpointsConfig: map[string]*network.EndpointSettings{opts.Network: endpoint},
+		}
+	}
+	labels := map[string]string{
+		"created_by":  "rodan",
+		instanceLabel: instanceID(),
+	}
+	for key, value := range opts.Labels {
+		labels[key] = value
 	}
 	resp, err := dockerClient.ContainerCreate(ctx, &container.Config{
 		Image:        imageName,
 		ExposedPorts: exposedPorts,
-		Labels: map[string]string{
-			"created_by": "rodan",
-		},
-	}, &container.HostConfig{
-		PortBindings: portBindings,
-	}, nil, nil, containerName)
+		Env:          opts.Env,
+		Labels:       labels,
+	}, hostConfig, networking, nil, containerName)
 	if err != nil {
 		return
 	}
@@ -87,15 +107,96 @@ func CreateContainer(ctx context.Context, containerName, imageName string, inter
 }
 
 func RemoveContainer(ctx context.Context, containerID string) (err error) {
+	published, _ := publishedPorts(ctx, containerID)
 	err = dockerClient.ContainerRemove(ctx, containerID, container.RemoveOptions{
 		Force: true,
 	})
+	if err == nil || client.IsErrNotFound(err) {
+		ports.Default().Release(published...)
+	}
 	return
 }
 
+// publishedPorts reads the host ports a container was created with. Unlike
+// GetBoundPorts it also works for stopped containers.
+func publishedPorts(ctx context.Context, containerID string) ([]int, error) {
+	info, err := dockerClient.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return nil, err
+	}
+	var hostPorts []int
+	if info.HostConfig == nil {
+		return hostPorts, nil
+	}
+	for _, bindings := range info.HostConfig.PortBindings {
+		for _, binding := range bindings {
+			if port, err := strconv.Atoi(binding.HostPort); err == nil {
+				hostPorts = append(hostPorts, port)
+			}
+		}
+	}
+	return hostPorts, nil
+}
+
+// ReconcilePorts hands the host ports of this instance's containers to the port
+// allocator, so nothing that survived a restart gets its ports leased twice. They are
+// reserved under the container name, the owner CreateServiceContainer leased them for.
+// Containers of other instances on the same docker host keep their own leases.
+func ReconcilePorts(ctx context.Context) (int, error) {
+	containers, err := ListContainers(ctx)
+	if err != nil {
+		return 0, err
+	}
+	reserved := 0
+	for _, ctr := range containers {
+		if !OwnedByInstance(ctr.Labels) || len(ctr.Names) == 0 {
+			continue
+		}
+		published, err := publishedPorts(ctx, ctr.ID)
+		if err != nil {
+			continue
+		}
+		ports.Default().Reserve(strings.TrimPrefix(ctr.Names[0], "/"), published...)
+		reserved += len(published)
+	}
+	return reserved, nil
+}
+
+const (
+	instanceLabel     = "rodan.instance"
+	defaultInstanceID = "rodan"
+)
+
+func instanceID() string {
+	if id := values.GetConfig().Docker.InstanceID; id != "" {
+		return id
+	}
+	return defaultInstanceID
+}
+
+// OwnedByInstance reports whether a container was created by this instance. Containers
+// from before the instance label only count as ours when no other instance leases
+// from the same docker host.
+func OwnedByInstance(labels map[string]string) bool {
+	id, ok := labels[instanceLabel]
+	if !ok {
+		return !ports.Default().Shared()
+	}
+	return id == instanceID()
+}
+
 func ListContainers(ctx context.Context) ([]container.Summary, error) {
+	return ListLabeled(ctx)
+}
+
+// ListLabeled lists the rodan containers that carry every one of labels, given as
+// key=value
+func ListLabeled(ctx context.Context, labels ...string) ([]container.Summary, error) {
 	filterArgs := filters.NewArgs()
 	filterArgs.Add("label", "created_by=rodan")
+	for _, label := range labels {
+		filterArgs.Add("label", label)
+	}
 	containers, err := dockerClient.ContainerList(ctx, container.ListOptions{
 		All:     true,
 		Filters: filterArgs,
@@ -167,3 +268,24 @@ func GetBoundPorts(ctx context.Context, containerID string) (map[string]string,
 	}
 	return bindings, nil
 }
+
+// ContainerIP returns the address of a container on the given network, or on any
+// network it is attached to when networkID is empty
+func ContainerIP(ctx context.Context, containerID, networkID string) (string, error) {
+	info, err := dockerClient.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return "", err
+	}
+	if info.NetworkSettings == nil {
+		return "", fmt.Errorf("container %s has no network settings", containerID)
+	}
+	for _, endpoint := range info.NetworkSettings.Networks {
+		if endpoint == nil || endpoint.IPAddress == "" {
+			continue
+		}
+		if networkID == "" || endpoint.NetworkID == networkID {
+			return endpoint.IPAddress, nil
+		}
+	}
+	return "", fmt.Errorf("container %s has no address", containerID)
+}
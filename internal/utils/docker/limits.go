@@ -0,0 +1,45 @@
+package docker
+
+import (
+	"github.com/docker/docker/api/types/container"
+)
+
+// ContainerLimits are the resource and security settings a container is created with
+type ContainerLimits struct {
+	MemoryMB        int64
+	CPUs            float64
+	PidsLimit       int64
+	CapAdd          []string
+	CapDrop         []string
+	ReadOnly        bool
+	Tmpfs           map[string]string // mount path to mount options
+	Seccomp         string            // profile json, or "unconfined", empty keeps the daemon default
+	NoNewPrivileges bool
+}
+
+func (l ContainerLimits) apply(hostConfig *container.HostConfig) {
+	if l.MemoryMB > 0 {
+		hostConfig.Memory = l.MemoryMB << 20
+		// no swap on top of the memory limit
+		hostConfig.MemorySwap = hostConfig.Memory
+	}
+	if l.CPUs > 0 {
+		hostConfig.NanoCPUs = int64(l.CPUs * 1e9)
+	}
+	if l.PidsLimit > 0 {
+		pids := l.PidsLimit
+		hostConfig.PidsLimit = &pids
+	}
+	hostConfig.CapAdd = l.CapAdd
+	hostConfig.CapDrop = l.CapDrop
+	hostConfig.ReadonlyRootfs = l.ReadOnly
+	if len(l.Tmpfs) > 0 {
+		hostConfig.Tmpfs = l.Tmpfs
+	}
+	if l.NoNewPrivileges {
+		hostConfig.SecurityOpt = append(hostConfig.SecurityOpt, "no-new-privileges:true")
+	}
+	if l.Seccomp != "" {
+		hostConfig.SecurityOpt = append(hostConfig.SecurityOpt, "seccomp="+l.Seccomp)
+	}
+}
@@ -0,0 +1,40 @@
+package docker
+
+import (
+	"context"
+	"time"
+
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+)
+
+// CreateNetwork creates a bridge network for the containers of one sandbox. Containers
+// on different sandbox networks cannot reach each other.
+func CreateNetwork(ctx context.Context, name string) (networkID string, err error) {
+	resp, err := dockerClient.NetworkCreate(ctx, name, network.CreateOptions{
+		Driver: "bridge",
+		Labels: map[string]string{
+			"created_by": "rodan",
+		},
+	})
+	if err != nil {
+		return
+	}
+	networkID = resp.ID
+	return
+}
+
+func RemoveNetwork(ctx context.Context, networkID string) (err error) {
+	err = dockerClient.NetworkRemove(ctx, networkID)
+	return
+}
+
+// PruneNetworks removes rodan networks without containers that are older than age,
+// younger ones may still be waiting for their containers
+func PruneNetworks(ctx context.Context, age time.Duration) (err error) {
+	filterArgs := filters.NewArgs()
+	filterArgs.Add("label", "created_by=rodan")
+	filterArgs.Add("until", age.String())
+	_, err = dockerClient.NetworksPrune(ctx, filterArgs)
+	return
+}
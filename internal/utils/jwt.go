@@ -36,7 +36,8 @@ func ValidateJWT(tokenString string, secret string) (*Claims, error) {
 	}); err != nil {
 		return nil, err
 	} else {
-		if claims, ok := token.Claims.(*Claims); ok && token.Valid {
+		// tokens with an audience are scoped tickets, not sessions
+		if claims, ok := token.Claims.(*Claims); ok && token.Valid && len(claims.Audience) == 0 {
 			return claims, nil
 		}
 	}
@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/intraware/rodan/api/shared"
+)
+
+// EventStartedMiddleware keeps challenges hidden until the configured event start
+func EventStartedMiddleware(ctx *gin.Context) {
+	if !shared.EventStarted() {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Event has not started yet"})
+		ctx.Abort()
+		return
+	}
+	ctx.Next()
+}
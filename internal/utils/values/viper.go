@@ -1,13 +1,16 @@
 package values
 
 import (
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"log"
 	"reflect"
+	"time"
 
 	"github.com/fsnotify/fsnotify"
+	"github.com/go-viper/mapstructure/v2"
 	"github.com/intraware/rodan/internal/config"
 	"github.com/spf13/viper"
 )
@@ -40,7 +43,48 @@ func reloadEqual(a, b any) bool {
 	return true
 }
 
+// decodeHook extends viper's defaults so quoted RFC3339 strings (e.g. from env) decode into time.Time
+var decodeHook = viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
+	mapstructure.StringToTimeDurationHookFunc(),
+	mapstructure.StringToSliceHookFunc(","),
+	mapstructure.StringToTimeHookFunc(time.RFC3339),
+))
+
+// setDefaults keeps sandboxes locked down and flag checks padded when a config file
+// leaves the settings out, an explicit value in the file still wins
+func setDefaults() {
+	viper.SetDefault("app.flag-verify-floor", 25*time.Millisecond)
+	viper.SetDefault("docker.sandbox.memory-mb", 256)
+	viper.SetDefault("docker.sandbox.cpus", 0.5)
+	viper.SetDefault("docker.sandbox.pids-limit", 256)
+	viper.SetDefault("docker.sandbox.cap-drop", []string{"ALL"})
+	viper.SetDefault("docker.sandbox.cap-add", []string{"CHOWN", "SETUID", "SETGID", "NET_BIND_SERVICE"})
+	viper.SetDefault("docker.sandbox.allowed-cap-add", []string{
+		"CHOWN", "DAC_OVERRIDE", "FOWNER", "FSETID", "KILL", "SETGID", "SETUID", "SETPCAP",
+		"NET_BIND_SERVICE", "NET_RAW", "SYS_CHROOT", "MKNOD", "AUDIT_WRITE", "SETFCAP",
+	})
+	viper.SetDefault("docker.sandbox.read-only", true)
+	viper.SetDefault("docker.sandbox.tmpfs", []string{"/tmp:rw,noexec,nosuid,size=64m"})
+}
+
+// fillFileSecret gives a config without file-secret the one in use, or a random one on
+// startup. Download links signed with a random secret stop working on a restart and
+// only work on the instance that signed them.
+func fillFileSecret(cfg *config.Config, current string) {
+	if cfg.Server.Security.FileSecret != "" {
+		return
+	}
+	if current == "" {
+		secret := make([]byte, 32)
+		rand.Read(secret)
+		current = hex.EncodeToString(secret)
+		log.Println("[CONFIG] file-secret is not set, signing attachment links with a random one until restart")
+	}
+	cfg.Server.Security.FileSecret = current
+}
+
 func InitWithViper(path string) error {
+	setDefaults()
 	viper.SetConfigFile(path)
 	viper.SetConfigType("toml")
 	viper.AutomaticEnv()
@@ -48,7 +92,7 @@ func InitWithViper(path string) error {
 		return fmt.Errorf("failed to read config: %w", err)
 	}
 	var cfg config.Config
-	if err := viper.Unmarshal(&cfg); err != nil {
+	if err := viper.Unmarshal(&cfg, decodeHook); err != nil {
 		return fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 	if cfg.App.Notification.HTTP != nil && cfg.App.Notification.HTTP.APIKey != "" {
@@ -59,6 +103,7 @@ func InitWithViper(path string) error {
 		hash := sha256.Sum256([]byte(cfg.App.Auth.ApiKey))
 		cfg.App.Auth.HashedAPIKey = hex.EncodeToString(hash[:])
 	}
+	fillFileSecret(&cfg, "")
 	if err := cfg.Validate(); err != nil {
 		return err
 	}
@@ -67,7 +112,7 @@ func InitWithViper(path string) error {
 	viper.OnConfigChange(func(e fsnotify.Event) {
 		log.Println("[CONFIG] Reloading due to change in:", e.Name)
 		var newCfg config.Config
-		if err := viper.Unmarshal(&newCfg); err != nil {
+		if err := viper.Unmarshal(&newCfg, decodeHook); err != nil {
 			log.Println("[CONFIG] Failed to reload config:", err)
 			return
 		}
@@ -79,11 +124,17 @@ func InitWithViper(path string) error {
 			hash := sha256.Sum256([]byte(newCfg.App.Auth.ApiKey))
 			newCfg.App.Auth.HashedAPIKey = hex.EncodeToString(hash[:])
 		}
+		oldCfg := GetConfig()
+		fillFileSecret(&newCfg, oldCfg.Server.Security.FileSecret)
 		if err := newCfg.Validate(); err != nil {
 			log.Println("[CONFIG] Validation failed after reload:", err)
 			return
 		}
-		oldCfg := GetConfig()
+		// stored flags are hashed with the secret, a new one would make them unsolvable
+		if newCfg.Server.Security.FlagSecret != oldCfg.Server.Security.FlagSecret {
+			log.Println("[CONFIG] Ignoring changed flag-secret: stored flag hashes are keyed with the current one, so static flags would stop matching")
+			newCfg.Server.Security.FlagSecret = oldCfg.Server.Security.FlagSecret
+		}
 		if !reloadEqual(oldCfg.Server, newCfg.Server) ||
 			!reloadEqual(oldCfg.App, newCfg.App) {
 			SetConfig(&newCfg)